@@ -0,0 +1,128 @@
+package internal
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// CloudEvents encoding modes.
+const (
+	CloudEventsBinary     = "binary"
+	CloudEventsStructured = "structured"
+)
+
+const (
+	cloudEventsSpecVersion = "1.0"
+	// cloudEventsMetadataPrefix prefixes binary-mode attributes in message metadata,
+	// following the Kafka and AMQP protocol bindings.
+	cloudEventsMetadataPrefix = "ce_"
+	// CloudEventsContentType is the content type of structured-mode messages.
+	CloudEventsContentType = "application/cloudevents+json"
+)
+
+// cloudEvent is the structured-mode JSON envelope.
+type cloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            string          `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	StateID         string          `json:"stateid,omitempty"`
+	DeliveryID      string          `json:"deliveryid,omitempty"`
+	Data            json.RawMessage `json:"data,omitempty"`
+	// DataBase64 carries bodies that are not JSON, such as form-encoded payloads.
+	DataBase64 string `json:"data_base64,omitempty"`
+}
+
+func validateCloudEvents(cfg CloudEventsConfig) error {
+	switch strings.ToLower(cfg.Mode) {
+	case "", CloudEventsBinary, CloudEventsStructured:
+		return nil
+	default:
+		return fmt.Errorf("unsupported cloudevents mode: %s", cfg.Mode)
+	}
+}
+
+// encodeCloudEvent maps event onto CloudEvents attributes: source is the
+// configured prefix plus provider, type the event name, subject the topic and
+// id the request id (or fallbackID). Both modes carry the same attributes. It
+// returns the message payload and the metadata to add; with CloudEvents
+// disabled both are returned unchanged.
+func encodeCloudEvent(cfg CloudEventsConfig, topic string, event Event, payload []byte, fallbackID string) ([]byte, map[string]string, error) {
+	mode := strings.ToLower(cfg.Mode)
+	if mode == "" {
+		return payload, nil, nil
+	}
+	source := strings.TrimRight(cfg.Source, "/")
+	if source == "" {
+		source = "githooks"
+	}
+	ce := cloudEvent{
+		SpecVersion:     cloudEventsSpecVersion,
+		ID:              event.RequestID,
+		Source:          source + "/" + event.Provider,
+		Type:            event.Name,
+		Subject:         topic,
+		Time:            time.Now().UTC().Format(time.RFC3339Nano),
+		DataContentType: "application/json",
+		StateID:         event.StateID,
+		DeliveryID:      event.DeliveryID,
+	}
+	if ce.ID == "" {
+		ce.ID = fallbackID
+	}
+	if json.Valid(payload) {
+		ce.Data = json.RawMessage(payload)
+	} else {
+		ce.DataContentType = "application/octet-stream"
+		ce.DataBase64 = base64.StdEncoding.EncodeToString(payload)
+	}
+
+	if mode == CloudEventsStructured {
+		encoded, err := json.Marshal(ce)
+		if err != nil {
+			return nil, nil, err
+		}
+		return encoded, map[string]string{"content-type": CloudEventsContentType}, nil
+	}
+
+	metadata := map[string]string{
+		cloudEventsMetadataPrefix + "specversion":     ce.SpecVersion,
+		cloudEventsMetadataPrefix + "id":              ce.ID,
+		cloudEventsMetadataPrefix + "source":          ce.Source,
+		cloudEventsMetadataPrefix + "type":            ce.Type,
+		cloudEventsMetadataPrefix + "time":            ce.Time,
+		cloudEventsMetadataPrefix + "datacontenttype": ce.DataContentType,
+		"content-type": ce.DataContentType,
+	}
+	optional := map[string]string{
+		"subject":    ce.Subject,
+		"stateid":    ce.StateID,
+		"deliveryid": ce.DeliveryID,
+	}
+	for name, value := range optional {
+		if value != "" {
+			metadata[cloudEventsMetadataPrefix+name] = value
+		}
+	}
+	return payload, metadata, nil
+}
+
+// setCloudEventsHeaders applies the HTTP protocol binding: binary-mode
+// attributes become ce-* headers and content-type sets Content-Type.
+func setCloudEventsHeaders(header http.Header, metadata map[string]string) {
+	for key, value := range metadata {
+		if name, ok := strings.CutPrefix(key, cloudEventsMetadataPrefix); ok {
+			header.Set("ce-"+name, value)
+		}
+	}
+	if contentType := metadata["content-type"]; contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+}
@@ -23,6 +23,13 @@ type AppConfig struct {
 		ReadHeaderMS   int64  `yaml:"read_header_timeout_ms"`
 		MaxBodyBytes   int64  `yaml:"max_body_bytes"`
 		DebugEvents    bool   `yaml:"debug_events"`
+		// TrustedProxies are CIDRs whose X-Forwarded-For is used for IP allowlists.
+		TrustedProxies []string `yaml:"trusted_proxies"`
+		// MetricsAddr is the address of a separate listener serving /debug/vars
+		// (e.g., "127.0.0.1:9090"). Empty disables it.
+		MetricsAddr string `yaml:"metrics_addr"`
+		// RateLimit throttles inbound deliveries before they are published.
+		RateLimit RateLimitConfig `yaml:"rate_limit"`
 	} `yaml:"server"`
 	// Providers contains configuration for each Git provider.
 	Providers auth.Config `yaml:"providers"`
@@ -32,6 +39,21 @@ type AppConfig struct {
 	Storage StorageConfig `yaml:"storage"`
 	// OAuth holds callback configuration for provider integrations.
 	OAuth OAuthConfig `yaml:"oauth"`
+	// Dedupe holds configuration for webhook delivery deduplication.
+	Dedupe DedupeConfig `yaml:"dedupe"`
+	// Inbox holds configuration for durable, asynchronous webhook dispatch.
+	Inbox InboxConfig `yaml:"inbox"`
+	// Archive holds configuration for the webhook delivery archive.
+	Archive ArchiveConfig `yaml:"archive"`
+	// RulesReload holds configuration for reloading rules without a restart.
+	RulesReload RulesReloadConfig `yaml:"rules_reload"`
+}
+
+// RulesReloadConfig controls reloading rules from the config file. Rules are
+// always reloaded on SIGHUP; Watch also reloads them when the file changes.
+type RulesReloadConfig struct {
+	Watch      bool  `yaml:"watch"`
+	IntervalMS int64 `yaml:"interval_ms"`
 }
 
 // Config represents the application configuration including rules.
@@ -39,6 +61,20 @@ type Config struct {
 	AppConfig   `yaml:",inline"`
 	Rules       []Rule `yaml:"rules"`
 	RulesStrict bool   `yaml:"rules_strict"`
+	// DefaultEmit receives events no rule matched.
+	DefaultEmit EmitList `yaml:"default_emit"`
+	// ProviderDefaultEmit overrides DefaultEmit per provider.
+	ProviderDefaultEmit map[string]EmitList `yaml:"provider_default_emit"`
+}
+
+// RulesConfig returns the rule-specific parts of the configuration.
+func (c Config) RulesConfig() RulesConfig {
+	return RulesConfig{
+		Rules:               c.Rules,
+		Strict:              c.RulesStrict,
+		DefaultEmit:         c.DefaultEmit,
+		ProviderDefaultEmit: c.ProviderDefaultEmit,
+	}
 }
 
 // ProviderConfig represents the configuration for a single Git provider.
@@ -57,6 +93,16 @@ type WatermillConfig struct {
 	RiverQueue   RiverQueueConfig   `yaml:"riverqueue"`
 	PublishRetry PublishRetryConfig `yaml:"publish_retry"`
 	DLQDriver    string             `yaml:"dlq_driver"`
+	CloudEvents  CloudEventsConfig  `yaml:"cloudevents"`
+}
+
+// CloudEventsConfig enables CloudEvents 1.0 encoding of published messages.
+// Mode is "binary" (attributes in metadata, raw payload as data) or
+// "structured" (a JSON envelope with the payload under data); empty disables it.
+type CloudEventsConfig struct {
+	Mode string `yaml:"mode"`
+	// Source is the source prefix; the provider name is appended, e.g. "githooks/github".
+	Source string `yaml:"source"`
 }
 
 // GoChannelConfig holds configuration for the GoChannel pub/sub.
@@ -122,6 +168,58 @@ type StorageConfig struct {
 	DSN             string `yaml:"dsn"`
 	Dialect         string `yaml:"dialect"`
 	AutoMigrate     bool   `yaml:"auto_migrate"`
+	// EncryptionKey encrypts generated per-namespace webhook secrets at rest.
+	EncryptionKey string `yaml:"encryption_key"`
+}
+
+// DedupeConfig holds configuration for delivery-id deduplication.
+type DedupeConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Driver is "memory" (per-process LRU) or "sql" (uses the storage DSN).
+	Driver string `yaml:"driver"`
+	// Mode is "drop" (acknowledge and skip) or "flag" (process with duplicate=true metadata).
+	Mode       string `yaml:"mode"`
+	TTLSeconds int64  `yaml:"ttl_seconds"`
+	MaxEntries int    `yaml:"max_entries"`
+	Table      string `yaml:"table"`
+}
+
+// InboxConfig holds configuration for the durable webhook inbox. When enabled,
+// handlers persist webhooks to SQL and a background dispatcher publishes them.
+type InboxConfig struct {
+	Enabled        bool   `yaml:"enabled"`
+	Table          string `yaml:"table"`
+	Concurrency    int    `yaml:"concurrency"`
+	BatchSize      int    `yaml:"batch_size"`
+	PollIntervalMS int64  `yaml:"poll_interval_ms"`
+	LeaseMS        int64  `yaml:"lease_ms"`
+	MaxAttempts    int    `yaml:"max_attempts"`
+	BackoffBaseMS  int64  `yaml:"backoff_base_ms"`
+	BackoffMaxMS   int64  `yaml:"backoff_max_ms"`
+}
+
+// ArchiveConfig holds configuration for the delivery archive. When enabled,
+// every delivery is stored in SQL with its matched topics and publish outcome
+// and can be replayed through /api/deliveries.
+type ArchiveConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Table   string `yaml:"table"`
+	// RetentionDays is how long deliveries are kept; a negative value keeps them forever.
+	RetentionDays   int   `yaml:"retention_days"`
+	PruneIntervalMS int64 `yaml:"prune_interval_ms"`
+}
+
+// RateLimitConfig holds configuration for inbound token-bucket rate limiting.
+// Key selects the bucket (provider, state_id or repository) and Mode the
+// over-limit behavior (reject with 429, shed, or spill to SpillTopic).
+type RateLimitConfig struct {
+	Enabled    bool    `yaml:"enabled"`
+	Key        string  `yaml:"key"`
+	Mode       string  `yaml:"mode"`
+	SpillTopic string  `yaml:"spill_topic"`
+	Rate       float64 `yaml:"rate"`
+	Burst      int     `yaml:"burst"`
+	MaxKeys    int     `yaml:"max_keys"`
 }
 
 // OAuthConfig holds configuration for OAuth callbacks.
@@ -144,6 +242,9 @@ func LoadAppConfig(path string) (AppConfig, error) {
 	}
 
 	applyDefaults(&cfg)
+	if err := validateGenericProviders(cfg); err != nil {
+		return cfg, err
+	}
 	return cfg, nil
 }
 
@@ -162,6 +263,9 @@ func LoadConfig(path string) (Config, error) {
 	}
 
 	applyDefaults(&cfg.AppConfig)
+	if err := validateGenericProviders(cfg.AppConfig); err != nil {
+		return cfg, err
+	}
 	normalized, err := normalizeRules(cfg.Rules)
 	if err != nil {
 		return cfg, err
@@ -174,9 +278,11 @@ func LoadConfig(path string) (Config, error) {
 
 // RulesConfig represents the rule-specific parts of the configuration.
 type RulesConfig struct {
-	Rules  []Rule `yaml:"rules"`
-	Strict bool   `yaml:"rules_strict"`
-	Logger *log.Logger
+	Rules               []Rule              `yaml:"rules"`
+	Strict              bool                `yaml:"rules_strict"`
+	DefaultEmit         EmitList            `yaml:"default_emit"`
+	ProviderDefaultEmit map[string]EmitList `yaml:"provider_default_emit"`
+	Logger              *log.Logger
 }
 
 // LoadRulesConfig loads only the rules from a YAML configuration file.
@@ -197,6 +303,47 @@ func LoadRulesConfig(path string) (RulesConfig, error) {
 	return cfg, nil
 }
 
+// validateGenericProviders rejects generic providers whose name is empty,
+// repeated or taken by a built-in provider, and whose path is repeated or
+// served by an enabled built-in provider. Names key rate limits, dedupe and
+// the archive, and a repeated path would make the mux panic at startup.
+func validateGenericProviders(cfg AppConfig) error {
+	builtins := []struct {
+		name string
+		cfg  auth.ProviderConfig
+	}{
+		{"github", cfg.Providers.GitHub},
+		{"gitlab", cfg.Providers.GitLab},
+		{"bitbucket", cfg.Providers.Bitbucket},
+		{"bitbucket_server", cfg.Providers.BitbucketServer},
+		{"gitea", cfg.Providers.Gitea},
+		{"azuredevops", cfg.Providers.AzureDevOps},
+	}
+	names := make(map[string]bool, len(builtins)+len(cfg.Providers.Generic))
+	paths := make(map[string]string, len(builtins)+len(cfg.Providers.Generic))
+	for _, builtin := range builtins {
+		names[builtin.name] = true
+		if builtin.cfg.Enabled {
+			paths[builtin.cfg.Path] = builtin.name
+		}
+	}
+	for i, generic := range cfg.Providers.Generic {
+		name := strings.TrimSpace(generic.Name)
+		if name == "" {
+			return fmt.Errorf("generic provider %d: name is required", i)
+		}
+		if names[name] {
+			return fmt.Errorf("generic provider %s: name is already used by another provider", name)
+		}
+		names[name] = true
+		if owner, ok := paths[generic.Path]; ok {
+			return fmt.Errorf("generic provider %s: path %s is already used by provider %s", name, generic.Path, owner)
+		}
+		paths[generic.Path] = name
+	}
+	return nil
+}
+
 func applyDefaults(cfg *AppConfig) {
 	if cfg.Server.Port == 0 {
 		cfg.Server.Port = 8080
@@ -216,6 +363,21 @@ func applyDefaults(cfg *AppConfig) {
 	if cfg.Server.MaxBodyBytes == 0 {
 		cfg.Server.MaxBodyBytes = 1 << 20
 	}
+	if cfg.Server.RateLimit.Key == "" {
+		cfg.Server.RateLimit.Key = "provider"
+	}
+	if cfg.Server.RateLimit.Mode == "" {
+		cfg.Server.RateLimit.Mode = "reject"
+	}
+	if cfg.Server.RateLimit.Rate == 0 {
+		cfg.Server.RateLimit.Rate = 10
+	}
+	if cfg.Server.RateLimit.Burst == 0 {
+		cfg.Server.RateLimit.Burst = 20
+	}
+	if cfg.RulesReload.IntervalMS == 0 {
+		cfg.RulesReload.IntervalMS = 2000
+	}
 	if cfg.Providers.GitHub.Path == "" {
 		cfg.Providers.GitHub.Path = "/webhooks/github"
 	}
@@ -225,6 +387,27 @@ func applyDefaults(cfg *AppConfig) {
 	if cfg.Providers.Bitbucket.Path == "" {
 		cfg.Providers.Bitbucket.Path = "/webhooks/bitbucket"
 	}
+	if cfg.Providers.BitbucketServer.Path == "" {
+		cfg.Providers.BitbucketServer.Path = "/webhooks/bitbucket-server"
+	}
+	if cfg.Providers.Gitea.Path == "" {
+		cfg.Providers.Gitea.Path = "/webhooks/gitea"
+	}
+	if cfg.Providers.AzureDevOps.Path == "" {
+		cfg.Providers.AzureDevOps.Path = "/webhooks/azuredevops"
+	}
+	for i := range cfg.Providers.Generic {
+		generic := &cfg.Providers.Generic[i]
+		if generic.Path == "" && generic.Name != "" {
+			generic.Path = "/webhooks/generic/" + generic.Name
+		}
+		if generic.Signature.Type == "" {
+			generic.Signature.Type = "none"
+			if generic.Signature.Secret != "" {
+				generic.Signature.Type = "hmac-sha256"
+			}
+		}
+	}
 	if cfg.Providers.GitHub.Path == "" {
 		cfg.Providers.GitHub.Path = "/webhooks/github"
 	}
@@ -234,12 +417,54 @@ func applyDefaults(cfg *AppConfig) {
 	if cfg.Providers.Bitbucket.Path == "" {
 		cfg.Providers.Bitbucket.Path = "/webhooks/bitbucket"
 	}
+	if cfg.Dedupe.Driver == "" {
+		cfg.Dedupe.Driver = "memory"
+	}
+	if cfg.Dedupe.Mode == "" {
+		cfg.Dedupe.Mode = "drop"
+	}
+	if cfg.Dedupe.TTLSeconds == 0 {
+		cfg.Dedupe.TTLSeconds = 86400
+	}
+	if cfg.Dedupe.MaxEntries == 0 {
+		cfg.Dedupe.MaxEntries = 10000
+	}
+	if cfg.Inbox.Concurrency == 0 {
+		cfg.Inbox.Concurrency = 4
+	}
+	if cfg.Inbox.BatchSize == 0 {
+		cfg.Inbox.BatchSize = 32
+	}
+	if cfg.Inbox.PollIntervalMS == 0 {
+		cfg.Inbox.PollIntervalMS = 1000
+	}
+	if cfg.Inbox.LeaseMS == 0 {
+		cfg.Inbox.LeaseMS = 60000
+	}
+	if cfg.Inbox.MaxAttempts == 0 {
+		cfg.Inbox.MaxAttempts = 10
+	}
+	if cfg.Inbox.BackoffBaseMS == 0 {
+		cfg.Inbox.BackoffBaseMS = 1000
+	}
+	if cfg.Inbox.BackoffMaxMS == 0 {
+		cfg.Inbox.BackoffMaxMS = 300000
+	}
+	if cfg.Archive.RetentionDays == 0 {
+		cfg.Archive.RetentionDays = 7
+	}
+	if cfg.Archive.PruneIntervalMS == 0 {
+		cfg.Archive.PruneIntervalMS = 3600000
+	}
 	if cfg.Watermill.Driver == "" {
 		cfg.Watermill.Driver = "gochannel"
 	}
 	if cfg.Watermill.GoChannel.OutputChannelBuffer == 0 {
 		cfg.Watermill.GoChannel.OutputChannelBuffer = 64
 	}
+	if cfg.Watermill.CloudEvents.Source == "" {
+		cfg.Watermill.CloudEvents.Source = "githooks"
+	}
 	if cfg.Watermill.HTTP.Mode == "" {
 		cfg.Watermill.HTTP.Mode = "topic_url"
 	}
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 // TestLoadConfigDefaults tests that the default values are applied correctly when loading a config.
@@ -31,6 +32,12 @@ func TestLoadConfigDefaults(t *testing.T) {
 	if cfg.AppConfig.Providers.Bitbucket.Path != "/webhooks/bitbucket" {
 		t.Fatalf("expected default bitbucket path, got %q", cfg.AppConfig.Providers.Bitbucket.Path)
 	}
+	if cfg.AppConfig.Providers.BitbucketServer.Path != "/webhooks/bitbucket-server" {
+		t.Fatalf("expected default bitbucket_server path, got %q", cfg.AppConfig.Providers.BitbucketServer.Path)
+	}
+	if cfg.AppConfig.Providers.Gitea.Path != "/webhooks/gitea" {
+		t.Fatalf("expected default gitea path, got %q", cfg.AppConfig.Providers.Gitea.Path)
+	}
 	if cfg.AppConfig.Watermill.Driver != "gochannel" {
 		t.Fatalf("expected default watermill driver, got %q", cfg.AppConfig.Watermill.Driver)
 	}
@@ -96,3 +103,88 @@ func TestLoadConfigEmitList(t *testing.T) {
 		t.Fatalf("expected 2 emits, got %v", cfg.Rules[0].Emit)
 	}
 }
+
+// TestLoadConfigGenericDefaults tests path and signature defaults for generic providers.
+func TestLoadConfigGenericDefaults(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	content := "providers:\n  generic:\n    - name: argocd\n      signature:\n        secret: s3cret\n        header: X-Signature\n    - name: jenkins\n      path: /hooks/ci\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	generic := cfg.Providers.Generic
+	if len(generic) != 2 {
+		t.Fatalf("expected 2 generic providers, got %d", len(generic))
+	}
+	if generic[0].Path != "/webhooks/generic/argocd" {
+		t.Fatalf("expected default generic path, got %q", generic[0].Path)
+	}
+	if generic[0].Signature.Type != "hmac-sha256" {
+		t.Fatalf("expected hmac-sha256 when secret is set, got %q", generic[0].Signature.Type)
+	}
+	if generic[1].Path != "/hooks/ci" || generic[1].Signature.Type != "none" {
+		t.Fatalf("unexpected jenkins config: %+v", generic[1])
+	}
+}
+
+// TestLoadConfigGenericConflicts tests that generic providers cannot reuse names or paths.
+func TestLoadConfigGenericConflicts(t *testing.T) {
+	cases := map[string]string{
+		"duplicate name": "providers:\n  generic:\n    - name: argocd\n    - name: argocd\n      path: /hooks/argocd\n",
+		"duplicate path": "providers:\n  generic:\n    - name: argocd\n      path: /hooks/ci\n    - name: jenkins\n      path: /hooks/ci\n",
+		"built-in name":  "providers:\n  generic:\n    - name: github\n",
+		"built-in path":  "providers:\n  gitlab:\n    enabled: true\n  generic:\n    - name: argocd\n      path: /webhooks/gitlab\n",
+		"missing name":   "providers:\n  generic:\n    - path: /hooks/ci\n",
+	}
+	for name, content := range cases {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("write config: %v", err)
+		}
+		if _, err := LoadConfig(path); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := "providers:\n  gitlab:\n    enabled: false\n  generic:\n    - name: argocd\n      path: /webhooks/gitlab\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if _, err := LoadConfig(path); err != nil {
+		t.Fatalf("expected path of a disabled provider to be allowed: %v", err)
+	}
+}
+
+// TestLoadConfigWebhookSecrets tests parsing rotated secrets with not_after dates.
+func TestLoadConfigWebhookSecrets(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	content := "providers:\n  github:\n    secret: current\n    secrets:\n      - id: old\n        value: previous\n        not_after: 2026-01-31T00:00:00Z\n      - value: other\n        not_after: 2026-02-15\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	secrets := cfg.Providers.GitHub.Secrets
+	if len(secrets) != 2 {
+		t.Fatalf("expected 2 secrets, got %d", len(secrets))
+	}
+	if secrets[0].ID != "old" || secrets[0].Value != "previous" {
+		t.Fatalf("unexpected first secret: %+v", secrets[0])
+	}
+	if !secrets[0].NotAfter.Equal(time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected not_after: %v", secrets[0].NotAfter)
+	}
+	if !secrets[1].NotAfter.Equal(time.Date(2026, 2, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected date-only not_after: %v", secrets[1].NotAfter)
+	}
+}
@@ -16,4 +16,11 @@ type Event struct {
 	RawObject interface{} `json:"-"`
 	// StateID maps the event to an installation/account id for token lookup.
 	StateID string `json:"-"`
+	// DeliveryID is the provider-assigned delivery id, stable across redeliveries.
+	DeliveryID string `json:"delivery_id,omitempty"`
+	// Normalized is the provider-independent model of the event as a JSON
+	// object, or nil when the event has no normalized form.
+	Normalized map[string]interface{} `json:"normalized,omitempty"`
+	// Duplicate is set when the dedupe layer flagged the delivery as already seen.
+	Duplicate bool `json:"-"`
 }
@@ -33,8 +33,9 @@ type Publisher interface {
 
 // watermillPublisher is a wrapper around a Watermill message.Publisher.
 type watermillPublisher struct {
-	publisher message.Publisher
-	closeFn   func() error
+	publisher   message.Publisher
+	closeFn     func() error
+	cloudEvents CloudEventsConfig
 }
 
 // PublisherFactory is a function that creates a new Watermill publisher.
@@ -57,6 +58,10 @@ func RegisterPublisherDriver(name string, factory PublisherFactory) {
 func NewPublisher(cfg WatermillConfig) (Publisher, error) {
 	logger := watermill.NewStdLogger(false, false)
 
+	if err := validateCloudEvents(cfg.CloudEvents); err != nil {
+		return nil, err
+	}
+
 	drivers := cfg.Drivers
 	if len(drivers) == 0 && cfg.Driver != "" {
 		drivers = []string{cfg.Driver}
@@ -111,13 +116,18 @@ func newSinglePublisher(cfg WatermillConfig, driver string) (Publisher, error) {
 				if err != nil {
 					return nil, err
 				}
-				return wmhttp.DefaultMarshalMessageFunc(target, msg)
+				req, err := wmhttp.DefaultMarshalMessageFunc(target, msg)
+				if err != nil {
+					return nil, err
+				}
+				setCloudEventsHeaders(req.Header, msg.Metadata)
+				return req, nil
 			},
 		}, logger)
 		if err != nil {
 			return nil, err
 		}
-		return &watermillPublisher{publisher: pub}, nil
+		return &watermillPublisher{publisher: pub, cloudEvents: cfg.CloudEvents}, nil
 	case "kafka":
 		if len(cfg.Kafka.Brokers) == 0 {
 			return nil, fmt.Errorf("kafka brokers are required")
@@ -128,7 +138,7 @@ func newSinglePublisher(cfg WatermillConfig, driver string) (Publisher, error) {
 		if err != nil {
 			return nil, err
 		}
-		return &watermillPublisher{publisher: pub}, nil
+		return &watermillPublisher{publisher: pub, cloudEvents: cfg.CloudEvents}, nil
 	case "nats":
 		if cfg.NATS.ClusterID == "" || cfg.NATS.ClientID == "" {
 			return nil, fmt.Errorf("nats cluster_id and client_id are required")
@@ -145,7 +155,7 @@ func newSinglePublisher(cfg WatermillConfig, driver string) (Publisher, error) {
 		if err != nil {
 			return nil, err
 		}
-		return &watermillPublisher{publisher: pub}, nil
+		return &watermillPublisher{publisher: pub, cloudEvents: cfg.CloudEvents}, nil
 	case "amqp":
 		if cfg.AMQP.URL == "" {
 			return nil, fmt.Errorf("amqp url is required")
@@ -158,7 +168,7 @@ func newSinglePublisher(cfg WatermillConfig, driver string) (Publisher, error) {
 		if err != nil {
 			return nil, err
 		}
-		return &watermillPublisher{publisher: pub}, nil
+		return &watermillPublisher{publisher: pub, cloudEvents: cfg.CloudEvents}, nil
 	case "sql":
 		if cfg.SQL.Driver == "" || cfg.SQL.DSN == "" {
 			return nil, fmt.Errorf("sql driver and dsn are required")
@@ -181,11 +191,12 @@ func newSinglePublisher(cfg WatermillConfig, driver string) (Publisher, error) {
 			return nil, err
 		}
 		return &watermillPublisher{
-			publisher: pub,
-			closeFn:   db.Close,
+			publisher:   pub,
+			closeFn:     db.Close,
+			cloudEvents: cfg.CloudEvents,
 		}, nil
 	case "riverqueue":
-		pub, err := newRiverQueuePublisher(cfg.RiverQueue)
+		pub, err := newRiverQueuePublisher(cfg.RiverQueue, cfg.CloudEvents)
 		if err != nil {
 			return nil, err
 		}
@@ -196,7 +207,7 @@ func newSinglePublisher(cfg WatermillConfig, driver string) (Publisher, error) {
 			if err != nil {
 				return nil, err
 			}
-			return &watermillPublisher{publisher: pub, closeFn: closeFn}, nil
+			return &watermillPublisher{publisher: pub, closeFn: closeFn, cloudEvents: cfg.CloudEvents}, nil
 		}
 		return nil, fmt.Errorf("unsupported watermill driver: %s", driver)
 	}
@@ -245,10 +256,19 @@ func (w *watermillPublisher) Publish(ctx context.Context, topic string, event Ev
 		}
 	}
 
-	msg := message.NewMessage(watermill.NewUUID(), payload)
+	uuid := watermill.NewUUID()
+	payload, ceMetadata, err := encodeCloudEvent(w.cloudEvents, topic, event, payload, uuid)
+	if err != nil {
+		return err
+	}
+
+	msg := message.NewMessage(uuid, payload)
 	if msg.Metadata == nil {
 		msg.Metadata = message.Metadata{}
 	}
+	for key, value := range ceMetadata {
+		msg.Metadata.Set(key, value)
+	}
 	if event.Provider != "" {
 		msg.Metadata.Set("provider", event.Provider)
 	}
@@ -261,6 +281,19 @@ func (w *watermillPublisher) Publish(ctx context.Context, topic string, event Ev
 	if event.StateID != "" {
 		msg.Metadata.Set("state_id", event.StateID)
 	}
+	if event.DeliveryID != "" {
+		msg.Metadata.Set("delivery_id", event.DeliveryID)
+	}
+	if event.Duplicate {
+		msg.Metadata.Set("duplicate", "true")
+	}
+	if event.Normalized != nil {
+		encoded, err := json.Marshal(event.Normalized)
+		if err != nil {
+			return err
+		}
+		msg.Metadata.Set("normalized", string(encoded))
+	}
 	return w.publisher.Publish(topic, msg)
 }
 
@@ -331,7 +364,11 @@ func (m *publisherMux) publishWithRetry(ctx context.Context, pub Publisher, topi
 	var lastErr error
 	for i := 0; i < attempts; i++ {
 		if i > 0 && delay > 0 {
-			time.Sleep(delay)
+			select {
+			case <-ctx.Done():
+				return errors.Join(lastErr, ctx.Err())
+			case <-time.After(delay):
+			}
 		}
 		if err := pub.Publish(ctx, topic, event); err != nil {
 			lastErr = err
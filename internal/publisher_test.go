@@ -2,6 +2,10 @@ package internal
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/ThreeDotsLabs/watermill"
@@ -152,6 +156,7 @@ func TestPublishUsesRawPayloadAndMetadata(t *testing.T) {
 		Provider:   "github",
 		Name:       "push",
 		RequestID:  "req-123",
+		DeliveryID: "delivery-123",
 		RawPayload: raw,
 	}
 	if err := pub.PublishForDrivers(context.Background(), "payload.topic", event, nil); err != nil {
@@ -170,4 +175,112 @@ func TestPublishUsesRawPayloadAndMetadata(t *testing.T) {
 	if stub.lastMetadata.Get("request_id") != "req-123" {
 		t.Fatalf("expected request_id metadata")
 	}
+	if stub.lastMetadata.Get("delivery_id") != "delivery-123" {
+		t.Fatalf("expected delivery_id metadata")
+	}
+}
+
+// TestPublishCloudEvents tests the binary and structured CloudEvents encodings.
+func TestPublishCloudEvents(t *testing.T) {
+	const driverName = "cloudevents"
+
+	orig, had := publisherFactories[driverName]
+	defer func() {
+		if had {
+			publisherFactories[driverName] = orig
+		} else {
+			delete(publisherFactories, driverName)
+		}
+	}()
+
+	stub := &stubPublisher{}
+	RegisterPublisherDriver(driverName, func(cfg WatermillConfig, logger watermill.LoggerAdapter) (message.Publisher, func() error, error) {
+		return stub, nil, nil
+	})
+
+	raw := []byte(`{"ref":"refs/heads/main"}`)
+	event := Event{Provider: "github", Name: "push", RequestID: "req-123", StateID: "acme", DeliveryID: "delivery-123", RawPayload: raw}
+
+	pub, err := NewPublisher(WatermillConfig{Driver: driverName, CloudEvents: CloudEventsConfig{Mode: CloudEventsBinary, Source: "githooks"}})
+	if err != nil {
+		t.Fatalf("new publisher: %v", err)
+	}
+	if err := pub.Publish(context.Background(), "ci.push", event); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	if string(stub.lastPayload) != string(raw) {
+		t.Fatalf("expected binary mode to keep the raw payload")
+	}
+	want := map[string]string{
+		"ce_specversion": "1.0",
+		"ce_id":          "req-123",
+		"ce_source":      "githooks/github",
+		"ce_type":        "push",
+		"ce_subject":     "ci.push",
+		"ce_stateid":     "acme",
+		"ce_deliveryid":  "delivery-123",
+		"provider":       "github",
+	}
+	for key, value := range want {
+		if got := stub.lastMetadata.Get(key); got != value {
+			t.Fatalf("expected metadata %s=%q, got %q", key, value, got)
+		}
+	}
+	binaryAttributes := map[string]string{}
+	for key, value := range stub.lastMetadata {
+		if name, ok := strings.CutPrefix(key, "ce_"); ok && name != "time" {
+			binaryAttributes[name] = value
+		}
+	}
+
+	pub, err = NewPublisher(WatermillConfig{Driver: driverName, CloudEvents: CloudEventsConfig{Mode: CloudEventsStructured, Source: "githooks"}})
+	if err != nil {
+		t.Fatalf("new publisher: %v", err)
+	}
+	if err := pub.Publish(context.Background(), "ci.push", event); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	var ce cloudEvent
+	if err := json.Unmarshal(stub.lastPayload, &ce); err != nil {
+		t.Fatalf("decode structured event: %v", err)
+	}
+	if ce.SpecVersion != "1.0" || ce.Source != "githooks/github" || ce.Type != "push" || ce.Subject != "ci.push" || ce.ID != "req-123" || ce.StateID != "acme" {
+		t.Fatalf("unexpected structured attributes: %+v", ce)
+	}
+	if string(ce.Data) != string(raw) {
+		t.Fatalf("expected raw payload as data, got %s", ce.Data)
+	}
+	if stub.lastMetadata.Get("content-type") != CloudEventsContentType {
+		t.Fatalf("expected structured content type")
+	}
+	var envelope map[string]interface{}
+	if err := json.Unmarshal(stub.lastPayload, &envelope); err != nil {
+		t.Fatalf("decode structured envelope: %v", err)
+	}
+	structuredAttributes := map[string]string{}
+	for name, value := range envelope {
+		if text, ok := value.(string); ok && name != "time" {
+			structuredAttributes[name] = text
+		}
+	}
+	if !reflect.DeepEqual(binaryAttributes, structuredAttributes) {
+		t.Fatalf("expected both modes to carry the same attributes, got binary %v and structured %v", binaryAttributes, structuredAttributes)
+	}
+
+	form := event
+	form.RawPayload = []byte("payload=%7B%7D")
+	if err := pub.Publish(context.Background(), "ci.push", form); err != nil {
+		t.Fatalf("publish form body: %v", err)
+	}
+	ce = cloudEvent{}
+	if err := json.Unmarshal(stub.lastPayload, &ce); err != nil {
+		t.Fatalf("decode structured form event: %v", err)
+	}
+	if ce.Data != nil || ce.DataContentType != "application/octet-stream" || ce.DataBase64 != base64.StdEncoding.EncodeToString(form.RawPayload) {
+		t.Fatalf("expected non-JSON body as data_base64, got %+v", ce)
+	}
+
+	if _, err := NewPublisher(WatermillConfig{Driver: driverName, CloudEvents: CloudEventsConfig{Mode: "xml"}}); err == nil {
+		t.Fatalf("expected unsupported mode to fail")
+	}
 }
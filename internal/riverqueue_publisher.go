@@ -7,17 +7,19 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/ThreeDotsLabs/watermill"
 	"github.com/lib/pq"
 )
 
 // riverQueuePublisher is a publisher that sends events to a RiverQueue job queue.
 type riverQueuePublisher struct {
-	db  *sql.DB
-	cfg RiverQueueConfig
+	db          *sql.DB
+	cfg         RiverQueueConfig
+	cloudEvents CloudEventsConfig
 }
 
 // newRiverQueuePublisher creates a new RiverQueue publisher.
-func newRiverQueuePublisher(cfg RiverQueueConfig) (*riverQueuePublisher, error) {
+func newRiverQueuePublisher(cfg RiverQueueConfig, cloudEvents CloudEventsConfig) (*riverQueuePublisher, error) {
 	driver := cfg.Driver
 	if driver == "" {
 		driver = "postgres"
@@ -29,7 +31,7 @@ func newRiverQueuePublisher(cfg RiverQueueConfig) (*riverQueuePublisher, error)
 	if err != nil {
 		return nil, err
 	}
-	return &riverQueuePublisher{db: db, cfg: cfg}, nil
+	return &riverQueuePublisher{db: db, cfg: cfg, cloudEvents: cloudEvents}, nil
 }
 
 // Publish inserts a new job into the RiverQueue jobs table.
@@ -42,12 +44,28 @@ func (p *riverQueuePublisher) Publish(ctx context.Context, topic string, event E
 		}
 		argsPayload = encoded
 	}
+	argsPayload, ceMetadata, err := encodeCloudEvent(p.cloudEvents, topic, event, argsPayload, watermill.NewUUID())
+	if err != nil {
+		return err
+	}
 
 	metadata := map[string]interface{}{
 		"provider": event.Provider,
 		"name":     event.Name,
 		"topic":    topic,
 	}
+	if event.DeliveryID != "" {
+		metadata["delivery_id"] = event.DeliveryID
+	}
+	if event.Duplicate {
+		metadata["duplicate"] = true
+	}
+	if event.Normalized != nil {
+		metadata["normalized"] = event.Normalized
+	}
+	for key, value := range ceMetadata {
+		metadata[key] = value
+	}
 	metadataPayload, err := json.Marshal(metadata)
 	if err != nil {
 		return err
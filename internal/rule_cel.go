@@ -0,0 +1,127 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/google/cel-go/cel"
+	celast "github.com/google/cel-go/common/ast"
+	"github.com/google/cel-go/common/operators"
+	"github.com/google/cel-go/common/types"
+	"github.com/google/cel-go/ext"
+)
+
+// Rule expression languages.
+const (
+	RuleLangGovaluate = "govaluate"
+	RuleLangCEL       = "cel"
+)
+
+// celEnv declares the variables available to CEL rules: the raw payload, the
+// normalized model, and the provider and event name.
+var celEnv = sync.OnceValues(func() (*cel.Env, error) {
+	return cel.NewEnv(
+		cel.Variable("payload", cel.DynType),
+		cel.Variable("normalized", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("provider", cel.StringType),
+		cel.Variable("event", cel.StringType),
+		ext.Strings(),
+	)
+})
+
+// celRule is a type-checked CEL expression.
+type celRule struct {
+	program cel.Program
+	// accessIDs are the expression ids of field selections and index
+	// operations. An evaluation error raised by one of them means the payload
+	// lacks the accessed key.
+	accessIDs map[int64]bool
+}
+
+func compileCELRule(expr string) (*celRule, error) {
+	env, err := celEnv()
+	if err != nil {
+		return nil, err
+	}
+	ast, issues := env.Compile(expr)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("cel: %w", issues.Err())
+	}
+	if out := ast.OutputType(); !out.IsExactType(cel.BoolType) && !out.IsExactType(cel.DynType) {
+		return nil, fmt.Errorf("cel: expression returns %s, not bool", out)
+	}
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("cel: %w", err)
+	}
+	return &celRule{program: program, accessIDs: celAccessIDs(ast)}, nil
+}
+
+func celAccessIDs(ast *cel.Ast) map[int64]bool {
+	ids := make(map[int64]bool)
+	celast.PostOrderVisit(ast.NativeRep().Expr(), celast.NewExprVisitor(func(expr celast.Expr) {
+		switch expr.Kind() {
+		case celast.SelectKind:
+			if !expr.AsSelect().IsTestOnly() {
+				ids[expr.ID()] = true
+			}
+		case celast.CallKind:
+			switch expr.AsCall().FunctionName() {
+			case operators.Index, operators.OptIndex, operators.OptSelect:
+				ids[expr.ID()] = true
+			}
+		}
+	}))
+	return ids
+}
+
+// evaluate runs the expression against event. Missing keys do not match, or
+// fail the rule in strict mode.
+func (r *celRule) evaluate(event Event, strict bool) (bool, error) {
+	normalized := event.Normalized
+	if normalized == nil {
+		normalized = map[string]interface{}{}
+	}
+	out, _, err := r.program.Eval(map[string]interface{}{
+		"payload":    celPayload(event),
+		"normalized": normalized,
+		"provider":   event.Provider,
+		"event":      event.Name,
+	})
+	if err != nil {
+		if r.isMissing(err) {
+			if strict {
+				return false, fmt.Errorf("rule strict missing params: %v", err)
+			}
+			return false, nil
+		}
+		return false, fmt.Errorf("cel: %w", err)
+	}
+	ok, isBool := out.Value().(bool)
+	if !isBool {
+		return false, fmt.Errorf("cel: expression returned %s, not bool", out.Type())
+	}
+	return ok, nil
+}
+
+func celPayload(event Event) interface{} {
+	if event.RawObject != nil {
+		return event.RawObject
+	}
+	if len(event.RawPayload) > 0 {
+		var raw interface{}
+		if err := json.Unmarshal(event.RawPayload, &raw); err == nil {
+			return raw
+		}
+	}
+	return map[string]interface{}{}
+}
+
+// isMissing reports whether err was raised while resolving a field selection
+// or index rather than by an operator or function.
+func (r *celRule) isMissing(err error) bool {
+	var celErr *types.Err
+	return errors.As(err, &celErr) && r.accessIDs[celErr.NodeID()]
+}
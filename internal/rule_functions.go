@@ -0,0 +1,372 @@
+package internal
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/Knetic/govaluate"
+)
+
+// maxCachedRegexps bounds the compiled pattern cache, since patterns can come
+// from payload values as well as rule literals.
+const maxCachedRegexps = 1024
+
+var (
+	regexpCacheMu sync.RWMutex
+	regexpCache   = make(map[string]*regexp.Regexp)
+)
+
+// cachedRegexp compiles pattern once and reuses it across evaluations.
+func cachedRegexp(pattern string) (*regexp.Regexp, error) {
+	regexpCacheMu.RLock()
+	re, ok := regexpCache[pattern]
+	regexpCacheMu.RUnlock()
+	if ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	regexpCacheMu.Lock()
+	if len(regexpCache) < maxCachedRegexps {
+		regexpCache[pattern] = re
+	}
+	regexpCacheMu.Unlock()
+	return re, nil
+}
+
+// stringArgs returns the args as strings, or false when any is not a string.
+func stringArgs(args []interface{}) ([]string, bool) {
+	out := make([]string, len(args))
+	for i, arg := range args {
+		value, ok := arg.(string)
+		if !ok {
+			return nil, false
+		}
+		out[i] = value
+	}
+	return out, true
+}
+
+func matchesFunc(args ...interface{}) (interface{}, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("matches expects 2 args")
+	}
+	values, ok := stringArgs(args)
+	if !ok {
+		return false, nil
+	}
+	re, err := cachedRegexp(values[1])
+	if err != nil {
+		return false, fmt.Errorf("matches: %w", err)
+	}
+	return re.MatchString(values[0]), nil
+}
+
+func startsWithFunc(args ...interface{}) (interface{}, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("startsWith expects 2 args")
+	}
+	values, ok := stringArgs(args)
+	if !ok {
+		return false, nil
+	}
+	return strings.HasPrefix(values[0], values[1]), nil
+}
+
+func endsWithFunc(args ...interface{}) (interface{}, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("endsWith expects 2 args")
+	}
+	values, ok := stringArgs(args)
+	if !ok {
+		return false, nil
+	}
+	return strings.HasSuffix(values[0], values[1]), nil
+}
+
+func globFunc(args ...interface{}) (interface{}, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("glob expects 2 args")
+	}
+	values, ok := stringArgs(args)
+	if !ok {
+		return false, nil
+	}
+	re, err := cachedRegexp(globPatternToRegex(values[1]))
+	if err != nil {
+		return false, fmt.Errorf("glob: %w", err)
+	}
+	return re.MatchString(values[0]), nil
+}
+
+// globPatternToRegex converts a file path glob: * and ? stay within one path
+// segment, ** spans segments and **/ also matches no directory.
+func globPatternToRegex(pattern string) string {
+	var b strings.Builder
+	b.WriteByte('^')
+	for i := 0; i < len(pattern); i++ {
+		switch ch := pattern[i]; ch {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				if i+1 < len(pattern) && pattern[i+1] == '/' {
+					i++
+					b.WriteString("(?:.*/)?")
+				} else {
+					b.WriteString(".*")
+				}
+				continue
+			}
+			b.WriteString("[^/]*")
+		case '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	b.WriteByte('$')
+	return b.String()
+}
+
+func lowerFunc(args ...interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("lower expects 1 arg")
+	}
+	value, ok := args[0].(string)
+	if !ok {
+		return nil, nil
+	}
+	return strings.ToLower(value), nil
+}
+
+func upperFunc(args ...interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("upper expects 1 arg")
+	}
+	value, ok := args[0].(string)
+	if !ok {
+		return nil, nil
+	}
+	return strings.ToUpper(value), nil
+}
+
+// lenFunc returns the length of a string (in characters), list or map; other
+// values, including missing ones, have length 0.
+func lenFunc(args ...interface{}) (interface{}, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("len expects 1 arg")
+	}
+	switch value := args[0].(type) {
+	case nil:
+		return float64(0), nil
+	case string:
+		return float64(utf8.RuneCountInString(value)), nil
+	case ruleList:
+		return float64(len(value)), nil
+	}
+	switch value := reflect.ValueOf(args[0]); value.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(value.Len()), nil
+	}
+	return float64(0), nil
+}
+
+// ruleItems returns value as a list. A single JSONPath match is a scalar, so
+// scalars are a list of one; missing values are empty.
+func ruleItems(value interface{}) []interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case ruleList:
+		return v
+	case []interface{}:
+		return v
+	case []string:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = item
+		}
+		return out
+	}
+	return []interface{}{value}
+}
+
+// quantifierFunc builds any or all. With one arg the items must be true
+// bools; otherwise the second arg names a function that is called with each
+// item followed by the remaining args, e.g. any(files, "glob", "docs/**").
+// Both return false for missing or empty lists.
+func quantifierFunc(name string, all bool, functions map[string]govaluate.ExpressionFunction) govaluate.ExpressionFunction {
+	return func(args ...interface{}) (interface{}, error) {
+		if len(args) == 0 {
+			return nil, fmt.Errorf("%s expects at least 1 arg", name)
+		}
+		predicate := func(item interface{}) (bool, error) {
+			ok, _ := item.(bool)
+			return ok, nil
+		}
+		if len(args) > 1 {
+			fnName, ok := args[1].(string)
+			if !ok {
+				return nil, fmt.Errorf("%s expects a function name as its second arg", name)
+			}
+			fn, ok := functions[fnName]
+			if !ok || fnName == "any" || fnName == "all" {
+				return nil, fmt.Errorf("%s: unknown function %q", name, fnName)
+			}
+			rest := args[2:]
+			predicate = func(item interface{}) (bool, error) {
+				result, err := fn(append([]interface{}{item}, rest...)...)
+				if err != nil {
+					return false, err
+				}
+				ok, _ := result.(bool)
+				return ok, nil
+			}
+		}
+		items := ruleItems(args[0])
+		if len(items) == 0 {
+			return false, nil
+		}
+		for _, item := range items {
+			ok, err := predicate(item)
+			if err != nil {
+				return false, err
+			}
+			if ok != all {
+				return !all, nil
+			}
+		}
+		return all, nil
+	}
+}
+
+// semverGteFunc reports whether version is at least minimum. Both may carry a
+// refs/tags/ or v prefix; versions that are not semver do not match.
+func semverGteFunc(args ...interface{}) (interface{}, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("semverGte expects 2 args")
+	}
+	values, ok := stringArgs(args)
+	if !ok {
+		return false, nil
+	}
+	version, ok := parseSemver(values[0])
+	if !ok {
+		return false, nil
+	}
+	minimum, ok := parseSemver(values[1])
+	if !ok {
+		return false, fmt.Errorf("semverGte: invalid version %q", values[1])
+	}
+	return compareSemver(version, minimum) >= 0, nil
+}
+
+type semver struct {
+	core       [3]int
+	prerelease []string
+}
+
+// parseSemver parses MAJOR[.MINOR[.PATCH]][-PRERELEASE][+BUILD].
+func parseSemver(value string) (semver, bool) {
+	var out semver
+	value = strings.TrimPrefix(strings.TrimSpace(value), "refs/tags/")
+	value = strings.TrimPrefix(strings.TrimPrefix(value, "v"), "V")
+	if i := strings.IndexByte(value, '+'); i >= 0 {
+		value = value[:i]
+	}
+	if i := strings.IndexByte(value, '-'); i >= 0 {
+		if value[i+1:] == "" {
+			return out, false
+		}
+		out.prerelease = strings.Split(value[i+1:], ".")
+		value = value[:i]
+	}
+	parts := strings.Split(value, ".")
+	if len(parts) > 3 {
+		return out, false
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return out, false
+		}
+		out.core[i] = n
+	}
+	return out, true
+}
+
+// compareSemver orders versions by semver precedence.
+func compareSemver(a, b semver) int {
+	for i := range a.core {
+		if a.core[i] != b.core[i] {
+			if a.core[i] < b.core[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case len(a.prerelease) == 0 && len(b.prerelease) == 0:
+		return 0
+	case len(a.prerelease) == 0:
+		return 1
+	case len(b.prerelease) == 0:
+		return -1
+	}
+	for i := 0; i < len(a.prerelease) && i < len(b.prerelease); i++ {
+		left, right := a.prerelease[i], b.prerelease[i]
+		if left == right {
+			continue
+		}
+		leftNum, leftErr := strconv.Atoi(left)
+		rightNum, rightErr := strconv.Atoi(right)
+		switch {
+		case leftErr == nil && rightErr == nil:
+			if leftNum < rightNum {
+				return -1
+			}
+			return 1
+		case leftErr == nil:
+			return -1
+		case rightErr == nil:
+			return 1
+		case left < right:
+			return -1
+		default:
+			return 1
+		}
+	}
+	switch {
+	case len(a.prerelease) < len(b.prerelease):
+		return -1
+	case len(a.prerelease) > len(b.prerelease):
+		return 1
+	}
+	return 0
+}
+
+// inListFunc reports whether the first arg equals any of the others. List
+// args contribute their items.
+func inListFunc(args ...interface{}) (interface{}, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("inList expects at least 2 args")
+	}
+	if args[0] == nil {
+		return false, nil
+	}
+	for _, arg := range args[1:] {
+		for _, candidate := range ruleItems(arg) {
+			if reflect.DeepEqual(args[0], candidate) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
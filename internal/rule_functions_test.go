@@ -0,0 +1,133 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestRuleFunctions tests each rule function directly.
+func TestRuleFunctions(t *testing.T) {
+	functions := ruleFunctions()
+	cases := []struct {
+		name string
+		args []interface{}
+		want interface{}
+	}{
+		{"matches", []interface{}{"release/1.2", `^release/\d+\.\d+$`}, true},
+		{"matches", []interface{}{"feature/x", `^release/`}, false},
+		{"matches", []interface{}{nil, `.*`}, false},
+		{"startsWith", []interface{}{"refs/heads/main", "refs/heads/"}, true},
+		{"startsWith", []interface{}{"refs/tags/v1", "refs/heads/"}, false},
+		{"endsWith", []interface{}{"docs/rules.md", ".md"}, true},
+		{"endsWith", []interface{}{float64(1), ".md"}, false},
+		{"glob", []interface{}{"docs/rules.md", "docs/*.md"}, true},
+		{"glob", []interface{}{"docs/api/rules.md", "docs/*.md"}, false},
+		{"glob", []interface{}{"docs/api/rules.md", "docs/**"}, true},
+		{"glob", []interface{}{"README.md", "**/*.md"}, true},
+		{"glob", []interface{}{"pkg/a/b.go", "pkg/**/*.go"}, true},
+		{"glob", []interface{}{"pkg/b.go", "pkg/**/*.go"}, true},
+		{"glob", []interface{}{"a.go", "?.go"}, true},
+		{"lower", []interface{}{"MAIN"}, "main"},
+		{"upper", []interface{}{"main"}, "MAIN"},
+		{"lower", []interface{}{nil}, nil},
+		{"len", []interface{}{"héllo"}, float64(5)},
+		{"len", []interface{}{ruleList{1, 2, 3}}, float64(3)},
+		{"len", []interface{}{map[string]interface{}{"a": 1}}, float64(1)},
+		{"len", []interface{}{nil}, float64(0)},
+		{"any", []interface{}{ruleList{false, true}}, true},
+		{"any", []interface{}{ruleList{"README.md", "docs/a.md"}, "glob", "docs/**"}, true},
+		{"any", []interface{}{ruleList{"README.md"}, "startsWith", "docs/"}, false},
+		{"any", []interface{}{nil, "startsWith", "docs/"}, false},
+		{"all", []interface{}{ruleList{true, true}}, true},
+		{"all", []interface{}{ruleList{"docs/a.md", "docs/b.md"}, "startsWith", "docs/"}, true},
+		{"all", []interface{}{ruleList{"docs/a.md", "main.go"}, "startsWith", "docs/"}, false},
+		{"all", []interface{}{"docs/a.md", "startsWith", "docs/"}, true},
+		{"all", []interface{}{ruleList{}, "startsWith", "docs/"}, false},
+		{"semverGte", []interface{}{"v1.10.0", "1.9.0"}, true},
+		{"semverGte", []interface{}{"refs/tags/v2.0.0", "v2"}, true},
+		{"semverGte", []interface{}{"v2.0.0-rc.1", "2.0.0"}, false},
+		{"semverGte", []interface{}{"v2.0.0-rc.10", "2.0.0-rc.9"}, true},
+		{"semverGte", []interface{}{"v2.0.0-beta", "2.0.0-alpha.1"}, true},
+		{"semverGte", []interface{}{"v1.2.3+build.5", "1.2.3"}, true},
+		{"semverGte", []interface{}{"latest", "1.0.0"}, false},
+		{"inList", []interface{}{"opened", "opened", "reopened"}, true},
+		{"inList", []interface{}{"closed", "opened", "reopened"}, false},
+		{"inList", []interface{}{float64(2), ruleList{float64(1), float64(2)}}, true},
+		{"inList", []interface{}{nil, "opened"}, false},
+	}
+	for _, tc := range cases {
+		got, err := functions[tc.name](tc.args...)
+		if err != nil {
+			t.Fatalf("%s%v: %v", tc.name, tc.args, err)
+		}
+		if got != tc.want {
+			t.Fatalf("%s%v: expected %v, got %v", tc.name, tc.args, tc.want, got)
+		}
+	}
+}
+
+// TestRuleFunctionErrors tests argument and pattern errors.
+func TestRuleFunctionErrors(t *testing.T) {
+	functions := ruleFunctions()
+	cases := []struct {
+		name string
+		args []interface{}
+	}{
+		{"matches", []interface{}{"a"}},
+		{"matches", []interface{}{"a", "("}},
+		{"len", []interface{}{}},
+		{"any", []interface{}{ruleList{"a"}, "unknown"}},
+		{"all", []interface{}{ruleList{"a"}, "all"}},
+		{"semverGte", []interface{}{"1.0.0", "next"}},
+		{"inList", []interface{}{"a"}},
+	}
+	for _, tc := range cases {
+		if _, err := functions[tc.name](tc.args...); err == nil {
+			t.Fatalf("%s%v: expected error", tc.name, tc.args)
+		}
+	}
+}
+
+// TestCachedRegexp tests that patterns are compiled once.
+func TestCachedRegexp(t *testing.T) {
+	first, err := cachedRegexp(`^v\d+$`)
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	second, err := cachedRegexp(`^v\d+$`)
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	if first != second {
+		t.Fatalf("expected cached regexp to be reused")
+	}
+}
+
+// TestRuleEngineFunctionLibrary tests the functions in rule expressions against payloads.
+func TestRuleEngineFunctionLibrary(t *testing.T) {
+	engine, err := NewRuleEngine(RulesConfig{
+		Rules: []Rule{
+			{When: `any($.commits[*].modified[*], "glob", "docs/**")`, Emit: EmitList{"docs"}},
+			{When: `semverGte(ref, "v1.2.0") && startsWith(ref, "refs/tags/")`, Emit: EmitList{"release"}},
+			{When: `inList(lower($.sender.login), "octocat", "hubot") && len($.commits) == 2`, Emit: EmitList{"team"}},
+			{When: `matches($.head_commit.message, "^(feat|fix)(\\(.+\\))?: ")`, Emit: EmitList{"conventional"}},
+			{When: `all($.commits[*].modified[*], "endsWith", ".go")`, Emit: EmitList{"go.only"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("new rule engine: %v", err)
+	}
+	payload := `{
+		"ref": "refs/tags/v1.10.0",
+		"sender": {"login": "OctoCat"},
+		"head_commit": {"message": "feat(rules): add functions"},
+		"commits": [{"modified": ["README.md"]}, {"modified": ["docs/rules.md", "internal/rules.go"]}]
+	}`
+	var topics []string
+	for _, match := range engine.Evaluate(Event{Provider: "github", Name: "push", RawPayload: []byte(payload)}) {
+		topics = append(topics, match.Topic)
+	}
+	if got := strings.Join(topics, ","); got != "docs,release,team,conventional" {
+		t.Fatalf("unexpected topics: %s", got)
+	}
+}
@@ -0,0 +1,166 @@
+package internal
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ScopeList supports either a string or list of strings in YAML.
+type ScopeList []string
+
+func (l *ScopeList) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		if value.Value == "" {
+			*l = nil
+			return nil
+		}
+		*l = ScopeList{value.Value}
+		return nil
+	case yaml.SequenceNode:
+		out := make([]string, 0, len(value.Content))
+		for _, item := range value.Content {
+			if item.Kind != yaml.ScalarNode {
+				return fmt.Errorf("provider and event items must be strings")
+			}
+			out = append(out, item.Value)
+		}
+		*l = ScopeList(out)
+		return nil
+	default:
+		return fmt.Errorf("provider and event must be a string or list of strings")
+	}
+}
+
+// Values returns the trimmed, non-empty entries.
+func (l ScopeList) Values() []string {
+	out := make([]string, 0, len(l))
+	for _, val := range l {
+		trimmed := strings.TrimSpace(val)
+		if trimmed != "" {
+			out = append(out, trimmed)
+		}
+	}
+	return out
+}
+
+// ruleScope limits a rule to providers and event names. Literal names are
+// kept in sets for lookup; globs are matched with path.Match.
+type ruleScope struct {
+	providerSet  map[string]bool
+	providerGlob []string
+	eventSet     map[string]bool
+	eventGlob    []string
+}
+
+func compileRuleScope(providers, events ScopeList) (ruleScope, error) {
+	var scope ruleScope
+	var err error
+	if scope.providerSet, scope.providerGlob, err = compileScopePatterns(providers.Values()); err != nil {
+		return scope, fmt.Errorf("provider: %w", err)
+	}
+	if scope.eventSet, scope.eventGlob, err = compileScopePatterns(events.Values()); err != nil {
+		return scope, fmt.Errorf("event: %w", err)
+	}
+	return scope, nil
+}
+
+func compileScopePatterns(patterns []string) (map[string]bool, []string, error) {
+	if len(patterns) == 0 {
+		return nil, nil, nil
+	}
+	literals := make(map[string]bool, len(patterns))
+	var globs []string
+	for _, pattern := range patterns {
+		if !strings.ContainsAny(pattern, `*?[\`) {
+			literals[pattern] = true
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		globs = append(globs, pattern)
+	}
+	return literals, globs, nil
+}
+
+// literalNames returns the names a scope dimension is limited to, or [""]
+// when it has globs or no entries and so may match any name.
+func literalNames(literals map[string]bool, globs []string) []string {
+	if len(literals) == 0 || len(globs) > 0 {
+		return []string{""}
+	}
+	names := make([]string, 0, len(literals))
+	for name := range literals {
+		names = append(names, name)
+	}
+	return names
+}
+
+func (s ruleScope) matches(provider, event string) bool {
+	return scopeMatches(s.providerSet, s.providerGlob, provider) && s.matchesEvent(event)
+}
+
+func (s ruleScope) matchesEvent(event string) bool {
+	return scopeMatches(s.eventSet, s.eventGlob, event)
+}
+
+func scopeMatches(literals map[string]bool, globs []string, value string) bool {
+	if literals == nil && globs == nil {
+		return true
+	}
+	if literals[value] {
+		return true
+	}
+	for _, glob := range globs {
+		if ok, _ := path.Match(glob, value); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// scopeKey indexes rules by literal provider and event name. An empty field
+// holds the rules that may match any name in that dimension.
+type scopeKey struct {
+	provider string
+	event    string
+}
+
+// indexScopes builds the provider and event index over the rules.
+func (set *ruleSet) indexScopes() {
+	set.byScope = make(map[scopeKey][]int)
+	for position, rule := range set.rules {
+		for _, provider := range literalNames(rule.scope.providerSet, rule.scope.providerGlob) {
+			for _, event := range literalNames(rule.scope.eventSet, rule.scope.eventGlob) {
+				key := scopeKey{provider: provider, event: event}
+				set.byScope[key] = append(set.byScope[key], position)
+			}
+		}
+	}
+}
+
+// candidates returns, in evaluation order, the positions of the rules that
+// may match provider and event.
+func (set *ruleSet) candidates(provider, event string) []int {
+	var lists [][]int
+	for _, key := range []scopeKey{{provider, event}, {provider, ""}, {"", event}, {}} {
+		if positions := set.byScope[key]; len(positions) > 0 {
+			lists = append(lists, positions)
+		}
+	}
+	switch len(lists) {
+	case 0:
+		return nil
+	case 1:
+		return lists[0]
+	}
+	out := slices.Concat(lists...)
+	slices.Sort(out)
+	// An empty provider or event name looks up the same key twice.
+	return slices.Compact(out)
+}
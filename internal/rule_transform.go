@@ -0,0 +1,91 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/PaesslerAG/jsonpath"
+)
+
+// Transform projects the published message body. Each key is an output field
+// and each value a JSONPath (bare paths mean $.path and $.normalized... reads
+// the normalized model) or a nested Transform for an object.
+type Transform map[string]interface{}
+
+// projection is a compiled Transform. Leaves hold a JSONPath, objects their
+// fields.
+type projection struct {
+	path   string
+	fields map[string]*projection
+}
+
+func compileTransform(transform Transform) (*projection, error) {
+	if len(transform) == 0 {
+		return nil, nil
+	}
+	return compileProjection(map[string]interface{}(transform), "")
+}
+
+func compileProjection(fields map[string]interface{}, prefix string) (*projection, error) {
+	out := &projection{fields: make(map[string]*projection, len(fields))}
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		name := prefix + key
+		switch value := fields[key].(type) {
+		case string:
+			path := strings.TrimSpace(value)
+			if path == "" {
+				return nil, fmt.Errorf("transform %s: missing JSONPath", name)
+			}
+			if !strings.HasPrefix(path, "$") {
+				path = "$." + path
+			}
+			if _, err := jsonpath.New(path); err != nil {
+				return nil, fmt.Errorf("transform %s: %w", name, err)
+			}
+			out.fields[key] = &projection{path: path}
+		case map[string]interface{}:
+			nested, err := compileProjection(value, name+".")
+			if err != nil {
+				return nil, err
+			}
+			out.fields[key] = nested
+		case Transform:
+			nested, err := compileProjection(map[string]interface{}(value), name+".")
+			if err != nil {
+				return nil, err
+			}
+			out.fields[key] = nested
+		default:
+			return nil, fmt.Errorf("transform %s: must be a JSONPath or a map", name)
+		}
+	}
+	return out, nil
+}
+
+// render builds the projected document for event and encodes it as JSON.
+// Paths that do not resolve are null.
+func (p *projection) render(event Event) (json.RawMessage, error) {
+	return json.Marshal(p.value(event))
+}
+
+func (p *projection) value(event Event) interface{} {
+	if p.fields == nil {
+		value, err := lookupJSONPath(event, p.path)
+		if err != nil {
+			return nil
+		}
+		return value
+	}
+	out := make(map[string]interface{}, len(p.fields))
+	for key, field := range p.fields {
+		out[key] = field.value(event)
+	}
+	return out
+}
@@ -2,11 +2,15 @@ package internal
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"reflect"
 	"regexp"
+	"sort"
 	"strings"
+	"sync/atomic"
 
 	"github.com/Knetic/govaluate"
 	"github.com/PaesslerAG/jsonpath"
@@ -15,35 +19,80 @@ import (
 
 // Rule defines a condition and an action to take when the condition is met.
 type Rule struct {
-	// When is a govaluate expression that is evaluated against the event data.
+	// When is a govaluate expression that is evaluated against the event data,
+	// or a CEL expression when Lang is "cel".
 	When string `yaml:"when"`
+	// Lang selects the expression language of When: "govaluate" (the default)
+	// or "cel".
+	Lang string `yaml:"lang"`
 	// Emit is the topic to publish the event to if the 'When' expression is true.
 	Emit EmitList `yaml:"emit"`
 	// Drivers is a list of publisher drivers to use for this rule.
 	// If empty, the default drivers are used.
 	Drivers []string `yaml:"drivers"`
+	// Priority orders evaluation: higher priorities run first, and rules with
+	// equal priority run in config order.
+	Priority int `yaml:"priority"`
+	// Stop halts evaluation of the remaining rules when this rule matches.
+	Stop bool `yaml:"stop"`
+	// Provider limits the rule to events from these providers. Entries may be
+	// globs such as "bitbucket*". If empty, every provider matches.
+	Provider ScopeList `yaml:"provider"`
+	// Event limits the rule to these event names, with glob support such as
+	// "pull_request*". If empty, every event matches.
+	Event ScopeList `yaml:"event"`
+	// Transform projects the published body to these fields. If empty, the
+	// raw payload is published.
+	Transform Transform `yaml:"transform"`
 }
 
 // compiledRule is a pre-processed version of a Rule.
 type compiledRule struct {
-	emit    []string
-	drivers []string
-	vars    []string
-	varMap  map[string]string
-	expr    *govaluate.EvaluableExpression
+	index     int
+	priority  int
+	stop      bool
+	scope     ruleScope
+	lang      string
+	when      string
+	emit      []string
+	topics    []*topicTemplate
+	drivers   []string
+	transform *projection
+	vars      []string
+	varMap    map[string]string
+	expr      *govaluate.EvaluableExpression
+	cel       *celRule
 }
 
-// RuleEngine evaluates events against a set of rules.
+// ruleSet is an immutable, compiled set of rules.
+type ruleSet struct {
+	rules   []compiledRule
+	strict  bool
+	version int64
+	// fallback holds the topics for events no rule matched, keyed by
+	// provider; the "" key is the global default.
+	fallback map[string][]*topicTemplate
+	// byScope indexes the positions of rules by the literal provider and
+	// event names they are scoped to.
+	byScope map[scopeKey][]int
+}
+
+// RuleEngine evaluates events against a set of rules. The rule set can be
+// replaced at runtime with Update.
 type RuleEngine struct {
-	rules  []compiledRule
-	strict bool
+	set    atomic.Pointer[ruleSet]
 	logger *log.Logger
 }
 
 // RuleMatch represents a successful rule evaluation.
 type RuleMatch struct {
-	Topic   string
-	Drivers []string
+	Topic   string   `json:"topic"`
+	Drivers []string `json:"drivers"`
+	// Fallback is set for default_emit topics of events no rule matched.
+	Fallback bool `json:"fallback,omitempty"`
+	// Payload is the projected body of a rule with a transform. If empty, the
+	// raw payload is published.
+	Payload json.RawMessage `json:"payload,omitempty"`
 }
 
 // NewRuleEngine creates a new RuleEngine from a set of rules.
@@ -53,23 +102,134 @@ func NewRuleEngine(cfg RulesConfig) (*RuleEngine, error) {
 	if logger == nil {
 		logger = log.Default()
 	}
-	rules := make([]compiledRule, 0, len(cfg.Rules))
-	for _, rule := range cfg.Rules {
-		rewritten, varMap := rewriteExpression(rule.When)
-		expr, err := govaluate.NewEvaluableExpressionWithFunctions(rewritten, ruleFunctions())
+	set, err := compileRuleSet(cfg)
+	if err != nil {
+		return nil, err
+	}
+	set.version = 1
+	engine := &RuleEngine{logger: logger}
+	engine.set.Store(set)
+	return engine, nil
+}
+
+// Update compiles cfg and swaps it in as the active rule set. If any
+// expression fails to compile the active rules are left unchanged.
+func (r *RuleEngine) Update(cfg RulesConfig) error {
+	next, err := compileRuleSet(cfg)
+	if err != nil {
+		return err
+	}
+	for {
+		current := r.set.Load()
+		next.version = current.version + 1
+		if r.set.CompareAndSwap(current, next) {
+			return nil
+		}
+	}
+}
+
+// Version returns the version of the active rule set, starting at 1 and
+// incremented by every successful Update.
+func (r *RuleEngine) Version() int64 {
+	return r.set.Load().version
+}
+
+func compileRuleSet(cfg RulesConfig) (*ruleSet, error) {
+	rules, err := compileRules(cfg.Rules)
+	if err != nil {
+		return nil, err
+	}
+	set := &ruleSet{rules: rules, strict: cfg.Strict, fallback: make(map[string][]*topicTemplate)}
+	set.indexScopes()
+	if set.fallback[""], err = compileTopics(cfg.DefaultEmit.Values()); err != nil {
+		return nil, fmt.Errorf("default_emit: %w", err)
+	}
+	for provider, emit := range cfg.ProviderDefaultEmit {
+		if set.fallback[provider], err = compileTopics(emit.Values()); err != nil {
+			return nil, fmt.Errorf("provider_default_emit %s: %w", provider, err)
+		}
+	}
+	return set, nil
+}
+
+// compileRules compiles rules and orders them by descending priority.
+func compileRules(rules []Rule) ([]compiledRule, error) {
+	compiled := make([]compiledRule, 0, len(rules))
+	for i, rule := range rules {
+		lang := strings.ToLower(strings.TrimSpace(rule.Lang))
+		if lang == "" {
+			lang = RuleLangGovaluate
+		}
+		var (
+			expr    *govaluate.EvaluableExpression
+			varMap  map[string]string
+			vars    []string
+			program *celRule
+			err     error
+		)
+		switch lang {
+		case RuleLangGovaluate:
+			var rewritten string
+			rewritten, varMap = rewriteExpression(rule.When)
+			expr, err = govaluate.NewEvaluableExpressionWithFunctions(rewritten, ruleFunctions())
+			if err != nil {
+				return nil, fmt.Errorf("rule %d: %w", i, err)
+			}
+			vars = expr.Vars()
+		case RuleLangCEL:
+			program, err = compileCELRule(rule.When)
+			if err != nil {
+				return nil, fmt.Errorf("rule %d: %w", i, err)
+			}
+		default:
+			return nil, fmt.Errorf("rule %d: unsupported lang %q", i, rule.Lang)
+		}
+		scope, err := compileRuleScope(rule.Provider, rule.Event)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i, err)
+		}
+		emit := rule.Emit.Values()
+		topics, err := compileTopics(emit)
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %w", i, err)
+		}
+		transform, err := compileTransform(rule.Transform)
 		if err != nil {
-			return nil, err
-		}
-		rules = append(rules, compiledRule{
-			emit:    rule.Emit.Values(),
-			drivers: rule.Drivers,
-			vars:    expr.Vars(),
-			varMap:  varMap,
-			expr:    expr,
+			return nil, fmt.Errorf("rule %d: %w", i, err)
+		}
+		compiled = append(compiled, compiledRule{
+			index:     i,
+			priority:  rule.Priority,
+			stop:      rule.Stop,
+			scope:     scope,
+			lang:      lang,
+			when:      rule.When,
+			emit:      emit,
+			topics:    topics,
+			drivers:   rule.Drivers,
+			transform: transform,
+			vars:      vars,
+			varMap:    varMap,
+			expr:      expr,
+			cel:       program,
 		})
 	}
+	sort.SliceStable(compiled, func(i, j int) bool {
+		return compiled[i].priority > compiled[j].priority
+	})
+	return compiled, nil
+}
 
-	return &RuleEngine{rules: rules, strict: cfg.Strict, logger: logger}, nil
+func compileTopics(emit []string) ([]*topicTemplate, error) {
+	topics := make([]*topicTemplate, 0, len(emit))
+	for _, raw := range emit {
+		topic, err := compileTopicTemplate(raw)
+		if err != nil {
+			return nil, fmt.Errorf("emit %q: %w", raw, err)
+		}
+		topics = append(topics, topic)
+	}
+	return topics, nil
 }
 
 // EmitList supports either a string or list of strings in YAML.
@@ -77,7 +237,7 @@ type EmitList []string
 
 func (e *EmitList) UnmarshalYAML(value *yaml.Node) error {
 	switch value.Kind {
-	case yaml.Scalar:
+	case yaml.ScalarNode:
 		if value.Value == "" {
 			*e = nil
 			return nil
@@ -87,7 +247,7 @@ func (e *EmitList) UnmarshalYAML(value *yaml.Node) error {
 	case yaml.SequenceNode:
 		out := make([]string, 0, len(value.Content))
 		for _, item := range value.Content {
-			if item.Kind != yaml.Scalar {
+			if item.Kind != yaml.ScalarNode {
 				return fmt.Errorf("emit items must be strings")
 			}
 			out = append(out, item.Value)
@@ -111,10 +271,22 @@ func (e EmitList) Values() []string {
 }
 
 func ruleFunctions() map[string]govaluate.ExpressionFunction {
-	return map[string]govaluate.ExpressionFunction{
-		"contains": containsFunc,
-		"like":     likeFunc,
-	}
+	functions := map[string]govaluate.ExpressionFunction{
+		"contains":   containsFunc,
+		"like":       likeFunc,
+		"matches":    matchesFunc,
+		"startsWith": startsWithFunc,
+		"endsWith":   endsWithFunc,
+		"glob":       globFunc,
+		"lower":      lowerFunc,
+		"upper":      upperFunc,
+		"len":        lenFunc,
+		"semverGte":  semverGteFunc,
+		"inList":     inListFunc,
+	}
+	functions["any"] = quantifierFunc("any", false, functions)
+	functions["all"] = quantifierFunc("all", true, functions)
+	return functions
 }
 
 func containsFunc(args ...interface{}) (interface{}, error) {
@@ -133,6 +305,8 @@ func containsFunc(args ...interface{}) (interface{}, error) {
 		return strings.Contains(hay, needle), nil
 	case []interface{}:
 		return sliceContains(hay, args[1]), nil
+	case ruleList:
+		return sliceContains(hay, args[1]), nil
 	case []string:
 		needle, ok := args[1].(string)
 		if !ok {
@@ -212,34 +386,217 @@ func (r *RuleEngine) EvaluateWithLogger(event Event, logger *log.Logger) []RuleM
 }
 
 func (r *RuleEngine) evaluateWithLogger(event Event, logger *log.Logger) []RuleMatch {
-	if len(r.rules) == 0 {
-		return nil
-	}
+	set := r.set.Load()
 	if logger == nil {
 		logger = log.Default()
 	}
 
 	matches := make([]RuleMatch, 0, 1)
-	for _, rule := range r.rules {
-		params, missing := resolveRuleParams(logger, event, rule.vars, rule.varMap)
-		logger.Printf("rule debug: when=%q params=%v", rule.expr.String(), params)
-		if r.strict && len(missing) > 0 {
-			logger.Printf("rule strict missing params: %v", missing)
+	for _, position := range set.candidates(event.Provider, event.Name) {
+		rule := set.rules[position]
+		if !rule.scope.matches(event.Provider, event.Name) {
 			continue
 		}
-		result, err := rule.expr.Evaluate(params)
+		_, _, ok, err := rule.check(logger, event, set.strict)
 		if err != nil {
 			logger.Printf("rule eval failed: %v", err)
 			continue
 		}
-		ok, _ := result.(bool)
+		if !ok {
+			continue
+		}
+		ruleMatches, errs := rule.matches(event)
+		for _, err := range errs {
+			logger.Printf("rule emit failed: %v", err)
+		}
+		matches = append(matches, ruleMatches...)
+		if rule.stop {
+			break
+		}
+	}
+	if len(matches) == 0 {
+		topics, errs := set.fallbackTopics(event)
+		for _, err := range errs {
+			logger.Printf("rule fallback emit failed: %v", err)
+		}
+		for _, topic := range topics {
+			matches = append(matches, RuleMatch{Topic: topic, Fallback: true})
+		}
+	}
+	return matches
+}
+
+// fallbackTopics renders the provider's fallback topics, or the global ones
+// when the provider has none.
+func (set *ruleSet) fallbackTopics(event Event) ([]string, []error) {
+	templates, ok := set.fallback[event.Provider]
+	if !ok || len(templates) == 0 {
+		templates = set.fallback[""]
+	}
+	return renderTopics(templates, event, nil)
+}
+
+// Explanation describes how the rules evaluated an event.
+type Explanation struct {
+	// Rules are in evaluation order.
+	Rules []RuleExplanation `json:"rules"`
+	// Matches are the topics the event would be published to.
+	Matches []RuleMatch `json:"matches"`
+}
+
+// RuleExplanation describes how one rule evaluated against an event.
+type RuleExplanation struct {
+	// Index is the position of the rule in the config.
+	Index    int    `json:"index"`
+	Priority int    `json:"priority"`
+	Stop     bool   `json:"stop"`
+	Lang     string `json:"lang"`
+	When     string `json:"when"`
+	// Params holds the resolved value of each JSONPath or field in When.
+	Params  map[string]interface{} `json:"params"`
+	Missing []string               `json:"missing"`
+	Matched bool                   `json:"matched"`
+	Error   string                 `json:"error,omitempty"`
+	Emit    []string               `json:"emit"`
+	// Topics are the rendered Emit topics of a matched rule.
+	Topics  []string `json:"topics"`
+	Drivers []string `json:"drivers"`
+	// Skipped is set when an earlier rule with stop matched.
+	Skipped bool `json:"skipped,omitempty"`
+	// OutOfScope is set when the rule's provider or event does not match.
+	OutOfScope bool `json:"out_of_scope,omitempty"`
+}
+
+// Explain evaluates the rules against event like Evaluate and reports the
+// resolved parameters and result of each, without logging.
+func (r *RuleEngine) Explain(event Event) Explanation {
+	set := r.set.Load()
+	logger := log.New(io.Discard, "", 0)
+	out := Explanation{Rules: make([]RuleExplanation, 0, len(set.rules)), Matches: []RuleMatch{}}
+	stopped := false
+	for _, rule := range set.rules {
+		explanation := RuleExplanation{
+			Index:    rule.index,
+			Priority: rule.priority,
+			Stop:     rule.stop,
+			Lang:     rule.lang,
+			When:     rule.when,
+			Params:   map[string]interface{}{},
+			Missing:  []string{},
+			Emit:     rule.emit,
+			Topics:   []string{},
+			Drivers:  rule.drivers,
+			Skipped:  stopped,
+		}
+		if !rule.scope.matches(event.Provider, event.Name) {
+			explanation.OutOfScope = true
+		}
+		if stopped || explanation.OutOfScope {
+			out.Rules = append(out.Rules, explanation)
+			continue
+		}
+		params, missing, ok, err := rule.check(logger, event, set.strict)
+		for _, name := range rule.vars {
+			key := name
+			if path, ok := rule.varMap[name]; ok {
+				key = path
+			}
+			explanation.Params[key] = params[name]
+		}
+		if missing != nil {
+			explanation.Missing = missing
+		}
+		if err != nil {
+			explanation.Error = err.Error()
+		}
+		explanation.Matched = ok
 		if ok {
-			for _, topic := range rule.emit {
-				matches = append(matches, RuleMatch{Topic: topic, Drivers: rule.drivers})
+			ruleMatches, errs := rule.matches(event)
+			for _, match := range ruleMatches {
+				explanation.Topics = append(explanation.Topics, match.Topic)
 			}
+			if len(errs) > 0 {
+				explanation.Error = errors.Join(errs...).Error()
+			}
+			out.Matches = append(out.Matches, ruleMatches...)
+			stopped = rule.stop
 		}
+		out.Rules = append(out.Rules, explanation)
 	}
-	return matches
+	if len(out.Matches) == 0 {
+		topics, _ := set.fallbackTopics(event)
+		for _, topic := range topics {
+			out.Matches = append(out.Matches, RuleMatch{Topic: topic, Fallback: true})
+		}
+	}
+	return out
+}
+
+// matches renders the rule's topics and projected payload for event. If the
+// transform fails the rule emits nothing rather than the raw payload.
+func (rule compiledRule) matches(event Event) ([]RuleMatch, []error) {
+	topics, errs := rule.renderTopics(event)
+	var payload json.RawMessage
+	if rule.transform != nil && len(topics) > 0 {
+		encoded, err := rule.transform.render(event)
+		if err != nil {
+			return nil, append(errs, fmt.Errorf("rule %d transform: %w", rule.index, err))
+		}
+		payload = encoded
+	}
+	matches := make([]RuleMatch, 0, len(topics))
+	for _, topic := range topics {
+		matches = append(matches, RuleMatch{Topic: topic, Drivers: rule.drivers, Payload: payload})
+	}
+	return matches, errs
+}
+
+// renderTopics renders the rule's emit topics for event. Topics that fail to
+// render are skipped and their errors returned.
+func (rule compiledRule) renderTopics(event Event) ([]string, []error) {
+	return renderTopics(rule.topics, event, rule.drivers)
+}
+
+func renderTopics(templates []*topicTemplate, event Event, drivers []string) ([]string, []error) {
+	topics := make([]string, 0, len(templates))
+	var errs []error
+	for _, topic := range templates {
+		rendered, err := topic.render(event, drivers)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("emit %q: %w", topic.raw, err))
+			continue
+		}
+		topics = append(topics, rendered)
+	}
+	return topics, errs
+}
+
+// check evaluates the rule's condition against event. Params and missing are
+// the resolved govaluate parameters; CEL rules have none.
+func (rule compiledRule) check(logger *log.Logger, event Event, strict bool) (map[string]interface{}, []string, bool, error) {
+	if rule.cel != nil {
+		logger.Printf("rule debug: cel=%q", rule.when)
+		ok, err := rule.cel.evaluate(event, strict)
+		return nil, nil, ok, err
+	}
+	params, missing := resolveRuleParams(logger, event, rule.vars, rule.varMap)
+	logger.Printf("rule debug: when=%q params=%v", rule.expr.String(), params)
+	ok, err := rule.evaluate(params, missing, strict)
+	return params, missing, ok, err
+}
+
+// evaluate runs the rule's expression against params. In strict mode a rule
+// with missing params fails without being evaluated.
+func (rule compiledRule) evaluate(params map[string]interface{}, missing []string, strict bool) (bool, error) {
+	if strict && len(missing) > 0 {
+		return false, fmt.Errorf("rule strict missing params: %v", missing)
+	}
+	result, err := rule.expr.Evaluate(params)
+	if err != nil {
+		return false, err
+	}
+	ok, _ := result.(bool)
+	return ok, nil
 }
 
 func resolveRuleParams(logger *log.Logger, event Event, vars []string, varMap map[string]string) (map[string]interface{}, []string) {
@@ -267,12 +624,12 @@ func resolveRuleParams(logger *log.Logger, event Event, vars []string, varMap ma
 					missing = append(missing, path)
 					logger.Printf("rule warn: jsonpath no match path=%s", path)
 				}
-				params[name] = value
+				params[name] = ruleParam(value)
 			}
 			continue
 		}
 		if value, ok := event.Data[name]; ok {
-			params[name] = value
+			params[name] = ruleParam(value)
 		} else {
 			missing = append(missing, name)
 			params[name] = nil
@@ -281,21 +638,44 @@ func resolveRuleParams(logger *log.Logger, event Event, vars []string, varMap ma
 	return params, missing
 }
 
+// ruleList wraps array parameters so govaluate does not splat them into
+// function arguments when they appear before a comma.
+type ruleList []interface{}
+
+func ruleParam(value interface{}) interface{} {
+	if items, ok := value.([]interface{}); ok {
+		return ruleList(items)
+	}
+	return value
+}
+
+// normalizedPathPrefix selects the normalized event model instead of the raw
+// payload, e.g. normalized.pull_request.state.
+const normalizedPathPrefix = "$.normalized"
+
 func resolveJSONPath(event Event, path string) (interface{}, error) {
-	if event.RawObject != nil {
-		value, err := jsonpath.Get(path, event.RawObject)
-		if err != nil {
-			return nil, err
+	value, err := lookupJSONPath(event, path)
+	if err != nil {
+		return nil, err
+	}
+	return normalizeJSONPathResult(value), nil
+}
+
+// lookupJSONPath resolves path against event without collapsing single-item
+// results, so lists keep their shape.
+func lookupJSONPath(event Event, path string) (interface{}, error) {
+	if rest, ok := strings.CutPrefix(path, normalizedPathPrefix); ok && (rest == "" || rest[0] == '.' || rest[0] == '[') {
+		if event.Normalized == nil {
+			return nil, nil
 		}
-		return normalizeJSONPathResult(value), nil
+		return jsonpath.Get("$"+rest, event.Normalized)
+	}
+	if event.RawObject != nil {
+		return jsonpath.Get(path, event.RawObject)
 	}
 	if len(event.RawPayload) == 0 {
 		if event.Data != nil {
-			value, err := jsonpath.Get(path, event.Data)
-			if err != nil {
-				return nil, err
-			}
-			return normalizeJSONPathResult(value), nil
+			return jsonpath.Get(path, event.Data)
 		}
 		return nil, nil
 	}
@@ -303,11 +683,7 @@ func resolveJSONPath(event Event, path string) (interface{}, error) {
 	if err := json.Unmarshal(event.RawPayload, &raw); err != nil {
 		return nil, err
 	}
-	value, err := jsonpath.Get(path, raw)
-	if err != nil {
-		return nil, err
-	}
-	return normalizeJSONPathResult(value), nil
+	return jsonpath.Get(path, raw)
 }
 
 func normalizeJSONPathResult(value interface{}) interface{} {
@@ -359,7 +735,7 @@ func rewriteExpression(expr string) (string, map[string]string) {
 
 		if ch == '$' || isIdentStart(ch) {
 			token, next := parseJSONPathToken(expr, i)
-			if isKeyword(token) {
+			if isKeyword(token) || isFunctionCall(expr, next) {
 				out.WriteString(token)
 				i = next
 				continue
@@ -415,9 +791,10 @@ func parseJSONPathToken(expr string, start int) (string, int) {
 				bracketDepth--
 			}
 		case '(':
-			if bracketDepth > 0 {
-				parenDepth++
+			if bracketDepth == 0 {
+				return expr[start:i], i
 			}
+			parenDepth++
 		case ')':
 			if parenDepth > 0 {
 				parenDepth--
@@ -433,6 +810,10 @@ func parseJSONPathToken(expr string, start int) (string, int) {
 	return expr[start:i], i
 }
 
+func isFunctionCall(expr string, next int) bool {
+	return next < len(expr) && expr[next] == '('
+}
+
 func isTerminator(ch byte) bool {
 	switch ch {
 	case ' ', '\t', '\n', '\r', ',', ';':
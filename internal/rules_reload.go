@@ -0,0 +1,106 @@
+package internal
+
+import (
+	"context"
+	"expvar"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// rulesStats exposes the server's active rule-set version and reload outcomes
+// on /debug/vars. Only the reloader updates it, so other engines in the process
+// (e.g., `githooks rules test`) do not affect it.
+var rulesStats = expvar.NewMap("githooks_rules")
+
+// RulesReloader reloads the rules of a RuleEngine from the config file on
+// SIGHUP and, when watching, whenever the file changes.
+type RulesReloader struct {
+	path   string
+	engine *RuleEngine
+	logger *log.Logger
+
+	modTime time.Time
+	size    int64
+}
+
+// NewRulesReloader creates a reloader for the rules in the config file at path.
+func NewRulesReloader(path string, engine *RuleEngine, logger *log.Logger) *RulesReloader {
+	if logger == nil {
+		logger = log.Default()
+	}
+	reloader := &RulesReloader{path: path, engine: engine, logger: logger}
+	reloader.changed()
+	setRulesVersion(engine.Version())
+	return reloader
+}
+
+// Reload loads the rules from the config file and swaps them into the engine.
+// The active rules are kept if the file cannot be loaded or a rule fails to compile.
+func (r *RulesReloader) Reload() error {
+	cfg, err := LoadConfig(r.path)
+	if err != nil {
+		rulesStats.Add("reload_failures", 1)
+		r.logger.Printf("rules reload failed path=%s: %v", r.path, err)
+		return err
+	}
+	if err := r.engine.Update(cfg.RulesConfig()); err != nil {
+		rulesStats.Add("reload_failures", 1)
+		r.logger.Printf("rules reload failed path=%s: %v", r.path, err)
+		return err
+	}
+	rulesStats.Add("reloads", 1)
+	setRulesVersion(r.engine.Version())
+	r.logger.Printf("rules reloaded path=%s version=%d rules=%d", r.path, r.engine.Version(), len(cfg.Rules))
+	return nil
+}
+
+// Run reloads the rules on SIGHUP and, if interval is positive, when the
+// file's modification time or size changes. It blocks until ctx is canceled.
+func (r *RulesReloader) Run(ctx context.Context, interval time.Duration) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	var tick <-chan time.Time
+	if interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+			r.changed()
+			_ = r.Reload()
+		case <-tick:
+			if r.changed() {
+				_ = r.Reload()
+			}
+		}
+	}
+}
+
+func setRulesVersion(version int64) {
+	value := new(expvar.Int)
+	value.Set(version)
+	rulesStats.Set("version", value)
+}
+
+// changed records the file's current modification time and size and reports
+// whether either differs from the last observation.
+func (r *RulesReloader) changed() bool {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return false
+	}
+	if info.ModTime().Equal(r.modTime) && info.Size() == r.size {
+		return false
+	}
+	r.modTime, r.size = info.ModTime(), info.Size()
+	return true
+}
@@ -0,0 +1,90 @@
+package internal
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestRulesReloaderReload tests that a reload swaps in valid rules and keeps
+// the active rules when an expression fails to compile.
+func TestRulesReloaderReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	write := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("write config: %v", err)
+		}
+	}
+	write("rules:\n  - when: action == \"opened\"\n    emit: pr.opened\n")
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	engine, err := NewRuleEngine(RulesConfig{Rules: cfg.Rules, Logger: log.New(io.Discard, "", 0)})
+	if err != nil {
+		t.Fatalf("new rule engine: %v", err)
+	}
+	reloader := NewRulesReloader(path, engine, log.New(io.Discard, "", 0))
+	event := Event{Provider: "github", Name: "pull_request", RawPayload: []byte(`{"action":"closed"}`)}
+
+	write("rules:\n  - when: action == \"closed\"\n    emit: pr.closed\n")
+	if !reloader.changed() {
+		t.Fatalf("expected file change to be detected")
+	}
+	if err := reloader.Reload(); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if engine.Version() != 2 {
+		t.Fatalf("expected version 2, got %d", engine.Version())
+	}
+	if matches := engine.Evaluate(event); len(matches) != 1 || matches[0].Topic != "pr.closed" {
+		t.Fatalf("expected reloaded rule to match, got %v", matches)
+	}
+
+	write("rules:\n  - when: action == \"closed\"\n    emit: pr.closed\n  - when: action ==\n    emit: broken\n")
+	if err := reloader.Reload(); err == nil {
+		t.Fatalf("expected invalid rules to fail")
+	}
+	if engine.Version() != 2 {
+		t.Fatalf("expected version to stay 2, got %d", engine.Version())
+	}
+	if matches := engine.Evaluate(event); len(matches) != 1 || matches[0].Topic != "pr.closed" {
+		t.Fatalf("expected previous rules to stay active, got %v", matches)
+	}
+}
+
+// TestRulesStatsOnlyTrackReloader tests that the githooks_rules version follows the
+// reloaded engine and is not reset by other engines.
+func TestRulesStatsOnlyTrackReloader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("rules:\n  - when: action == \"opened\"\n    emit: pr.opened\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	rules := RulesConfig{Rules: []Rule{{When: `action == "opened"`, Emit: EmitList{"pr.opened"}}}, Logger: log.New(io.Discard, "", 0)}
+	engine, err := NewRuleEngine(rules)
+	if err != nil {
+		t.Fatalf("new rule engine: %v", err)
+	}
+	reloader := NewRulesReloader(path, engine, log.New(io.Discard, "", 0))
+	if err := reloader.Reload(); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	version := func() string { return rulesStats.Get("version").String() }
+	if version() != "2" {
+		t.Fatalf("expected version 2, got %s", version())
+	}
+
+	other, err := NewRuleEngine(rules)
+	if err != nil {
+		t.Fatalf("new rule engine: %v", err)
+	}
+	if err := other.Update(rules); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if version() != "2" {
+		t.Fatalf("expected other engines to leave the version alone, got %s", version())
+	}
+}
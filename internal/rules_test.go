@@ -1,6 +1,12 @@
 package internal
 
-import "testing"
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
 
 // TestRuleEngineEvaluate tests that the rule engine correctly evaluates a simple rule.
 func TestRuleEngineEvaluate(t *testing.T) {
@@ -211,6 +217,28 @@ func TestRuleEngineStrictMissing(t *testing.T) {
 	}
 }
 
+// TestRewriteExpressionFunctionCalls tests that function names are kept and list arguments are not splatted.
+func TestRewriteExpressionFunctionCalls(t *testing.T) {
+	rewritten, varMap := rewriteExpression(`contains($.labels[*].name, "bug") && like(ref, "refs/%")`)
+	if want := `contains(v___labels____name, "bug") && like(v___ref, "refs/%")`; rewritten != want {
+		t.Fatalf("expected %s, got %s", want, rewritten)
+	}
+	if len(varMap) != 2 || varMap["v___labels____name"] != "$.labels[*].name" || varMap["v___ref"] != "$.ref" {
+		t.Fatalf("unexpected vars: %v", varMap)
+	}
+
+	engine, err := NewRuleEngine(RulesConfig{
+		Rules: []Rule{{When: `contains($.labels[*].name, "bug")`, Emit: EmitList{"label.bug"}}},
+	})
+	if err != nil {
+		t.Fatalf("new rule engine: %v", err)
+	}
+	matches := engine.Evaluate(Event{RawPayload: []byte(`{"labels":[{"name":"ui"},{"name":"bug"}]}`)})
+	if len(matches) != 1 {
+		t.Fatalf("expected list argument to match, got %d matches", len(matches))
+	}
+}
+
 func TestRuleEngineFunctions(t *testing.T) {
 	cfg := RulesConfig{
 		Rules: []Rule{
@@ -235,3 +263,283 @@ func TestRuleEngineFunctions(t *testing.T) {
 		t.Fatalf("expected 2 matches, got %d", len(matches))
 	}
 }
+
+// TestRuleEngineExplain tests that Explain reports params, missing fields and results per rule.
+func TestRuleEngineExplain(t *testing.T) {
+	engine, err := NewRuleEngine(RulesConfig{
+		Rules: []Rule{
+			{When: "action == \"opened\" && $.pull_request.draft == false", Emit: EmitList{"pr.ready"}, Drivers: []string{"amqp"}},
+			{When: "missing == true", Emit: EmitList{"never"}},
+		},
+		Strict: true,
+	})
+	if err != nil {
+		t.Fatalf("new rule engine: %v", err)
+	}
+	event := Event{
+		Provider:   "github",
+		Name:       "pull_request",
+		RawPayload: []byte(`{"action":"opened","pull_request":{"draft":false}}`),
+		Data:       map[string]interface{}{"action": "opened"},
+	}
+
+	rules := engine.Explain(event).Rules
+	if len(rules) != 2 {
+		t.Fatalf("expected 2 explanations, got %d", len(rules))
+	}
+	first := rules[0]
+	if !first.Matched || first.Params["$.pull_request.draft"] != false || first.Params["$.action"] != "opened" || len(first.Missing) != 0 {
+		t.Fatalf("unexpected first explanation: %+v", first)
+	}
+	if first.Emit[0] != "pr.ready" || first.Drivers[0] != "amqp" {
+		t.Fatalf("expected emit and drivers, got %+v", first)
+	}
+	second := rules[1]
+	if second.Matched || len(second.Missing) != 1 || second.Error == "" {
+		t.Fatalf("expected strict missing failure, got %+v", second)
+	}
+}
+
+// TestRuleEnginePriorityStopFallback tests priority ordering, stop-on-match and fallback topics.
+func TestRuleEnginePriorityStopFallback(t *testing.T) {
+	engine, err := NewRuleEngine(RulesConfig{
+		Rules: []Rule{
+			{When: "action == \"opened\"", Emit: EmitList{"pr.opened"}},
+			{When: "action == \"opened\" && draft == true", Emit: EmitList{"pr.draft"}, Priority: 10, Stop: true},
+			{When: "action == \"opened\"", Emit: EmitList{"audit"}, Priority: 5},
+		},
+		DefaultEmit:         EmitList{"unmatched"},
+		ProviderDefaultEmit: map[string]EmitList{"gitlab": {"gitlab.unmatched.{{ $.object_kind }}"}},
+	})
+	if err != nil {
+		t.Fatalf("new rule engine: %v", err)
+	}
+	topics := func(provider, payload string) string {
+		var out []string
+		for _, match := range engine.Evaluate(Event{Provider: provider, Name: "test", RawPayload: []byte(payload)}) {
+			out = append(out, match.Topic)
+		}
+		return strings.Join(out, ",")
+	}
+
+	if got := topics("github", `{"action":"opened","draft":false}`); got != "audit,pr.opened" {
+		t.Fatalf("expected priority order, got %s", got)
+	}
+	if got := topics("github", `{"action":"opened","draft":true}`); got != "pr.draft" {
+		t.Fatalf("expected stop after draft rule, got %s", got)
+	}
+	if got := topics("github", `{"action":"closed","draft":false}`); got != "unmatched" {
+		t.Fatalf("expected default fallback, got %s", got)
+	}
+	if got := topics("gitlab", `{"action":"closed","draft":false,"object_kind":"note"}`); got != "gitlab.unmatched.note" {
+		t.Fatalf("expected provider fallback, got %s", got)
+	}
+
+	explanation := engine.Explain(Event{Provider: "github", Name: "test", RawPayload: []byte(`{"action":"opened","draft":true}`)})
+	if explanation.Rules[0].Index != 1 || !explanation.Rules[0].Matched || !explanation.Rules[1].Skipped || !explanation.Rules[2].Skipped {
+		t.Fatalf("expected later rules to be skipped, got %+v", explanation.Rules)
+	}
+
+	_, err = NewRuleEngine(RulesConfig{Rules: []Rule{
+		{When: "true", Emit: EmitList{"low"}},
+		{When: "action == (", Emit: EmitList{"high"}, Priority: 10},
+	}})
+	if err == nil || !strings.HasPrefix(err.Error(), "rule 1: ") {
+		t.Fatalf("expected expression error to name the rule's config index, got %v", err)
+	}
+}
+
+// TestRuleEngineScope tests provider and event scoping of rules.
+func TestRuleEngineScope(t *testing.T) {
+	engine, err := NewRuleEngine(RulesConfig{
+		Rules: []Rule{
+			{When: "action == \"opened\"", Emit: EmitList{"any.opened"}},
+			{When: "action == \"opened\"", Emit: EmitList{"github.pr.opened"}, Provider: ScopeList{"github"}, Event: ScopeList{"pull_request*"}},
+			{When: "action == \"opened\"", Emit: EmitList{"bitbucket.opened"}, Provider: ScopeList{"bitbucket*"}},
+			{When: "action == \"opened\"", Emit: EmitList{"gitlab.opened"}, Provider: ScopeList{"gitlab"}, Priority: 5},
+		},
+		Strict: true,
+	})
+	if err != nil {
+		t.Fatalf("new rule engine: %v", err)
+	}
+	topics := func(provider, name string) string {
+		var out []string
+		for _, match := range engine.Evaluate(Event{Provider: provider, Name: name, RawPayload: []byte(`{"action":"opened"}`)}) {
+			out = append(out, match.Topic)
+		}
+		return strings.Join(out, ",")
+	}
+
+	if got := topics("github", "pull_request_review"); got != "any.opened,github.pr.opened" {
+		t.Fatalf("expected github scoped match, got %s", got)
+	}
+	if got := topics("github", "issues"); got != "any.opened" {
+		t.Fatalf("expected event scope to filter, got %s", got)
+	}
+	if got := topics("gitlab", "Merge Request Hook"); got != "gitlab.opened,any.opened" {
+		t.Fatalf("expected priority order across index, got %s", got)
+	}
+	if got := topics("bitbucket-server", "pr:opened"); got != "any.opened,bitbucket.opened" {
+		t.Fatalf("expected provider glob match, got %s", got)
+	}
+
+	explanation := engine.Explain(Event{Provider: "gitlab", Name: "Push Hook", RawPayload: []byte(`{}`)})
+	for _, rule := range explanation.Rules {
+		if wantOut := rule.Index == 1 || rule.Index == 2; rule.OutOfScope != wantOut {
+			t.Fatalf("unexpected out_of_scope for rule %d: %+v", rule.Index, rule)
+		}
+	}
+
+	if _, err := NewRuleEngine(RulesConfig{Rules: []Rule{{When: "true", Emit: EmitList{"x"}, Event: ScopeList{"[bad"}}}}); err == nil {
+		t.Fatalf("expected invalid event pattern error")
+	}
+}
+
+// TestRuleSetCandidates tests that the scope index narrows rules by literal provider and event.
+func TestRuleSetCandidates(t *testing.T) {
+	set, err := compileRuleSet(RulesConfig{Rules: []Rule{
+		{When: "true", Emit: EmitList{"any"}},
+		{When: "true", Emit: EmitList{"github.push"}, Provider: ScopeList{"github"}, Event: ScopeList{"push"}},
+		{When: "true", Emit: EmitList{"push"}, Event: ScopeList{"push", "Push Hook"}},
+		{When: "true", Emit: EmitList{"github"}, Provider: ScopeList{"github"}, Event: ScopeList{"pull_request*"}},
+		{When: "true", Emit: EmitList{"gitlab.push"}, Provider: ScopeList{"gitlab"}, Event: ScopeList{"Push Hook"}},
+	}})
+	if err != nil {
+		t.Fatalf("compile rule set: %v", err)
+	}
+	cases := []struct {
+		provider, event string
+		want            []int
+	}{
+		{provider: "github", event: "push", want: []int{0, 1, 2, 3}},
+		{provider: "github", event: "issues", want: []int{0, 3}},
+		{provider: "gitlab", event: "Push Hook", want: []int{0, 2, 4}},
+		{provider: "gitea", event: "push", want: []int{0, 2}},
+		{provider: "gitea", event: "", want: []int{0}},
+	}
+	for _, tc := range cases {
+		if got := set.candidates(tc.provider, tc.event); !slices.Equal(got, tc.want) {
+			t.Fatalf("%s/%s: expected candidates %v, got %v", tc.provider, tc.event, tc.want, got)
+		}
+	}
+}
+
+// TestScopeListYAML tests that provider and event accept a string or list.
+func TestScopeListYAML(t *testing.T) {
+	var rules []Rule
+	data := "- when: \"true\"\n  emit: a\n  provider: github\n  event: [push, \"pull_request*\"]\n"
+	if err := yaml.Unmarshal([]byte(data), &rules); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(rules[0].Provider) != 1 || rules[0].Provider[0] != "github" || len(rules[0].Event) != 2 {
+		t.Fatalf("unexpected scope: %+v", rules[0])
+	}
+}
+
+// TestRuleEngineTransform tests that a rule transform projects the payload of its matches.
+func TestRuleEngineTransform(t *testing.T) {
+	engine, err := NewRuleEngine(RulesConfig{
+		Rules: []Rule{
+			{When: "action == \"opened\"", Emit: EmitList{"pr.opened"}, Transform: Transform{
+				"action": "action",
+				"labels": "$.labels[*].name",
+				"repo":   map[string]interface{}{"name": "$.repository.name", "kind": "$.normalized.kind"},
+				"absent": "missing",
+			}},
+			{When: "action == \"opened\"", Emit: EmitList{"pr.raw"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("new rule engine: %v", err)
+	}
+	event := Event{
+		Provider:   "github",
+		RawPayload: []byte(`{"action":"opened","labels":[{"name":"bug"}],"repository":{"name":"api","private":true}}`),
+		Normalized: map[string]interface{}{"kind": "pull_request"},
+	}
+	matches := engine.Evaluate(event)
+	if len(matches) != 2 {
+		t.Fatalf("expected 2 matches, got %+v", matches)
+	}
+	want := `{"absent":null,"action":"opened","labels":["bug"],"repo":{"kind":"pull_request","name":"api"}}`
+	if string(matches[0].Payload) != want {
+		t.Fatalf("expected projection %s, got %s", want, matches[0].Payload)
+	}
+	if matches[1].Payload != nil {
+		t.Fatalf("expected raw payload for rule without transform, got %s", matches[1].Payload)
+	}
+
+	if _, err := NewRuleEngine(RulesConfig{Rules: []Rule{{When: "true", Emit: EmitList{"x"}, Transform: Transform{"n": 1}}}}); err == nil {
+		t.Fatalf("expected invalid transform error")
+	}
+}
+
+// TestRuleEngineCEL tests CEL rules, macros, missing keys and load-time type checks.
+func TestRuleEngineCEL(t *testing.T) {
+	engine, err := NewRuleEngine(RulesConfig{
+		Rules: []Rule{
+			{Lang: "cel", When: `payload.commits.exists(c, c.modified.exists(f, f.startsWith("docs/")))`, Emit: EmitList{"docs.changed"}},
+			{Lang: "cel", When: `payload["x-team"] == "core" && provider == "github" && payload.size == 2`, Emit: EmitList{"core"}},
+			{Lang: "cel", When: `payload.pull_request.merged`, Emit: EmitList{"pr.merged"}},
+			{Lang: "cel", When: `normalized.kind == "push"`, Emit: EmitList{"push"}},
+			{When: `size == 2`, Emit: EmitList{"govaluate"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("new rule engine: %v", err)
+	}
+	event := Event{
+		Provider:   "github",
+		Name:       "push",
+		RawPayload: []byte(`{"x-team":"core","size":2,"commits":[{"modified":["README.md"]},{"modified":["docs/rules.md"]}]}`),
+		Normalized: map[string]interface{}{"kind": "push"},
+	}
+	var topics []string
+	for _, match := range engine.Evaluate(event) {
+		topics = append(topics, match.Topic)
+	}
+	if got := strings.Join(topics, ","); got != "docs.changed,core,push,govaluate" {
+		t.Fatalf("unexpected topics: %s", got)
+	}
+
+	strict, err := NewRuleEngine(RulesConfig{
+		Rules:  []Rule{{Lang: "cel", When: `payload.pull_request.merged`, Emit: EmitList{"pr.merged"}}},
+		Strict: true,
+	})
+	if err != nil {
+		t.Fatalf("new strict rule engine: %v", err)
+	}
+	explanation := strict.Explain(event)
+	if explanation.Rules[0].Lang != "cel" || !strings.Contains(explanation.Rules[0].Error, "strict") {
+		t.Fatalf("expected strict missing key error, got %+v", explanation.Rules[0])
+	}
+
+	// Errors raised by a field selection or index are missing keys; other
+	// evaluation errors are reported as is.
+	missing := map[string]bool{
+		`payload["x-owner"] == "core"`:           true,
+		`payload.commits[5].modified.size() > 0`: true,
+		`payload.size / (payload.size - 2) == 1`: false,
+		`int(payload["x-team"]) > 0`:             false,
+	}
+	for when, isMissing := range missing {
+		engine, err := NewRuleEngine(RulesConfig{Rules: []Rule{{Lang: "cel", When: when, Emit: EmitList{"x"}}}, Strict: true})
+		if err != nil {
+			t.Fatalf("new rule engine %q: %v", when, err)
+		}
+		errText := engine.Explain(event).Rules[0].Error
+		if errText == "" || strings.Contains(errText, "strict") != isMissing {
+			t.Fatalf("%q: expected missing=%t, got error %q", when, isMissing, errText)
+		}
+	}
+
+	for _, when := range []string{`payload.action ==`, `"opened"`, `size(provider) + "x"`} {
+		if _, err := NewRuleEngine(RulesConfig{Rules: []Rule{{Lang: "cel", When: when, Emit: EmitList{"x"}}}}); err == nil {
+			t.Fatalf("expected compile error for %q", when)
+		}
+	}
+	if _, err := NewRuleEngine(RulesConfig{Rules: []Rule{{Lang: "jq", When: "true", Emit: EmitList{"x"}}}}); err == nil {
+		t.Fatalf("expected unsupported lang error")
+	}
+}
@@ -0,0 +1,310 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/PaesslerAG/jsonpath"
+)
+
+// topicTemplate is a compiled emit topic. Expressions between {{ and }} are a
+// JSONPath optionally piped through functions, e.g.
+// push.{{ $.repository.name }}.{{ $.ref | trimPrefix "refs/heads/" }}.
+type topicTemplate struct {
+	raw   string
+	parts []topicPart
+}
+
+// topicPart is either literal text or an expression.
+type topicPart struct {
+	literal string
+	expr    string
+	path    string
+	funcs   []topicFuncCall
+}
+
+type topicFuncCall struct {
+	name string
+	args []string
+}
+
+// topicFuncs are the functions available in topic expressions with their
+// argument counts. Each receives the piped value as its last argument.
+var topicFuncs = map[string]struct {
+	args int
+	fn   func(value string, args []string) string
+}{
+	"lower":      {0, func(value string, _ []string) string { return strings.ToLower(value) }},
+	"upper":      {0, func(value string, _ []string) string { return strings.ToUpper(value) }},
+	"trimPrefix": {1, func(value string, args []string) string { return strings.TrimPrefix(value, args[0]) }},
+	"trimSuffix": {1, func(value string, args []string) string { return strings.TrimSuffix(value, args[0]) }},
+	"replace":    {2, func(value string, args []string) string { return strings.ReplaceAll(value, args[0], args[1]) }},
+	"default": {1, func(value string, args []string) string {
+		if value == "" {
+			return args[0]
+		}
+		return value
+	}},
+}
+
+// compileTopicTemplate parses raw, validating every JSONPath and function.
+func compileTopicTemplate(raw string) (*topicTemplate, error) {
+	tmpl := &topicTemplate{raw: raw}
+	rest := raw
+	for rest != "" {
+		start := strings.Index(rest, "{{")
+		if start < 0 {
+			if strings.Contains(rest, "}}") {
+				return nil, fmt.Errorf("unexpected }}")
+			}
+			tmpl.parts = append(tmpl.parts, topicPart{literal: rest})
+			break
+		}
+		if start > 0 {
+			if strings.Contains(rest[:start], "}}") {
+				return nil, fmt.Errorf("unexpected }}")
+			}
+			tmpl.parts = append(tmpl.parts, topicPart{literal: rest[:start]})
+		}
+		end := strings.Index(rest[start:], "}}")
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated {{")
+		}
+		part, err := compileTopicExpr(rest[start+2 : start+end])
+		if err != nil {
+			return nil, err
+		}
+		tmpl.parts = append(tmpl.parts, part)
+		rest = rest[start+end+2:]
+	}
+	return tmpl, nil
+}
+
+func compileTopicExpr(expr string) (topicPart, error) {
+	part := topicPart{expr: strings.TrimSpace(expr)}
+	segments, err := splitPipeline(part.expr)
+	if err != nil {
+		return part, fmt.Errorf("{{ %s }}: %w", part.expr, err)
+	}
+	part.path = strings.TrimSpace(segments[0])
+	if part.path == "" {
+		return part, fmt.Errorf("{{ %s }}: missing JSONPath", part.expr)
+	}
+	if !strings.HasPrefix(part.path, "$") {
+		part.path = "$." + part.path
+	}
+	if _, err := jsonpath.New(part.path); err != nil {
+		return part, fmt.Errorf("{{ %s }}: %w", part.expr, err)
+	}
+	for _, segment := range segments[1:] {
+		words, err := splitWords(segment)
+		if err != nil {
+			return part, fmt.Errorf("{{ %s }}: %w", part.expr, err)
+		}
+		if len(words) == 0 {
+			return part, fmt.Errorf("{{ %s }}: empty function", part.expr)
+		}
+		fn, ok := topicFuncs[words[0]]
+		if !ok {
+			return part, fmt.Errorf("{{ %s }}: unknown function %q", part.expr, words[0])
+		}
+		if len(words)-1 != fn.args {
+			return part, fmt.Errorf("{{ %s }}: %s takes %d argument(s)", part.expr, words[0], fn.args)
+		}
+		part.funcs = append(part.funcs, topicFuncCall{name: words[0], args: words[1:]})
+	}
+	return part, nil
+}
+
+// splitPipeline splits expr on | outside of quoted strings.
+func splitPipeline(expr string) ([]string, error) {
+	var segments []string
+	start := 0
+	var quote byte
+	for i := 0; i < len(expr); i++ {
+		ch := expr[i]
+		switch {
+		case quote != 0:
+			if ch == '\\' && quote == '"' {
+				i++
+			} else if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '`':
+			quote = ch
+		case ch == '|':
+			segments = append(segments, expr[start:i])
+			start = i + 1
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated string")
+	}
+	return append(segments, expr[start:]), nil
+}
+
+// splitWords splits a function call into its name and unquoted string arguments.
+func splitWords(segment string) ([]string, error) {
+	var words []string
+	rest := strings.TrimSpace(segment)
+	for rest != "" {
+		if rest[0] == '"' || rest[0] == '`' {
+			end := 1
+			for end < len(rest) && rest[end] != rest[0] {
+				if rest[0] == '"' && rest[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(rest) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			value, err := strconv.Unquote(rest[:end+1])
+			if err != nil {
+				return nil, err
+			}
+			words = append(words, value)
+			rest = strings.TrimSpace(rest[end+1:])
+			continue
+		}
+		if len(words) > 0 {
+			return nil, fmt.Errorf("arguments must be quoted strings: %s", rest)
+		}
+		end := strings.IndexFunc(rest, unicode.IsSpace)
+		if end < 0 {
+			end = len(rest)
+		}
+		words = append(words, rest[:end])
+		rest = strings.TrimSpace(rest[end:])
+	}
+	return words, nil
+}
+
+// static reports whether the topic has no expressions.
+func (t *topicTemplate) static() bool {
+	return len(t.parts) <= 1 && (len(t.parts) == 0 || t.parts[0].expr == "")
+}
+
+// render resolves the expressions against event. Interpolated values are
+// sanitized for drivers; literal text is used as written.
+func (t *topicTemplate) render(event Event, drivers []string) (string, error) {
+	if t.static() {
+		return t.raw, nil
+	}
+	var out strings.Builder
+	for _, part := range t.parts {
+		if part.expr == "" {
+			out.WriteString(part.literal)
+			continue
+		}
+		// Paths that do not resolve are empty, so default can replace them.
+		resolved, _ := resolveJSONPath(event, part.path)
+		value, err := topicValue(resolved)
+		if err != nil {
+			return "", fmt.Errorf("{{ %s }}: %w", part.expr, err)
+		}
+		for _, call := range part.funcs {
+			value = topicFuncs[call.name].fn(value, call.args)
+		}
+		value = sanitizeTopicValue(value, drivers)
+		if value == "" {
+			return "", fmt.Errorf("{{ %s }}: empty value", part.expr)
+		}
+		out.WriteString(value)
+	}
+	topic := out.String()
+	if err := checkTopicLength(topic, drivers); err != nil {
+		return "", err
+	}
+	return topic, nil
+}
+
+func topicValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int, int64, int32, uint, uint64, uint32:
+		return fmt.Sprint(v), nil
+	default:
+		return "", fmt.Errorf("value of type %T cannot be used in a topic", value)
+	}
+}
+
+// topicRuneRules lists the characters each driver accepts in a topic.
+// Interpolated characters outside the set are replaced with '-'.
+var topicRuneRules = map[string]func(rune) bool{
+	"kafka":      isKafkaTopicRune,
+	"sql":        isKafkaTopicRune,
+	"http":       isURLTopicRune,
+	"nats":       isNATSTopicRune,
+	"amqp":       isPrintableTopicRune,
+	"gochannel":  isPrintableTopicRune,
+	"riverqueue": isPrintableTopicRune,
+}
+
+// topicMaxLength is the longest topic each driver accepts.
+var topicMaxLength = map[string]int{
+	"kafka": 249,
+	"amqp":  255,
+}
+
+// sanitizeTopicValue replaces characters that any of drivers rejects. Rules
+// without drivers publish to the default drivers, so the Kafka character set,
+// which every driver accepts, is used.
+func sanitizeTopicValue(value string, drivers []string) string {
+	rules := make([]func(rune) bool, 0, len(drivers))
+	for _, driver := range drivers {
+		rule, ok := topicRuneRules[strings.ToLower(driver)]
+		if !ok {
+			rule = isKafkaTopicRune
+		}
+		rules = append(rules, rule)
+	}
+	if len(rules) == 0 {
+		rules = append(rules, isKafkaTopicRune)
+	}
+	return strings.Map(func(r rune) rune {
+		for _, allowed := range rules {
+			if !allowed(r) {
+				return '-'
+			}
+		}
+		return r
+	}, value)
+}
+
+func checkTopicLength(topic string, drivers []string) error {
+	if len(drivers) == 0 {
+		drivers = []string{"kafka"}
+	}
+	for _, driver := range drivers {
+		if max, ok := topicMaxLength[strings.ToLower(driver)]; ok && len(topic) > max {
+			return fmt.Errorf("topic %q exceeds %d characters for %s", topic, max, driver)
+		}
+	}
+	return nil
+}
+
+func isKafkaTopicRune(r rune) bool {
+	return r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' || r == '_' || r == '-')
+}
+
+func isURLTopicRune(r rune) bool {
+	return isKafkaTopicRune(r) || r == '~'
+}
+
+func isNATSTopicRune(r rune) bool {
+	return isPrintableTopicRune(r) && r != '*' && r != '>'
+}
+
+func isPrintableTopicRune(r rune) bool {
+	return unicode.IsPrint(r) && !unicode.IsSpace(r)
+}
@@ -0,0 +1,51 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestRuleEngineTemplatedEmit tests that emit topics interpolate JSONPath values and sanitize them.
+func TestRuleEngineTemplatedEmit(t *testing.T) {
+	engine, err := NewRuleEngine(RulesConfig{
+		Rules: []Rule{
+			{When: "ref != \"\"", Emit: EmitList{`push.{{ $.repository.name }}.{{ $.ref | trimPrefix "refs/heads/" }}`}},
+			{When: "ref != \"\"", Emit: EmitList{`nats.{{ repository.owner.login | upper }}.{{ $.ref | trimPrefix "refs/heads/" }}`}, Drivers: []string{"nats"}},
+			{When: "ref != \"\"", Emit: EmitList{`pr.{{ $.pull_request.number }}`, `tag.{{ $.tag | default "none" }}`}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("new rule engine: %v", err)
+	}
+	payload := []byte(`{"ref":"refs/heads/feature/login fix","repository":{"name":"api","owner":{"login":"acme"}}}`)
+	matches := engine.Evaluate(Event{Provider: "github", Name: "push", RawPayload: payload})
+
+	var topics []string
+	for _, match := range matches {
+		topics = append(topics, match.Topic)
+	}
+	want := []string{"push.api.feature-login-fix", "nats.ACME.feature/login-fix", "tag.none"}
+	if strings.Join(topics, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected topics %v, got %v", want, topics)
+	}
+}
+
+// TestRuleEngineTemplatedEmitInvalid tests that invalid emit templates fail to compile.
+func TestRuleEngineTemplatedEmitInvalid(t *testing.T) {
+	for _, emit := range []string{
+		"push.{{ $.ref",
+		"push.{{ }}",
+		"push.{{ $.ref | nope }}",
+		`push.{{ $.ref | trimPrefix }}`,
+		`push.{{ $.ref | trimPrefix refs }}`,
+		"push.{{ $.[ }}",
+	} {
+		_, err := NewRuleEngine(RulesConfig{Rules: []Rule{{When: "true", Emit: EmitList{"push"}}, {When: "true", Emit: EmitList{emit}}}})
+		if err == nil {
+			t.Fatalf("expected %q to fail", emit)
+		}
+		if !strings.HasPrefix(err.Error(), "rule 1: emit ") {
+			t.Fatalf("expected %q to fail with the rule index, got %v", emit, err)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"expvar"
 	"flag"
 	"net/http"
 	"os"
@@ -14,6 +15,10 @@ import (
 	"githooks/pkg/auth"
 	"githooks/pkg/api"
 	"githooks/pkg/oauth"
+	"githooks/pkg/storage"
+	"githooks/pkg/storage/dedupe"
+	"githooks/pkg/storage/deliveries"
+	"githooks/pkg/storage/inbox"
 	"githooks/pkg/storage/installations"
 	"githooks/pkg/storage/namespaces"
 	"githooks/pkg/webhook"
@@ -23,6 +28,9 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "rules" {
+		os.Exit(runRulesCommand(os.Args[2:], os.Stdout, os.Stderr))
+	}
 	logger := internal.NewLogger("server")
 	configPath := flag.String("config", "config.yaml", "Path to config file")
 	flag.Parse()
@@ -32,15 +40,22 @@ func main() {
 		logger.Fatalf("load config: %v", err)
 	}
 
-	ruleEngine, err := internal.NewRuleEngine(internal.RulesConfig{
-		Rules:  config.Rules,
-		Strict: config.RulesStrict,
-		Logger: logger,
-	})
+	rulesConfig := config.RulesConfig()
+	rulesConfig.Logger = logger
+	ruleEngine, err := internal.NewRuleEngine(rulesConfig)
 	if err != nil {
 		logger.Fatalf("compile rules: %v", err)
 	}
 
+	rulesCtx, stopRules := context.WithCancel(context.Background())
+	defer stopRules()
+	var rulesInterval time.Duration
+	if config.RulesReload.Watch {
+		rulesInterval = time.Duration(config.RulesReload.IntervalMS) * time.Millisecond
+	}
+	go internal.NewRulesReloader(*configPath, ruleEngine, logger).Run(rulesCtx, rulesInterval)
+	logger.Printf("rules reload enabled signal=SIGHUP watch=%t", config.RulesReload.Watch)
+
 	publisher, err := internal.NewPublisher(config.Watermill)
 	if err != nil {
 		logger.Fatalf("publisher: %v", err)
@@ -64,10 +79,12 @@ func main() {
 		logger.Printf("storage enabled driver=%s dialect=%s table=githooks_installations", config.Storage.Driver, config.Storage.Dialect)
 
 		nsStore, err := namespaces.Open(namespaces.Config{
-			Driver:      config.Storage.Driver,
-			DSN:         config.Storage.DSN,
-			Dialect:     config.Storage.Dialect,
-			AutoMigrate: config.Storage.AutoMigrate,
+			Driver:        config.Storage.Driver,
+			DSN:           config.Storage.DSN,
+			Dialect:       config.Storage.Dialect,
+			AutoMigrate:   config.Storage.AutoMigrate,
+			EncryptionKey: config.Storage.EncryptionKey,
+			Logger:        logger,
 		})
 		if err != nil {
 			logger.Fatalf("namespaces storage: %v", err)
@@ -79,6 +96,132 @@ func main() {
 		logger.Printf("storage disabled (missing storage.driver or storage.dsn)")
 	}
 
+	var handlerOpts []webhook.HandlerOption
+	var deliveryStore storage.DeliveryStore
+	var archive *webhook.Archive
+	if config.Archive.Enabled {
+		if config.Storage.Driver == "" || config.Storage.DSN == "" {
+			logger.Fatalf("archive: requires storage.driver and storage.dsn")
+		}
+		store, err := deliveries.Open(deliveries.Config{
+			Driver:      config.Storage.Driver,
+			DSN:         config.Storage.DSN,
+			Dialect:     config.Storage.Dialect,
+			Table:       config.Archive.Table,
+			AutoMigrate: config.Storage.AutoMigrate,
+		})
+		if err != nil {
+			logger.Fatalf("archive storage: %v", err)
+		}
+		defer store.Close()
+		retention := time.Duration(config.Archive.RetentionDays) * 24 * time.Hour
+		archive, err = webhook.NewArchive(store, ruleEngine, publisher, retention, logger)
+		if err != nil {
+			logger.Fatalf("archive: %v", err)
+		}
+		deliveryStore = store
+		archiveCtx, stopArchive := context.WithCancel(context.Background())
+		defer stopArchive()
+		go archive.RunRetention(archiveCtx, time.Duration(config.Archive.PruneIntervalMS)*time.Millisecond)
+		handlerOpts = append(handlerOpts, webhook.WithArchive(archive))
+		logger.Printf("archive enabled retention_days=%d", config.Archive.RetentionDays)
+	}
+
+	inboxDone := make(chan struct{})
+	inboxCtx, stopInbox := context.WithCancel(context.Background())
+	defer stopInbox()
+	if config.Inbox.Enabled {
+		if config.Storage.Driver == "" || config.Storage.DSN == "" {
+			logger.Fatalf("inbox: requires storage.driver and storage.dsn")
+		}
+		store, err := inbox.Open(inbox.Config{
+			Driver:      config.Storage.Driver,
+			DSN:         config.Storage.DSN,
+			Dialect:     config.Storage.Dialect,
+			Table:       config.Inbox.Table,
+			AutoMigrate: config.Storage.AutoMigrate,
+		})
+		if err != nil {
+			logger.Fatalf("inbox storage: %v", err)
+		}
+		defer store.Close()
+		dispatcher, err := webhook.NewInbox(store, ruleEngine, publisher, archive, webhook.InboxConfig{
+			Concurrency:  config.Inbox.Concurrency,
+			BatchSize:    config.Inbox.BatchSize,
+			PollInterval: time.Duration(config.Inbox.PollIntervalMS) * time.Millisecond,
+			Lease:        time.Duration(config.Inbox.LeaseMS) * time.Millisecond,
+			MaxAttempts:  config.Inbox.MaxAttempts,
+			BackoffBase:  time.Duration(config.Inbox.BackoffBaseMS) * time.Millisecond,
+			BackoffMax:   time.Duration(config.Inbox.BackoffMaxMS) * time.Millisecond,
+		}, logger)
+		if err != nil {
+			logger.Fatalf("inbox: %v", err)
+		}
+		go func() {
+			defer close(inboxDone)
+			dispatcher.Run(inboxCtx)
+		}()
+		handlerOpts = append(handlerOpts, webhook.WithInbox(dispatcher))
+		logger.Printf("inbox enabled concurrency=%d max_attempts=%d", config.Inbox.Concurrency, config.Inbox.MaxAttempts)
+	} else {
+		close(inboxDone)
+	}
+
+	if rl := config.Server.RateLimit; rl.Enabled {
+		limiter, err := webhook.NewRateLimiter(webhook.RateLimitConfig{
+			Key:        rl.Key,
+			Mode:       rl.Mode,
+			SpillTopic: rl.SpillTopic,
+			Rate:       rl.Rate,
+			Burst:      rl.Burst,
+			MaxKeys:    rl.MaxKeys,
+		})
+		if err != nil {
+			logger.Fatalf("rate limit: %v", err)
+		}
+		handlerOpts = append(handlerOpts, webhook.WithRateLimiter(limiter))
+		logger.Printf("rate limit enabled key=%s mode=%s rate=%g burst=%d", rl.Key, rl.Mode, rl.Rate, rl.Burst)
+	}
+
+	var dedupeStore storage.DedupeStore
+	if config.Dedupe.Enabled {
+		ttl := time.Duration(config.Dedupe.TTLSeconds) * time.Second
+		switch config.Dedupe.Driver {
+		case "memory":
+			dedupeStore = dedupe.NewMemory(config.Dedupe.MaxEntries, ttl)
+		case "sql":
+			if config.Storage.Driver == "" || config.Storage.DSN == "" {
+				logger.Fatalf("dedupe: driver=sql requires storage.driver and storage.dsn")
+			}
+			store, err := dedupe.Open(dedupe.Config{
+				Driver:      config.Storage.Driver,
+				DSN:         config.Storage.DSN,
+				Dialect:     config.Storage.Dialect,
+				Table:       config.Dedupe.Table,
+				AutoMigrate: config.Storage.AutoMigrate,
+				TTL:         ttl,
+			})
+			if err != nil {
+				logger.Fatalf("dedupe storage: %v", err)
+			}
+			dedupeStore = store
+		default:
+			logger.Fatalf("dedupe: unsupported driver %q", config.Dedupe.Driver)
+		}
+		defer dedupeStore.Close()
+		logger.Printf("dedupe enabled driver=%s mode=%s ttl=%s", config.Dedupe.Driver, config.Dedupe.Mode, ttl)
+	}
+	withDedupe := func(provider string, handler http.Handler) http.Handler {
+		if dedupeStore == nil {
+			return handler
+		}
+		wrapped, err := webhook.NewDedupeHandler(handler, provider, dedupeStore, config.Dedupe.Mode, config.Server.MaxBodyBytes, logger)
+		if err != nil {
+			logger.Fatalf("dedupe %s: %v", provider, err)
+		}
+		return wrapped
+	}
+
 	mux := http.NewServeMux()
 	mux.Handle("/", &oauth.StartHandler{
 		Providers:     config.Providers,
@@ -99,6 +242,20 @@ func main() {
 		Providers:     config.Providers,
 		Logger:        logger,
 	})
+	deliveriesHandler := &api.DeliveriesHandler{
+		Store:  deliveryStore,
+		Logger: logger,
+	}
+	if archive != nil {
+		deliveriesHandler.Replayer = archive
+	}
+	mux.Handle("/api/deliveries", deliveriesHandler)
+	mux.Handle("/api/deliveries/", deliveriesHandler)
+	mux.Handle("/api/rules/evaluate", &api.RulesEvaluateHandler{
+		Explainer: webhook.NewExplainer(ruleEngine),
+		Store:     deliveryStore,
+		Logger:    logger,
+	})
 	mux.Handle("/api/webhooks/namespace", &api.NamespaceWebhookHandler{
 		Store:         namespaceStore,
 		InstallStore:  installStore,
@@ -107,6 +264,39 @@ func main() {
 		Logger:        logger,
 	})
 
+	allowlistCtx, stopAllowlists := context.WithCancel(context.Background())
+	defer stopAllowlists()
+	// allowlistOpt builds a provider's IP allowlist; GitHub meta ranges are loaded
+	// now and refreshed in the background, retrying quickly if the first load fails.
+	allowlistOpt := func(provider string, cfg auth.IPAllowlistConfig, baseURL string) webhook.HandlerOption {
+		if !cfg.Enabled() {
+			return nil
+		}
+		if cfg.GitHubMeta && provider != "github" {
+			logger.Fatalf("%s ip allowlist: github_meta is only supported for github", provider)
+		}
+		allowlist, err := webhook.NewIPAllowlist(cfg.CIDRs, config.Server.TrustedProxies, logger)
+		if err != nil {
+			logger.Fatalf("%s ip allowlist: %v", provider, err)
+		}
+		if cfg.GitHubMeta {
+			metaCtx, cancel := context.WithTimeout(allowlistCtx, 10*time.Second)
+			if err := allowlist.RefreshGitHubMeta(metaCtx, baseURL); err != nil {
+				// Deliveries outside cidrs are rejected until a retry succeeds.
+				logger.Printf("ip allowlist error: initial github meta fetch failed, retrying: %v", err)
+			}
+			cancel()
+			go allowlist.RunGitHubMetaRefresh(allowlistCtx, baseURL, time.Duration(cfg.RefreshIntervalMS)*time.Millisecond)
+		}
+		logger.Printf("provider=%s ip_allowlist=enabled cidrs=%d github_meta=%t", provider, len(cfg.CIDRs), cfg.GitHubMeta)
+		return webhook.WithIPAllowlist(allowlist)
+	}
+	// providerOpts adds a provider's rotated secrets and IP allowlist to the shared handler options.
+	providerOpts := func(provider string, cfg auth.ProviderConfig) []webhook.HandlerOption {
+		opts := append([]webhook.HandlerOption{}, handlerOpts...)
+		return append(opts, webhook.WithSecrets(cfg.Secrets...), allowlistOpt(provider, cfg.IPAllowlist, cfg.BaseURL))
+	}
+
 	if config.Providers.GitHub.Enabled {
 		ghHandler, err := webhook.NewGitHubHandler(
 			config.Providers.GitHub.Secret,
@@ -117,11 +307,12 @@ func main() {
 			config.Server.DebugEvents,
 			installStore,
 			namespaceStore,
+			providerOpts("github", config.Providers.GitHub)...,
 		)
 		if err != nil {
 			logger.Fatalf("github handler: %v", err)
 		}
-		mux.Handle(config.Providers.GitHub.Path, ghHandler)
+		mux.Handle(config.Providers.GitHub.Path, withDedupe("github", ghHandler))
 		logger.Printf(
 			"provider=github webhook=enabled path=%s oauth_callback=/oauth/github/callback app_id=%d",
 			config.Providers.GitHub.Path,
@@ -138,11 +329,12 @@ func main() {
 			config.Server.MaxBodyBytes,
 			config.Server.DebugEvents,
 			namespaceStore,
+			providerOpts("gitlab", config.Providers.GitLab)...,
 		)
 		if err != nil {
 			logger.Fatalf("gitlab handler: %v", err)
 		}
-		mux.Handle(config.Providers.GitLab.Path, glHandler)
+		mux.Handle(config.Providers.GitLab.Path, withDedupe("gitlab", glHandler))
 		logger.Printf(
 			"provider=gitlab webhook=enabled path=%s oauth_callback=/oauth/gitlab/callback",
 			config.Providers.GitLab.Path,
@@ -158,17 +350,105 @@ func main() {
 			config.Server.MaxBodyBytes,
 			config.Server.DebugEvents,
 			namespaceStore,
+			providerOpts("bitbucket", config.Providers.Bitbucket)...,
 		)
 		if err != nil {
 			logger.Fatalf("bitbucket handler: %v", err)
 		}
-		mux.Handle(config.Providers.Bitbucket.Path, bbHandler)
+		mux.Handle(config.Providers.Bitbucket.Path, withDedupe("bitbucket", bbHandler))
 		logger.Printf(
 			"provider=bitbucket webhook=enabled path=%s oauth_callback=/oauth/bitbucket/callback",
 			config.Providers.Bitbucket.Path,
 		)
 	}
 
+	if config.Providers.BitbucketServer.Enabled {
+		bbsHandler, err := webhook.NewBitbucketServerHandler(
+			config.Providers.BitbucketServer.Secret,
+			ruleEngine,
+			publisher,
+			logger,
+			config.Server.MaxBodyBytes,
+			config.Server.DebugEvents,
+			namespaceStore,
+			providerOpts("bitbucket_server", config.Providers.BitbucketServer)...,
+		)
+		if err != nil {
+			logger.Fatalf("bitbucket_server handler: %v", err)
+		}
+		mux.Handle(config.Providers.BitbucketServer.Path, withDedupe("bitbucket_server", bbsHandler))
+		logger.Printf(
+			"provider=bitbucket_server webhook=enabled path=%s oauth_callback=/oauth/bitbucket_server/callback",
+			config.Providers.BitbucketServer.Path,
+		)
+	}
+
+	if config.Providers.Gitea.Enabled {
+		giteaHandler, err := webhook.NewGiteaHandler(
+			config.Providers.Gitea.Secret,
+			ruleEngine,
+			publisher,
+			logger,
+			config.Server.MaxBodyBytes,
+			config.Server.DebugEvents,
+			namespaceStore,
+			providerOpts("gitea", config.Providers.Gitea)...,
+		)
+		if err != nil {
+			logger.Fatalf("gitea handler: %v", err)
+		}
+		mux.Handle(config.Providers.Gitea.Path, withDedupe("gitea", giteaHandler))
+		logger.Printf(
+			"provider=gitea webhook=enabled path=%s oauth_callback=/oauth/gitea/callback",
+			config.Providers.Gitea.Path,
+		)
+	}
+
+	if config.Providers.AzureDevOps.Enabled {
+		adoHandler, err := webhook.NewAzureDevOpsHandler(
+			config.Providers.AzureDevOps.Secret,
+			ruleEngine,
+			publisher,
+			logger,
+			config.Server.MaxBodyBytes,
+			config.Server.DebugEvents,
+			namespaceStore,
+			providerOpts("azuredevops", config.Providers.AzureDevOps)...,
+		)
+		if err != nil {
+			logger.Fatalf("azuredevops handler: %v", err)
+		}
+		mux.Handle(config.Providers.AzureDevOps.Path, withDedupe("azuredevops", adoHandler))
+		logger.Printf(
+			"provider=azuredevops webhook=enabled path=%s",
+			config.Providers.AzureDevOps.Path,
+		)
+	}
+
+	for _, generic := range config.Providers.Generic {
+		genericOpts := append([]webhook.HandlerOption{}, handlerOpts...)
+		genericOpts = append(genericOpts, allowlistOpt(generic.Name, generic.IPAllowlist, ""))
+		genericHandler, err := webhook.NewGenericHandler(
+			generic,
+			ruleEngine,
+			publisher,
+			logger,
+			config.Server.MaxBodyBytes,
+			config.Server.DebugEvents,
+			genericOpts...,
+		)
+		if err != nil {
+			logger.Fatalf("generic handler: %v", err)
+		}
+		mux.Handle(generic.Path, withDedupe(generic.Name, genericHandler))
+		logger.Printf(
+			"provider=%s webhook=enabled path=%s signature=%s",
+			generic.Name,
+			generic.Path,
+			generic.Signature.Type,
+		)
+	}
+
 	redirectBase := config.OAuth.RedirectBaseURL
 	oauthHandler := func(provider string, cfg auth.ProviderConfig) *oauth.Handler {
 		return &oauth.Handler{
@@ -185,6 +465,8 @@ func main() {
 	mux.Handle("/oauth/github/callback", oauthHandler("github", config.Providers.GitHub))
 	mux.Handle("/oauth/gitlab/callback", oauthHandler("gitlab", config.Providers.GitLab))
 	mux.Handle("/oauth/bitbucket/callback", oauthHandler("bitbucket", config.Providers.Bitbucket))
+	mux.Handle("/oauth/bitbucket_server/callback", oauthHandler("bitbucket_server", config.Providers.BitbucketServer))
+	mux.Handle("/oauth/gitea/callback", oauthHandler("gitea", config.Providers.Gitea))
 
 	handler := h2c.NewHandler(mux, &http2.Server{})
 
@@ -211,10 +493,39 @@ func main() {
 		}
 	}()
 
+	// Counters stay off the public listener; they are only served on metrics_addr.
+	var metricsServer *http.Server
+	if config.Server.MetricsAddr != "" {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("/debug/vars", expvar.Handler())
+		metricsServer = &http.Server{
+			Addr:              config.Server.MetricsAddr,
+			Handler:           metricsMux,
+			ReadHeaderTimeout: time.Duration(config.Server.ReadHeaderMS) * time.Millisecond,
+		}
+		go func() {
+			logger.Printf("metrics listening on %s", config.Server.MetricsAddr)
+			if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Fatalf("metrics listen: %v", err)
+			}
+		}()
+	}
+
 	<-shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 	if err := server.Shutdown(ctx); err != nil {
 		logger.Printf("shutdown: %v", err)
 	}
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(ctx); err != nil {
+			logger.Printf("metrics shutdown: %v", err)
+		}
+	}
+	stopInbox()
+	select {
+	case <-inboxDone:
+	case <-ctx.Done():
+		logger.Printf("shutdown: inbox dispatcher did not stop in time")
+	}
 }
@@ -0,0 +1,207 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"githooks/pkg/storage"
+)
+
+// DeliveryReplayer re-runs the rule engine for an archived delivery. It returns
+// storage.ErrDeliveryNotFound for unknown ids.
+type DeliveryReplayer interface {
+	Replay(ctx context.Context, id string, driver string, topic string) (*storage.DeliveryRecord, error)
+}
+
+// DeliveriesHandler serves the delivery archive:
+//
+//	GET  /api/deliveries              list deliveries (newest first)
+//	GET  /api/deliveries/{id}         fetch a delivery including its payload
+//	POST /api/deliveries/{id}/replay  re-run rules and republish
+type DeliveriesHandler struct {
+	Store    storage.DeliveryStore
+	Replayer DeliveryReplayer
+	Logger   *log.Logger
+}
+
+type deliveryResponse struct {
+	ID            string            `json:"id"`
+	Provider      string            `json:"provider"`
+	EventName     string            `json:"event_name"`
+	RequestID     string            `json:"request_id"`
+	DeliveryID    string            `json:"delivery_id,omitempty"`
+	StateID       string            `json:"state_id,omitempty"`
+	MatchedTopics []string          `json:"matched_topics"`
+	Status        string            `json:"status"`
+	Error         string            `json:"error,omitempty"`
+	ReplayOf      string            `json:"replay_of,omitempty"`
+	CreatedAt     time.Time         `json:"created_at"`
+	Headers       map[string]string `json:"headers,omitempty"`
+	Payload       json.RawMessage   `json:"payload,omitempty"`
+}
+
+func (h *DeliveriesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.Store == nil {
+		http.Error(w, "delivery archive not configured", http.StatusServiceUnavailable)
+		return
+	}
+	rest := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/deliveries"), "/")
+	if rest == "" {
+		h.list(w, r)
+		return
+	}
+	parts := strings.Split(rest, "/")
+	switch {
+	case len(parts) == 1:
+		h.get(w, r, parts[0])
+	case len(parts) == 2 && parts[1] == "replay":
+		h.replay(w, r, parts[0])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h *DeliveriesHandler) list(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	query := r.URL.Query()
+	filter := storage.DeliveryFilter{
+		Provider:   strings.TrimSpace(query.Get("provider")),
+		EventName:  strings.TrimSpace(query.Get("event")),
+		StateID:    strings.TrimSpace(query.Get("state_id")),
+		DeliveryID: strings.TrimSpace(query.Get("delivery_id")),
+		Status:     strings.TrimSpace(query.Get("status")),
+	}
+	if raw := strings.TrimSpace(query.Get("since")); raw != "" {
+		since, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			http.Error(w, "invalid since (expected RFC3339)", http.StatusBadRequest)
+			return
+		}
+		filter.Since = &since
+	}
+	var err error
+	if filter.Limit, err = intParam(query.Get("limit")); err != nil {
+		http.Error(w, "invalid limit", http.StatusBadRequest)
+		return
+	}
+	if filter.Offset, err = intParam(query.Get("offset")); err != nil {
+		http.Error(w, "invalid offset", http.StatusBadRequest)
+		return
+	}
+
+	records, err := h.Store.ListDeliveries(r.Context(), filter)
+	if err != nil {
+		http.Error(w, "list deliveries failed", http.StatusInternalServerError)
+		if h.Logger != nil {
+			h.Logger.Printf("list deliveries failed: %v", err)
+		}
+		return
+	}
+	out := make([]deliveryResponse, 0, len(records))
+	for _, record := range records {
+		out = append(out, toDeliveryResponse(record, false))
+	}
+	writeJSON(w, out)
+}
+
+func (h *DeliveriesHandler) get(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	record, err := h.Store.GetDelivery(r.Context(), id)
+	if err != nil {
+		http.Error(w, "delivery lookup failed", http.StatusInternalServerError)
+		if h.Logger != nil {
+			h.Logger.Printf("delivery lookup failed: %v", err)
+		}
+		return
+	}
+	if record == nil {
+		http.Error(w, "delivery not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, toDeliveryResponse(*record, true))
+}
+
+func (h *DeliveriesHandler) replay(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.Replayer == nil {
+		http.Error(w, "replay not configured", http.StatusServiceUnavailable)
+		return
+	}
+	driver := strings.TrimSpace(r.URL.Query().Get("driver"))
+	topic := strings.TrimSpace(r.URL.Query().Get("topic"))
+	record, err := h.Replayer.Replay(r.Context(), id, driver, topic)
+	if errors.Is(err, storage.ErrDeliveryNotFound) {
+		http.Error(w, "delivery not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "replay failed", http.StatusInternalServerError)
+		if h.Logger != nil {
+			h.Logger.Printf("replay delivery %s failed: %v", id, err)
+		}
+		return
+	}
+	if record.Status == storage.DeliveryStatusFailed {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadGateway)
+		_ = json.NewEncoder(w).Encode(toDeliveryResponse(*record, false))
+		return
+	}
+	writeJSON(w, toDeliveryResponse(*record, false))
+}
+
+func toDeliveryResponse(record storage.DeliveryRecord, full bool) deliveryResponse {
+	out := deliveryResponse{
+		ID:            record.ID,
+		Provider:      record.Provider,
+		EventName:     record.EventName,
+		RequestID:     record.RequestID,
+		DeliveryID:    record.DeliveryID,
+		StateID:       record.StateID,
+		MatchedTopics: record.MatchedTopics,
+		Status:        record.Status,
+		Error:         record.Error,
+		ReplayOf:      record.ReplayOf,
+		CreatedAt:     record.CreatedAt,
+	}
+	if out.MatchedTopics == nil {
+		out.MatchedTopics = []string{}
+	}
+	if full {
+		out.Headers = record.Headers
+		if json.Valid(record.Payload) {
+			out.Payload = json.RawMessage(record.Payload)
+		} else if len(record.Payload) > 0 {
+			encoded, _ := json.Marshal(string(record.Payload))
+			out.Payload = encoded
+		}
+	}
+	return out
+}
+
+func intParam(raw string) (int, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return 0, nil
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < 0 {
+		return 0, errors.New("invalid integer")
+	}
+	return value, nil
+}
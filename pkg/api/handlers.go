@@ -2,6 +2,8 @@ package api
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"log"
@@ -54,7 +56,7 @@ func (h *InstallationsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request)
 		}
 		records = items
 	} else {
-		providers := []string{"github", "gitlab", "bitbucket"}
+		providers := []string{"github", "gitlab", "bitbucket", "bitbucket_server", "gitea"}
 		for _, p := range providers {
 			items, err := h.Store.ListInstallations(r.Context(), p, accountID)
 			if err != nil {
@@ -115,7 +117,7 @@ func writeJSON(w http.ResponseWriter, payload interface{}) {
 	_ = json.NewEncoder(w).Encode(payload)
 }
 
-// SyncNamespacesHandler triggers a namespace sync for GitLab or Bitbucket.
+// SyncNamespacesHandler triggers a namespace sync for GitLab, Bitbucket, Bitbucket Server, or Gitea.
 type SyncNamespacesHandler struct {
 	InstallStore  storage.Store
 	NamespaceStore storage.NamespaceStore
@@ -138,8 +140,8 @@ func (h *SyncNamespacesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request
 		return
 	}
 	provider := strings.TrimSpace(r.URL.Query().Get("provider"))
-	if provider != "github" && provider != "gitlab" && provider != "bitbucket" {
-		http.Error(w, "provider must be github, gitlab, or bitbucket", http.StatusBadRequest)
+	if provider != "github" && provider != "gitlab" && provider != "bitbucket" && provider != "bitbucket_server" && provider != "gitea" {
+		http.Error(w, "provider must be github, gitlab, bitbucket, bitbucket_server, or gitea", http.StatusBadRequest)
 		return
 	}
 
@@ -190,6 +192,32 @@ func (h *SyncNamespacesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request
 			record.AccessToken = refreshed.AccessToken
 			record.RefreshToken = refreshed.RefreshToken
 			record.ExpiresAt = refreshed.ExpiresAt
+		case "bitbucket_server":
+			refreshed, err := oauth.RefreshBitbucketServerToken(r.Context(), h.Providers.BitbucketServer, record.RefreshToken)
+			if err != nil {
+				http.Error(w, "token refresh failed", http.StatusInternalServerError)
+				if h.Logger != nil {
+					h.Logger.Printf("bitbucket_server token refresh failed: %v", err)
+				}
+				return
+			}
+			accessToken = refreshed.AccessToken
+			record.AccessToken = refreshed.AccessToken
+			record.RefreshToken = refreshed.RefreshToken
+			record.ExpiresAt = refreshed.ExpiresAt
+		case "gitea":
+			refreshed, err := oauth.RefreshGiteaToken(r.Context(), h.Providers.Gitea, record.RefreshToken)
+			if err != nil {
+				http.Error(w, "token refresh failed", http.StatusInternalServerError)
+				if h.Logger != nil {
+					h.Logger.Printf("gitea token refresh failed: %v", err)
+				}
+				return
+			}
+			accessToken = refreshed.AccessToken
+			record.AccessToken = refreshed.AccessToken
+			record.RefreshToken = refreshed.RefreshToken
+			record.ExpiresAt = refreshed.ExpiresAt
 		}
 		if err := h.InstallStore.UpsertInstallation(r.Context(), *record); err != nil {
 			if h.Logger != nil {
@@ -217,6 +245,22 @@ func (h *SyncNamespacesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request
 			}
 			return
 		}
+	case "bitbucket_server":
+		if err := oauth.SyncBitbucketServerNamespaces(r.Context(), h.NamespaceStore, h.Providers.BitbucketServer, accessToken, accountID); err != nil {
+			http.Error(w, "namespace sync failed", http.StatusInternalServerError)
+			if h.Logger != nil {
+				h.Logger.Printf("bitbucket_server namespace sync failed: %v", err)
+			}
+			return
+		}
+	case "gitea":
+		if err := oauth.SyncGiteaNamespaces(r.Context(), h.NamespaceStore, h.Providers.Gitea, accessToken, accountID); err != nil {
+			http.Error(w, "namespace sync failed", http.StatusInternalServerError)
+			if h.Logger != nil {
+				h.Logger.Printf("gitea namespace sync failed: %v", err)
+			}
+			return
+		}
 	}
 
 	records, err := h.NamespaceStore.ListNamespaces(r.Context(), storage.NamespaceFilter{
@@ -267,7 +311,7 @@ func (h *NamespaceWebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Reque
 		http.Error(w, "missing provider, repo_id, or state_id", http.StatusBadRequest)
 		return
 	}
-	if provider != "github" && provider != "gitlab" && provider != "bitbucket" {
+	if provider != "github" && provider != "gitlab" && provider != "bitbucket" && provider != "bitbucket_server" {
 		http.Error(w, "unsupported provider", http.StatusBadRequest)
 		return
 	}
@@ -307,6 +351,23 @@ func (h *NamespaceWebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Reque
 		enabled := strings.TrimSpace(r.URL.Query().Get("enabled"))
 		switch enabled {
 		case "true", "1":
+			if record.WebhookSecret == "" && namespaceSecretProviders[provider] {
+				secret, err := generateWebhookSecret()
+				if err != nil {
+					http.Error(w, "webhook secret generation failed", http.StatusInternalServerError)
+					return
+				}
+				// Persist before configuring the provider so a stored secret always
+				// exists for any hook that sends it.
+				record.WebhookSecret = secret
+				if err := h.Store.UpsertNamespace(r.Context(), *record); err != nil {
+					http.Error(w, "namespace update failed", http.StatusInternalServerError)
+					if h.Logger != nil {
+						h.Logger.Printf("namespace secret store failed: %v", err)
+					}
+					return
+				}
+			}
 			if err := enableProviderWebhook(r.Context(), provider, h.Providers, install.AccessToken, *record, webhookURL); err != nil {
 				http.Error(w, "webhook enable failed", http.StatusBadRequest)
 				if h.Logger != nil {
@@ -341,6 +402,21 @@ func (h *NamespaceWebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Reque
 		"enabled": record.WebhooksEnabled,
 	})
 }
+// namespaceSecretProviders get a generated webhook secret per namespace when
+// their webhook is enabled.
+var namespaceSecretProviders = map[string]bool{
+	"gitlab":    true,
+	"bitbucket": true,
+}
+
+func generateWebhookSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
 func latestInstallation(ctx context.Context, store storage.Store, provider, accountID string) (*storage.InstallRecord, error) {
 	records, err := store.ListInstallations(ctx, provider, accountID)
 	if err != nil {
@@ -375,6 +451,8 @@ func webhookURL(publicBaseURL, provider string) (string, error) {
 		return publicBaseURL + "/webhooks/gitlab", nil
 	case "bitbucket":
 		return publicBaseURL + "/webhooks/bitbucket", nil
+	case "bitbucket_server":
+		return publicBaseURL + "/webhooks/bitbucket-server", nil
 	default:
 		return "", errors.New("unsupported provider for webhook management")
 	}
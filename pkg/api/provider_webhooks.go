@@ -17,9 +17,11 @@ import (
 func enableProviderWebhook(ctx context.Context, provider string, cfg auth.Config, token string, record storage.NamespaceRecord, hookURL string) error {
 	switch provider {
 	case "gitlab":
-		return ensureGitLabWebhook(ctx, cfg.GitLab, token, record.RepoID, hookURL, true)
+		return ensureGitLabWebhook(ctx, cfg.GitLab, token, record.RepoID, hookURL, record.WebhookSecret, true)
 	case "bitbucket":
-		return ensureBitbucketWebhook(ctx, cfg.Bitbucket, token, record.Owner, record.RepoName, hookURL, true)
+		return ensureBitbucketWebhook(ctx, cfg.Bitbucket, token, record.Owner, record.RepoName, hookURL, record.WebhookSecret, true)
+	case "bitbucket_server":
+		return ensureBitbucketServerWebhook(ctx, cfg.BitbucketServer, token, record.Owner, record.RepoName, hookURL, true)
 	default:
 		return fmt.Errorf("unsupported provider: %s", provider)
 	}
@@ -28,15 +30,19 @@ func enableProviderWebhook(ctx context.Context, provider string, cfg auth.Config
 func disableProviderWebhook(ctx context.Context, provider string, cfg auth.Config, token string, record storage.NamespaceRecord, hookURL string) error {
 	switch provider {
 	case "gitlab":
-		return ensureGitLabWebhook(ctx, cfg.GitLab, token, record.RepoID, hookURL, false)
+		return ensureGitLabWebhook(ctx, cfg.GitLab, token, record.RepoID, hookURL, "", false)
 	case "bitbucket":
-		return ensureBitbucketWebhook(ctx, cfg.Bitbucket, token, record.Owner, record.RepoName, hookURL, false)
+		return ensureBitbucketWebhook(ctx, cfg.Bitbucket, token, record.Owner, record.RepoName, hookURL, "", false)
+	case "bitbucket_server":
+		return ensureBitbucketServerWebhook(ctx, cfg.BitbucketServer, token, record.Owner, record.RepoName, hookURL, false)
 	default:
 		return fmt.Errorf("unsupported provider: %s", provider)
 	}
 }
 
-func ensureGitLabWebhook(ctx context.Context, cfg auth.ProviderConfig, token, repoID, hookURL string, enable bool) error {
+// ensureGitLabWebhook creates or deletes the project hook. When enabling with a
+// secret, an existing hook is updated so it sends the secret as X-Gitlab-Token.
+func ensureGitLabWebhook(ctx context.Context, cfg auth.ProviderConfig, token, repoID, hookURL, secret string, enable bool) error {
 	if repoID == "" {
 		return fmt.Errorf("gitlab repo_id missing")
 	}
@@ -51,7 +57,7 @@ func ensureGitLabWebhook(ctx context.Context, cfg auth.ProviderConfig, token, re
 		return err
 	}
 	if enable {
-		if existingID != 0 {
+		if existingID != 0 && secret == "" {
 			return nil
 		}
 		body := map[string]interface{}{
@@ -60,6 +66,12 @@ func ensureGitLabWebhook(ctx context.Context, cfg auth.ProviderConfig, token, re
 			"merge_requests_events": true,
 			"enable_ssl_verification": true,
 		}
+		if secret != "" {
+			body["token"] = secret
+		}
+		if existingID != 0 {
+			return gitlabUpdateHook(ctx, fmt.Sprintf("%s/%d", hooksURL, existingID), token, body)
+		}
 		return gitlabCreateHook(ctx, hooksURL, token, body)
 	}
 	if existingID == 0 {
@@ -100,11 +112,19 @@ func gitlabHookID(ctx context.Context, hooksURL, token, targetURL string) (int64
 }
 
 func gitlabCreateHook(ctx context.Context, hooksURL, token string, payload map[string]interface{}) error {
+	return gitlabWriteHook(ctx, http.MethodPost, hooksURL, token, payload, "create")
+}
+
+func gitlabUpdateHook(ctx context.Context, hookURL, token string, payload map[string]interface{}) error {
+	return gitlabWriteHook(ctx, http.MethodPut, hookURL, token, payload, "update")
+}
+
+func gitlabWriteHook(ctx context.Context, method, hookURL, token string, payload map[string]interface{}, action string) error {
 	raw, err := json.Marshal(payload)
 	if err != nil {
 		return err
 	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hooksURL, bytes.NewReader(raw))
+	req, err := http.NewRequestWithContext(ctx, method, hookURL, bytes.NewReader(raw))
 	if err != nil {
 		return err
 	}
@@ -117,7 +137,7 @@ func gitlabCreateHook(ctx context.Context, hooksURL, token string, payload map[s
 	defer resp.Body.Close()
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
-		return fmt.Errorf("gitlab hook create failed: %s body=%s", resp.Status, strings.TrimSpace(string(body)))
+		return fmt.Errorf("gitlab hook %s failed: %s body=%s", action, resp.Status, strings.TrimSpace(string(body)))
 	}
 	return nil
 }
@@ -140,7 +160,9 @@ func gitlabDeleteHook(ctx context.Context, hookURL, token string) error {
 	return nil
 }
 
-func ensureBitbucketWebhook(ctx context.Context, cfg auth.ProviderConfig, token, owner, repo, hookURL string, enable bool) error {
+// ensureBitbucketWebhook creates or deletes the repository hook. When enabling
+// with a secret, an existing hook is updated so Bitbucket signs deliveries with it.
+func ensureBitbucketWebhook(ctx context.Context, cfg auth.ProviderConfig, token, owner, repo, hookURL, secret string, enable bool) error {
 	if owner == "" || repo == "" {
 		return fmt.Errorf("bitbucket owner/repo missing")
 	}
@@ -155,7 +177,7 @@ func ensureBitbucketWebhook(ctx context.Context, cfg auth.ProviderConfig, token,
 		return err
 	}
 	if enable {
-		if existingID != "" {
+		if existingID != "" && secret == "" {
 			return nil
 		}
 		body := map[string]interface{}{
@@ -169,6 +191,12 @@ func ensureBitbucketWebhook(ctx context.Context, cfg auth.ProviderConfig, token,
 				"pullrequest:fulfilled",
 			},
 		}
+		if secret != "" {
+			body["secret"] = secret
+		}
+		if existingID != "" {
+			return bitbucketUpdateHook(ctx, fmt.Sprintf("%s/%s", hooksURL, existingID), token, body)
+		}
 		return bitbucketCreateHook(ctx, hooksURL, token, body)
 	}
 	if existingID == "" {
@@ -211,11 +239,19 @@ func bitbucketHookID(ctx context.Context, hooksURL, token, targetURL string) (st
 }
 
 func bitbucketCreateHook(ctx context.Context, hooksURL, token string, payload map[string]interface{}) error {
+	return bitbucketWriteHook(ctx, http.MethodPost, hooksURL, token, payload, "create")
+}
+
+func bitbucketUpdateHook(ctx context.Context, hookURL, token string, payload map[string]interface{}) error {
+	return bitbucketWriteHook(ctx, http.MethodPut, hookURL, token, payload, "update")
+}
+
+func bitbucketWriteHook(ctx context.Context, method, hookURL, token string, payload map[string]interface{}, action string) error {
 	raw, err := json.Marshal(payload)
 	if err != nil {
 		return err
 	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hooksURL, bytes.NewReader(raw))
+	req, err := http.NewRequestWithContext(ctx, method, hookURL, bytes.NewReader(raw))
 	if err != nil {
 		return err
 	}
@@ -228,7 +264,7 @@ func bitbucketCreateHook(ctx context.Context, hooksURL, token string, payload ma
 	defer resp.Body.Close()
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
-		return fmt.Errorf("bitbucket hook create failed: %s body=%s", resp.Status, strings.TrimSpace(string(body)))
+		return fmt.Errorf("bitbucket hook %s failed: %s body=%s", action, resp.Status, strings.TrimSpace(string(body)))
 	}
 	return nil
 }
@@ -250,3 +286,122 @@ func bitbucketDeleteHook(ctx context.Context, hookURL, token string) error {
 	}
 	return nil
 }
+
+func ensureBitbucketServerWebhook(ctx context.Context, cfg auth.ProviderConfig, token, projectKey, slug, hookURL string, enable bool) error {
+	if projectKey == "" || slug == "" {
+		return fmt.Errorf("bitbucket_server project/repo missing")
+	}
+	baseURL := strings.TrimRight(cfg.BaseURL, "/")
+	if baseURL == "" {
+		return fmt.Errorf("bitbucket_server base_url is required")
+	}
+	if !strings.HasSuffix(baseURL, "/rest/api/1.0") {
+		baseURL += "/rest/api/1.0"
+	}
+	hooksURL := fmt.Sprintf("%s/projects/%s/repos/%s/webhooks", baseURL, url.PathEscape(projectKey), url.PathEscape(slug))
+
+	existingID, err := bitbucketServerHookID(ctx, hooksURL, token, hookURL)
+	if err != nil {
+		return err
+	}
+	if enable {
+		if existingID != 0 {
+			return nil
+		}
+		body := map[string]interface{}{
+			"name":   "githooks",
+			"url":    hookURL,
+			"active": true,
+			"events": []string{
+				"repo:refs_changed",
+				"pr:opened",
+				"pr:from_ref_updated",
+				"pr:modified",
+				"pr:merged",
+				"pr:declined",
+			},
+		}
+		if cfg.Secret != "" {
+			body["configuration"] = map[string]string{"secret": cfg.Secret}
+		}
+		return bitbucketServerCreateHook(ctx, hooksURL, token, body)
+	}
+	if existingID == 0 {
+		return nil
+	}
+	deleteURL := fmt.Sprintf("%s/%d", hooksURL, existingID)
+	return bitbucketServerDeleteHook(ctx, deleteURL, token)
+}
+
+func bitbucketServerHookID(ctx context.Context, hooksURL, token, targetURL string) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, hooksURL+"?limit=100", nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return 0, fmt.Errorf("bitbucket_server hook list failed: %s body=%s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	var payload struct {
+		Values []struct {
+			ID  int64  `json:"id"`
+			URL string `json:"url"`
+		} `json:"values"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return 0, err
+	}
+	for _, hook := range payload.Values {
+		if hook.URL == targetURL {
+			return hook.ID, nil
+		}
+	}
+	return 0, nil
+}
+
+func bitbucketServerCreateHook(ctx context.Context, hooksURL, token string, payload map[string]interface{}) error {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hooksURL, bytes.NewReader(raw))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("bitbucket_server hook create failed: %s body=%s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func bitbucketServerDeleteHook(ctx context.Context, hookURL, token string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, hookURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("bitbucket_server hook delete failed: %s body=%s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
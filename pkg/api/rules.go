@@ -0,0 +1,94 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"githooks/internal"
+	"githooks/pkg/storage"
+)
+
+// RuleExplainer evaluates the rules against a payload without publishing.
+type RuleExplainer interface {
+	Explain(provider, eventName string, payload []byte) internal.Explanation
+	RulesVersion() int64
+}
+
+// RulesEvaluateHandler serves POST /api/rules/evaluate, a dry run of the rules
+// against a payload or an archived delivery.
+type RulesEvaluateHandler struct {
+	Explainer RuleExplainer
+	Store     storage.DeliveryStore
+	Logger    *log.Logger
+}
+
+type rulesEvaluateRequest struct {
+	Provider string          `json:"provider"`
+	Event    string          `json:"event"`
+	Payload  json.RawMessage `json:"payload"`
+	// Delivery is the id of an archived delivery to evaluate instead of Payload.
+	Delivery string `json:"delivery"`
+}
+
+type rulesEvaluateResponse struct {
+	Provider     string                     `json:"provider"`
+	Event        string                     `json:"event"`
+	RulesVersion int64                      `json:"rules_version"`
+	Matches      []internal.RuleMatch       `json:"matches"`
+	Rules        []internal.RuleExplanation `json:"rules"`
+}
+
+func (h *RulesEvaluateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.Explainer == nil {
+		http.Error(w, "rules not configured", http.StatusServiceUnavailable)
+		return
+	}
+	var req rulesEvaluateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	req.Provider = strings.TrimSpace(req.Provider)
+	req.Event = strings.TrimSpace(req.Event)
+	req.Delivery = strings.TrimSpace(req.Delivery)
+
+	payload := []byte(req.Payload)
+	if req.Delivery != "" {
+		if h.Store == nil {
+			http.Error(w, "delivery archive not configured", http.StatusServiceUnavailable)
+			return
+		}
+		record, err := h.Store.GetDelivery(r.Context(), req.Delivery)
+		if err != nil {
+			http.Error(w, "delivery lookup failed", http.StatusInternalServerError)
+			if h.Logger != nil {
+				h.Logger.Printf("delivery lookup failed: %v", err)
+			}
+			return
+		}
+		if record == nil {
+			http.Error(w, "delivery not found", http.StatusNotFound)
+			return
+		}
+		req.Provider, req.Event, payload = record.Provider, record.EventName, record.Payload
+	}
+	if req.Provider == "" || req.Event == "" || len(payload) == 0 {
+		http.Error(w, "provider, event and payload (or delivery) are required", http.StatusBadRequest)
+		return
+	}
+
+	explanation := h.Explainer.Explain(req.Provider, req.Event, payload)
+	writeJSON(w, rulesEvaluateResponse{
+		Provider:     req.Provider,
+		Event:        req.Event,
+		RulesVersion: h.Explainer.RulesVersion(),
+		Matches:      explanation.Matches,
+		Rules:        explanation.Rules,
+	})
+}
@@ -1,10 +1,19 @@
 package auth
 
+import "time"
+
 // Config contains provider configuration for webhooks and SCM auth.
 type Config struct {
 	GitHub    ProviderConfig `yaml:"github"`
 	GitLab    ProviderConfig `yaml:"gitlab"`
 	Bitbucket ProviderConfig `yaml:"bitbucket"`
+	// BitbucketServer targets Bitbucket Server / Data Center; BaseURL is the instance root.
+	BitbucketServer ProviderConfig `yaml:"bitbucket_server"`
+	Gitea           ProviderConfig `yaml:"gitea"`
+	// AzureDevOps uses BaseURL as the organization URL and Token as a PAT.
+	AzureDevOps ProviderConfig `yaml:"azuredevops"`
+	// Generic declares signed webhook endpoints for non-SCM sources.
+	Generic []GenericConfig `yaml:"generic"`
 }
 
 // ProviderConfig contains webhook and auth configuration for a provider.
@@ -12,6 +21,11 @@ type ProviderConfig struct {
 	Enabled bool   `yaml:"enabled"`
 	Path    string `yaml:"path"`
 	Secret  string `yaml:"secret"`
+	// Secrets are additional webhook secrets accepted alongside Secret, used to
+	// rotate secrets without downtime. Only GitHub, GitLab and Bitbucket honor them.
+	Secrets []WebhookSecret `yaml:"secrets"`
+	// IPAllowlist restricts which source addresses may deliver webhooks.
+	IPAllowlist IPAllowlistConfig `yaml:"ip_allowlist"`
 
 	AppID          int64  `yaml:"app_id"`
 	PrivateKeyPath string `yaml:"private_key_path"`
@@ -19,8 +33,65 @@ type ProviderConfig struct {
 	WebBaseURL     string `yaml:"web_base_url"`
 
 	BaseURL string `yaml:"base_url"`
+	// Token is a static access token for providers without an install flow.
+	Token string `yaml:"token"`
 
 	OAuthClientID     string   `yaml:"oauth_client_id"`
 	OAuthClientSecret string   `yaml:"oauth_client_secret"`
 	OAuthScopes       []string `yaml:"oauth_scopes"`
 }
+
+// WebhookSecret is an accepted webhook secret with an optional expiry.
+type WebhookSecret struct {
+	// ID names the secret in logs; defaults to its position in the list.
+	ID    string `yaml:"id"`
+	Value string `yaml:"value"`
+	// NotAfter stops the secret from being accepted after this time (RFC 3339 or YYYY-MM-DD).
+	NotAfter time.Time `yaml:"not_after"`
+}
+
+// IPAllowlistConfig restricts webhook source addresses. It is enforced when
+// CIDRs is non-empty or GitHubMeta is set.
+type IPAllowlistConfig struct {
+	CIDRs []string `yaml:"cidrs"`
+	// GitHubMeta adds the "hooks" ranges from {base_url}/meta (GitHub only).
+	GitHubMeta        bool  `yaml:"github_meta"`
+	RefreshIntervalMS int64 `yaml:"refresh_interval_ms"`
+}
+
+// Enabled reports whether the allowlist should be enforced.
+func (c IPAllowlistConfig) Enabled() bool {
+	return len(c.CIDRs) > 0 || c.GitHubMeta
+}
+
+// GenericConfig describes a webhook endpoint for an arbitrary JSON source.
+type GenericConfig struct {
+	// Name is used as the event provider in rules (e.g., "argocd").
+	Name string `yaml:"name"`
+	Path string `yaml:"path"`
+
+	Signature GenericSignatureConfig `yaml:"signature"`
+	// IPAllowlist restricts which source addresses may deliver webhooks.
+	IPAllowlist IPAllowlistConfig `yaml:"ip_allowlist"`
+
+	// EventHeader names the header that carries the event name.
+	EventHeader string `yaml:"event_header"`
+	// DefaultEvent is used when EventHeader is unset or missing from the request.
+	DefaultEvent string `yaml:"default_event"`
+	// StateIDPath is a JSONPath into the payload that yields the state/tenant id.
+	StateIDPath string `yaml:"state_id_path"`
+	// DeliveryHeader names the header that carries a unique delivery id for dedupe.
+	DeliveryHeader string `yaml:"delivery_header"`
+}
+
+// GenericSignatureConfig configures request verification for a generic endpoint.
+type GenericSignatureConfig struct {
+	// Type is one of "hmac-sha256", "bearer", or "none".
+	Type   string `yaml:"type"`
+	Header string `yaml:"header"`
+	Secret string `yaml:"secret"`
+	// Prefix is stripped from the HMAC header value (e.g., "sha256=").
+	Prefix string `yaml:"prefix"`
+	// Encoding of the HMAC digest: "hex" (default) or "base64".
+	Encoding string `yaml:"encoding"`
+}
@@ -55,6 +55,14 @@ func (r *DefaultResolver) Resolve(_ context.Context, event EventContext) (AuthCo
 			Provider:       "github",
 			InstallationID: installationID,
 		}, nil
+	case "azuredevops":
+		if r.cfg.AzureDevOps.Token == "" {
+			return AuthContext{}, errors.New("azuredevops token is required")
+		}
+		return AuthContext{
+			Provider: "azuredevops",
+			Token:    r.cfg.AzureDevOps.Token,
+		}, nil
 	default:
 		return AuthContext{}, errors.New("unsupported provider for auth resolution")
 	}
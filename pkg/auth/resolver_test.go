@@ -57,3 +57,24 @@ func TestResolverUnsupportedProvider(t *testing.T) {
 		t.Fatalf("expected error for unsupported provider")
 	}
 }
+
+func TestResolverAzureDevOpsToken(t *testing.T) {
+	cfg := Config{
+		AzureDevOps: ProviderConfig{
+			BaseURL: "https://dev.azure.com/acme",
+			Token:   "pat",
+		},
+	}
+	resolver := NewResolver(cfg)
+
+	authCtx, err := resolver.Resolve(context.Background(), EventContext{
+		Provider: "azuredevops",
+		Payload:  []byte(`{}`),
+	})
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if authCtx.Token != "pat" {
+		t.Fatalf("expected configured token, got %q", authCtx.Token)
+	}
+}
@@ -0,0 +1,149 @@
+// Package normalized defines the provider-independent event model that the
+// webhook server derives from raw payloads. It is published next to the raw
+// body, exposed to rules under "normalized" and decoded by the worker SDK.
+package normalized
+
+// Event kinds.
+const (
+	KindPullRequest = "pull_request"
+	KindPush        = "push"
+	KindTag         = "tag"
+	KindComment     = "comment"
+	KindPipeline    = "pipeline"
+	KindRelease     = "release"
+)
+
+// Pull request states.
+const (
+	StateOpen   = "open"
+	StateClosed = "closed"
+	StateMerged = "merged"
+)
+
+// Pipeline statuses.
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusSuccess   = "success"
+	StatusFailure   = "failure"
+	StatusCancelled = "cancelled"
+)
+
+// Event is the normalized form of a webhook event. Exactly one of the kind
+// specific fields is set, matching Kind.
+type Event struct {
+	// Kind is one of the Kind* constants.
+	Kind string `json:"kind"`
+	// Provider is the name of the Git provider (e.g., "github", "gitlab").
+	Provider string `json:"provider"`
+	// Action is the provider action mapped to a common verb where one exists
+	// (opened, closed, merged, reopened, updated, created, deleted, completed).
+	Action     string      `json:"action,omitempty"`
+	Repository *Repository `json:"repository,omitempty"`
+	Actor      *Actor      `json:"actor,omitempty"`
+
+	PullRequest *PullRequest `json:"pull_request,omitempty"`
+	Push        *Push        `json:"push,omitempty"`
+	Tag         *Tag         `json:"tag,omitempty"`
+	Comment     *Comment     `json:"comment,omitempty"`
+	Pipeline    *Pipeline    `json:"pipeline,omitempty"`
+	Release     *Release     `json:"release,omitempty"`
+}
+
+// Repository identifies the repository an event belongs to.
+type Repository struct {
+	ID            string `json:"id,omitempty"`
+	Name          string `json:"name,omitempty"`
+	FullName      string `json:"full_name,omitempty"`
+	Owner         string `json:"owner,omitempty"`
+	URL           string `json:"url,omitempty"`
+	DefaultBranch string `json:"default_branch,omitempty"`
+	Private       bool   `json:"private,omitempty"`
+}
+
+// Actor is the user who triggered the event.
+type Actor struct {
+	ID    string `json:"id,omitempty"`
+	Login string `json:"login,omitempty"`
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email,omitempty"`
+}
+
+// PullRequest covers GitHub and Gitea pull requests, GitLab merge requests and
+// Bitbucket and Azure DevOps pull requests.
+type PullRequest struct {
+	Number       int      `json:"number"`
+	Title        string   `json:"title,omitempty"`
+	Body         string   `json:"body,omitempty"`
+	State        string   `json:"state"`
+	Draft        bool     `json:"draft,omitempty"`
+	Merged       bool     `json:"merged,omitempty"`
+	URL          string   `json:"url,omitempty"`
+	SourceBranch string   `json:"source_branch,omitempty"`
+	TargetBranch string   `json:"target_branch,omitempty"`
+	HeadSHA      string   `json:"head_sha,omitempty"`
+	BaseSHA      string   `json:"base_sha,omitempty"`
+	MergeSHA     string   `json:"merge_sha,omitempty"`
+	Labels       []string `json:"labels,omitempty"`
+	Author       *Actor   `json:"author,omitempty"`
+}
+
+// Push is a branch push.
+type Push struct {
+	Ref     string   `json:"ref"`
+	Branch  string   `json:"branch,omitempty"`
+	Before  string   `json:"before,omitempty"`
+	After   string   `json:"after,omitempty"`
+	Created bool     `json:"created,omitempty"`
+	Deleted bool     `json:"deleted,omitempty"`
+	Forced  bool     `json:"forced,omitempty"`
+	Commits []Commit `json:"commits,omitempty"`
+}
+
+// Commit is a commit included in a push.
+type Commit struct {
+	SHA     string `json:"sha"`
+	Message string `json:"message,omitempty"`
+	URL     string `json:"url,omitempty"`
+	Author  string `json:"author,omitempty"`
+}
+
+// Tag is a tag push, creation or deletion.
+type Tag struct {
+	Name    string `json:"name"`
+	Ref     string `json:"ref,omitempty"`
+	SHA     string `json:"sha,omitempty"`
+	Deleted bool   `json:"deleted,omitempty"`
+}
+
+// Comment is a comment on a pull request, issue or commit.
+type Comment struct {
+	ID   string `json:"id,omitempty"`
+	Body string `json:"body,omitempty"`
+	URL  string `json:"url,omitempty"`
+	// Target is "pull_request", "issue" or "commit".
+	Target string `json:"target,omitempty"`
+	// Number is the pull request or issue number when Target is one of those.
+	Number int    `json:"number,omitempty"`
+	SHA    string `json:"sha,omitempty"`
+}
+
+// Pipeline is a CI pipeline, workflow run or build.
+type Pipeline struct {
+	ID     string `json:"id,omitempty"`
+	Name   string `json:"name,omitempty"`
+	Status string `json:"status"`
+	Ref    string `json:"ref,omitempty"`
+	SHA    string `json:"sha,omitempty"`
+	URL    string `json:"url,omitempty"`
+}
+
+// Release is a published release.
+type Release struct {
+	Tag        string `json:"tag"`
+	Name       string `json:"name,omitempty"`
+	Body       string `json:"body,omitempty"`
+	URL        string `json:"url,omitempty"`
+	Draft      bool   `json:"draft,omitempty"`
+	Prerelease bool   `json:"prerelease,omitempty"`
+}
@@ -52,6 +52,10 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			config = h.Providers.GitLab
 		case "bitbucket":
 			config = h.Providers.Bitbucket
+		case "gitea":
+			config = h.Providers.Gitea
+		case "bitbucket_server":
+			config = h.Providers.BitbucketServer
 		}
 	}
 
@@ -60,6 +64,10 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		h.handleGitLab(w, r, logger, config)
 	case "bitbucket":
 		h.handleBitbucket(w, r, logger, config)
+	case "gitea":
+		h.handleGitea(w, r, logger, config)
+	case "bitbucket_server":
+		h.handleBitbucketServer(w, r, logger, config)
 	case "github":
 		h.handleGitHubApp(w, r, logger, config)
 	default:
@@ -280,6 +288,130 @@ func (h *Handler) handleBitbucket(w http.ResponseWriter, r *http.Request, logger
 	h.redirectOrJSON(w, r, params)
 }
 
+func (h *Handler) handleGitea(w http.ResponseWriter, r *http.Request, logger *log.Logger, cfg auth.ProviderConfig) {
+	code := r.URL.Query().Get("code")
+	state := r.URL.Query().Get("state")
+	if code == "" {
+		http.Error(w, "missing code", http.StatusBadRequest)
+		return
+	}
+	if cfg.OAuthClientID == "" || cfg.OAuthClientSecret == "" {
+		http.Error(w, "oauth client config missing", http.StatusInternalServerError)
+		return
+	}
+
+	redirectURL := callbackURL(r, "gitea", h.PublicBaseURL)
+	token, err := exchangeGiteaToken(r.Context(), cfg, code, redirectURL)
+	if err != nil {
+		logger.Printf("gitea token exchange failed: %v", err)
+		http.Error(w, "token exchange failed", http.StatusBadRequest)
+		return
+	}
+
+	warning := ""
+	accountID := state
+	accountName := ""
+	if accountID == "" {
+		if id, name, err := resolveGiteaAccount(r.Context(), cfg, token.AccessToken); err != nil {
+			logger.Printf("gitea account resolve failed: %v", err)
+		} else {
+			accountID = id
+			accountName = name
+		}
+	}
+	if err := SyncGiteaNamespaces(r.Context(), h.NamespaceStore, cfg, token.AccessToken, accountID); err != nil {
+		logger.Printf("gitea namespaces sync failed: %v", err)
+	}
+	record := storage.InstallRecord{
+		Provider:     "gitea",
+		AccountID:    accountID,
+		AccountName:  accountName,
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
+		ExpiresAt:    token.ExpiresAt,
+		MetadataJSON: token.MetadataJSON(),
+	}
+	logUpsertAttempt(logger, record, token.AccessToken)
+	if !storeAvailable(h.Store) {
+		warning = "storage_not_configured"
+	} else if err := h.Store.UpsertInstallation(r.Context(), record); err != nil {
+		logger.Printf("gitea install upsert failed: %v", err)
+		warning = "storage_persist_failed"
+	}
+
+	params := map[string]string{
+		"id":       randomID(),
+		"provider": "gitea",
+		"state":    state,
+	}
+	if warning != "" {
+		params["warning"] = warning
+	}
+	h.redirectOrJSON(w, r, params)
+}
+
+func (h *Handler) handleBitbucketServer(w http.ResponseWriter, r *http.Request, logger *log.Logger, cfg auth.ProviderConfig) {
+	code := r.URL.Query().Get("code")
+	state := r.URL.Query().Get("state")
+	if code == "" {
+		http.Error(w, "missing code", http.StatusBadRequest)
+		return
+	}
+	if cfg.OAuthClientID == "" || cfg.OAuthClientSecret == "" {
+		http.Error(w, "oauth client config missing", http.StatusInternalServerError)
+		return
+	}
+
+	redirectURL := callbackURL(r, "bitbucket_server", h.PublicBaseURL)
+	token, err := exchangeBitbucketServerToken(r.Context(), cfg, code, redirectURL)
+	if err != nil {
+		logger.Printf("bitbucket_server token exchange failed: %v", err)
+		http.Error(w, "token exchange failed", http.StatusBadRequest)
+		return
+	}
+
+	warning := ""
+	accountID := state
+	accountName := ""
+	if accountID == "" {
+		if id, name, err := resolveBitbucketServerAccount(r.Context(), cfg, token.AccessToken); err != nil {
+			logger.Printf("bitbucket_server account resolve failed: %v", err)
+		} else {
+			accountID = id
+			accountName = name
+		}
+	}
+	if err := SyncBitbucketServerNamespaces(r.Context(), h.NamespaceStore, cfg, token.AccessToken, accountID); err != nil {
+		logger.Printf("bitbucket_server namespaces sync failed: %v", err)
+	}
+	record := storage.InstallRecord{
+		Provider:     "bitbucket_server",
+		AccountID:    accountID,
+		AccountName:  accountName,
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
+		ExpiresAt:    token.ExpiresAt,
+		MetadataJSON: token.MetadataJSON(),
+	}
+	logUpsertAttempt(logger, record, token.AccessToken)
+	if !storeAvailable(h.Store) {
+		warning = "storage_not_configured"
+	} else if err := h.Store.UpsertInstallation(r.Context(), record); err != nil {
+		logger.Printf("bitbucket_server install upsert failed: %v", err)
+		warning = "storage_persist_failed"
+	}
+
+	params := map[string]string{
+		"id":       randomID(),
+		"provider": "bitbucket_server",
+		"state":    state,
+	}
+	if warning != "" {
+		params["warning"] = warning
+	}
+	h.redirectOrJSON(w, r, params)
+}
+
 func (h *Handler) redirectOrJSON(w http.ResponseWriter, r *http.Request, params map[string]string) {
 	redirect := strings.TrimSpace(h.RedirectBase)
 	if redirect == "" {
@@ -464,6 +596,71 @@ func resolveBitbucketAccount(ctx context.Context, cfg auth.ProviderConfig, acces
 	return payload.UUID, name, nil
 }
 
+func resolveGiteaAccount(ctx context.Context, cfg auth.ProviderConfig, accessToken string) (string, string, error) {
+	if accessToken == "" {
+		return "", "", errors.New("gitea access token missing")
+	}
+	endpoint := giteaAPIBase(cfg) + "/user"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return "", "", fmt.Errorf("gitea user lookup failed: %s body=%s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	var payload struct {
+		ID       int64  `json:"id"`
+		Login    string `json:"login"`
+		FullName string `json:"full_name"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return "", "", err
+	}
+	name := payload.Login
+	if name == "" {
+		name = payload.FullName
+	}
+	return strconv.FormatInt(payload.ID, 10), name, nil
+}
+
+// resolveBitbucketServerAccount looks up the authenticated user. Data Center has
+// no "current user" REST resource, so the username comes from the whoami servlet.
+func resolveBitbucketServerAccount(ctx context.Context, cfg auth.ProviderConfig, accessToken string) (string, string, error) {
+	if accessToken == "" {
+		return "", "", errors.New("bitbucket_server access token missing")
+	}
+	webBase := bitbucketServerWebBase(cfg)
+	if webBase == "" {
+		return "", "", errors.New("bitbucket_server base_url is required")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, webBase+"/plugins/servlet/applinks/whoami", nil)
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", "", fmt.Errorf("bitbucket_server user lookup failed: %s body=%s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	username := strings.TrimSpace(string(body))
+	if username == "" {
+		return "", "", errors.New("bitbucket_server user lookup returned empty username")
+	}
+	return username, username, nil
+}
+
 func storeAvailable(store storage.Store) bool {
 	if store == nil {
 		return false
@@ -582,6 +779,84 @@ func exchangeBitbucketToken(ctx context.Context, cfg auth.ProviderConfig, code,
 	return token, nil
 }
 
+func exchangeGiteaToken(ctx context.Context, cfg auth.ProviderConfig, code, redirectURL string) (oauthToken, error) {
+	endpoint := giteaWebBase(cfg) + "/login/oauth/access_token"
+
+	values := url.Values{}
+	values.Set("client_id", cfg.OAuthClientID)
+	values.Set("client_secret", cfg.OAuthClientSecret)
+	values.Set("code", code)
+	values.Set("grant_type", "authorization_code")
+	if redirectURL != "" {
+		values.Set("redirect_uri", redirectURL)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(values.Encode()))
+	if err != nil {
+		return oauthToken{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return oauthToken{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return oauthToken{}, fmt.Errorf("gitea token exchange failed: %s body=%s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	var token oauthToken
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return oauthToken{}, err
+	}
+	token.ExpiresAt = expiryFromToken(token)
+	if token.AccessToken == "" {
+		return oauthToken{}, errors.New("gitea access token missing")
+	}
+	return token, nil
+}
+
+func exchangeBitbucketServerToken(ctx context.Context, cfg auth.ProviderConfig, code, redirectURL string) (oauthToken, error) {
+	endpoint := bitbucketServerWebBase(cfg) + "/rest/oauth2/latest/token"
+
+	values := url.Values{}
+	values.Set("client_id", cfg.OAuthClientID)
+	values.Set("client_secret", cfg.OAuthClientSecret)
+	values.Set("code", code)
+	values.Set("grant_type", "authorization_code")
+	if redirectURL != "" {
+		values.Set("redirect_uri", redirectURL)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(values.Encode()))
+	if err != nil {
+		return oauthToken{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return oauthToken{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return oauthToken{}, fmt.Errorf("bitbucket_server token exchange failed: %s body=%s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	var token oauthToken
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return oauthToken{}, err
+	}
+	token.ExpiresAt = expiryFromToken(token)
+	if token.AccessToken == "" {
+		return oauthToken{}, errors.New("bitbucket_server access token missing")
+	}
+	return token, nil
+}
+
 func expiryFromToken(token oauthToken) *time.Time {
 	if token.ExpiresIn <= 0 {
 		return nil
@@ -3,6 +3,7 @@ package oauth
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -183,6 +184,187 @@ func SyncBitbucketNamespaces(ctx context.Context, store storage.NamespaceStore,
 	return nil
 }
 
+// SyncGiteaNamespaces fetches repositories and upserts them into the namespace store.
+func SyncGiteaNamespaces(ctx context.Context, store storage.NamespaceStore, cfg auth.ProviderConfig, accessToken, accountID string) error {
+	if !namespaceStoreAvailable(store) {
+		return nil
+	}
+	if accessToken == "" {
+		return nil
+	}
+	baseURL := giteaAPIBase(cfg)
+
+	const pageSize = 50
+	for page := 1; ; page++ {
+		endpoint := fmt.Sprintf("%s/user/repos?limit=%d&page=%d", baseURL, pageSize, page)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			resp.Body.Close()
+			return fmt.Errorf("gitea repo list failed: %s", resp.Status)
+		}
+		var payload []struct {
+			ID            int64  `json:"id"`
+			Name          string `json:"name"`
+			FullName      string `json:"full_name"`
+			Private       bool   `json:"private"`
+			DefaultBranch string `json:"default_branch"`
+			HTMLURL       string `json:"html_url"`
+			SSHURL        string `json:"ssh_url"`
+			Owner         struct {
+				Login string `json:"login"`
+			} `json:"owner"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&payload)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+		for _, repo := range payload {
+			repoID := strconv.FormatInt(repo.ID, 10)
+			existing, err := store.GetNamespace(ctx, "gitea", repoID)
+			if err != nil {
+				return err
+			}
+			visibility := "public"
+			if repo.Private {
+				visibility = "private"
+			}
+			record := storage.NamespaceRecord{
+				Provider:        "gitea",
+				AccountID:       accountID,
+				RepoID:          repoID,
+				Owner:           repo.Owner.Login,
+				RepoName:        repo.Name,
+				FullName:        repo.FullName,
+				Visibility:      visibility,
+				DefaultBranch:   repo.DefaultBranch,
+				HTTPURL:         repo.HTMLURL,
+				SSHURL:          repo.SSHURL,
+				WebhooksEnabled: existingWebhooks(existing, false),
+			}
+			if err := store.UpsertNamespace(ctx, record); err != nil {
+				return err
+			}
+		}
+		if len(payload) < pageSize {
+			break
+		}
+	}
+	return nil
+}
+
+// SyncBitbucketServerNamespaces fetches Bitbucket Data Center repositories and upserts
+// them into the namespace store. Owner holds the project key and RepoName the repo slug.
+func SyncBitbucketServerNamespaces(ctx context.Context, store storage.NamespaceStore, cfg auth.ProviderConfig, accessToken, accountID string) error {
+	if !namespaceStoreAvailable(store) {
+		return nil
+	}
+	if accessToken == "" {
+		return nil
+	}
+	baseURL := bitbucketServerAPIBase(cfg)
+	if baseURL == "" {
+		return errors.New("bitbucket_server base_url is required")
+	}
+
+	const pageSize = 100
+	start := 0
+	for {
+		endpoint := fmt.Sprintf("%s/repos?permission=REPO_READ&limit=%d&start=%d", baseURL, pageSize, start)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			resp.Body.Close()
+			return fmt.Errorf("bitbucket_server repo list failed: %s", resp.Status)
+		}
+		var payload struct {
+			Values []struct {
+				ID      int64  `json:"id"`
+				Slug    string `json:"slug"`
+				Name    string `json:"name"`
+				Public  bool   `json:"public"`
+				Project struct {
+					Key string `json:"key"`
+				} `json:"project"`
+				Links struct {
+					Self []struct {
+						Href string `json:"href"`
+					} `json:"self"`
+					Clone []struct {
+						Href string `json:"href"`
+						Name string `json:"name"`
+					} `json:"clone"`
+				} `json:"links"`
+			} `json:"values"`
+			IsLastPage    bool `json:"isLastPage"`
+			NextPageStart int  `json:"nextPageStart"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&payload)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+		for _, repo := range payload.Values {
+			repoID := strconv.FormatInt(repo.ID, 10)
+			existing, err := store.GetNamespace(ctx, "bitbucket_server", repoID)
+			if err != nil {
+				return err
+			}
+			visibility := "private"
+			if repo.Public {
+				visibility = "public"
+			}
+			httpURL := ""
+			if len(repo.Links.Self) > 0 {
+				httpURL = repo.Links.Self[0].Href
+			}
+			sshURL := ""
+			for _, link := range repo.Links.Clone {
+				if link.Name == "ssh" {
+					sshURL = link.Href
+				}
+			}
+			record := storage.NamespaceRecord{
+				Provider:        "bitbucket_server",
+				AccountID:       accountID,
+				RepoID:          repoID,
+				Owner:           repo.Project.Key,
+				RepoName:        repo.Slug,
+				FullName:        repo.Project.Key + "/" + repo.Slug,
+				Visibility:      visibility,
+				HTTPURL:         httpURL,
+				SSHURL:          sshURL,
+				WebhooksEnabled: existingWebhooks(existing, false),
+			}
+			if err := store.UpsertNamespace(ctx, record); err != nil {
+				return err
+			}
+		}
+		if payload.IsLastPage || payload.NextPageStart <= start {
+			break
+		}
+		start = payload.NextPageStart
+	}
+	return nil
+}
+
 func existingWebhooks(record *storage.NamespaceRecord, defaultValue bool) bool {
 	if record == nil {
 		return defaultValue
@@ -117,3 +117,97 @@ func RefreshBitbucketToken(ctx context.Context, cfg auth.ProviderConfig, refresh
 	}
 	return out, nil
 }
+
+// RefreshGiteaToken refreshes a Gitea OAuth token.
+func RefreshGiteaToken(ctx context.Context, cfg auth.ProviderConfig, refreshToken string) (TokenResult, error) {
+	if refreshToken == "" {
+		return TokenResult{}, errors.New("gitea refresh token missing")
+	}
+	endpoint := giteaWebBase(cfg) + "/login/oauth/access_token"
+
+	values := url.Values{}
+	values.Set("client_id", cfg.OAuthClientID)
+	values.Set("client_secret", cfg.OAuthClientSecret)
+	values.Set("grant_type", "refresh_token")
+	values.Set("refresh_token", refreshToken)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(values.Encode()))
+	if err != nil {
+		return TokenResult{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return TokenResult{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return TokenResult{}, fmt.Errorf("gitea token refresh failed: %s", resp.Status)
+	}
+	var token oauthToken
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return TokenResult{}, err
+	}
+	token.ExpiresAt = expiryFromToken(token)
+	if token.AccessToken == "" {
+		return TokenResult{}, errors.New("gitea access token missing")
+	}
+	out := TokenResult{
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
+		ExpiresAt:    token.ExpiresAt,
+	}
+	if out.RefreshToken == "" {
+		out.RefreshToken = refreshToken
+	}
+	return out, nil
+}
+
+// RefreshBitbucketServerToken refreshes a Bitbucket Data Center OAuth token.
+func RefreshBitbucketServerToken(ctx context.Context, cfg auth.ProviderConfig, refreshToken string) (TokenResult, error) {
+	if refreshToken == "" {
+		return TokenResult{}, errors.New("bitbucket_server refresh token missing")
+	}
+	endpoint := bitbucketServerWebBase(cfg) + "/rest/oauth2/latest/token"
+
+	values := url.Values{}
+	values.Set("client_id", cfg.OAuthClientID)
+	values.Set("client_secret", cfg.OAuthClientSecret)
+	values.Set("grant_type", "refresh_token")
+	values.Set("refresh_token", refreshToken)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(values.Encode()))
+	if err != nil {
+		return TokenResult{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return TokenResult{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return TokenResult{}, fmt.Errorf("bitbucket_server token refresh failed: %s", resp.Status)
+	}
+	var token oauthToken
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return TokenResult{}, err
+	}
+	token.ExpiresAt = expiryFromToken(token)
+	if token.AccessToken == "" {
+		return TokenResult{}, errors.New("bitbucket_server access token missing")
+	}
+	out := TokenResult{
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
+		ExpiresAt:    token.ExpiresAt,
+	}
+	if out.RefreshToken == "" {
+		out.RefreshToken = refreshToken
+	}
+	return out, nil
+}
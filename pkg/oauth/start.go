@@ -68,6 +68,22 @@ func (h *StartHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		http.Redirect(w, r, target, http.StatusFound)
+	case "gitea":
+		redirectURL := callbackURL(r, "gitea", h.PublicBaseURL)
+		target, err := giteaAuthorizeURL(h.Providers.Gitea, state, redirectURL)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Redirect(w, r, target, http.StatusFound)
+	case "bitbucket_server":
+		redirectURL := callbackURL(r, "bitbucket_server", h.PublicBaseURL)
+		target, err := bitbucketServerAuthorizeURL(h.Providers.BitbucketServer, state, redirectURL)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Redirect(w, r, target, http.StatusFound)
 	default:
 		http.Error(w, "unsupported provider", http.StatusBadRequest)
 	}
@@ -135,6 +151,58 @@ func bitbucketAuthorizeURL(cfg auth.ProviderConfig, state, redirectURL string) (
 	return u.String(), nil
 }
 
+func giteaAuthorizeURL(cfg auth.ProviderConfig, state, redirectURL string) (string, error) {
+	if cfg.OAuthClientID == "" {
+		return "", fmt.Errorf("gitea oauth_client_id is required")
+	}
+	u, err := url.Parse(giteaWebBase(cfg) + "/login/oauth/authorize")
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set("client_id", cfg.OAuthClientID)
+	q.Set("response_type", "code")
+	if redirectURL != "" {
+		q.Set("redirect_uri", redirectURL)
+	}
+	if len(cfg.OAuthScopes) > 0 {
+		q.Set("scope", strings.Join(cfg.OAuthScopes, " "))
+	}
+	if state != "" {
+		q.Set("state", state)
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+func bitbucketServerAuthorizeURL(cfg auth.ProviderConfig, state, redirectURL string) (string, error) {
+	if cfg.OAuthClientID == "" {
+		return "", fmt.Errorf("bitbucket_server oauth_client_id is required")
+	}
+	webBase := bitbucketServerWebBase(cfg)
+	if webBase == "" {
+		return "", fmt.Errorf("bitbucket_server base_url is required")
+	}
+	u, err := url.Parse(webBase + "/rest/oauth2/latest/authorize")
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set("client_id", cfg.OAuthClientID)
+	q.Set("response_type", "code")
+	if redirectURL != "" {
+		q.Set("redirect_uri", redirectURL)
+	}
+	if len(cfg.OAuthScopes) > 0 {
+		q.Set("scope", strings.Join(cfg.OAuthScopes, " "))
+	}
+	if state != "" {
+		q.Set("state", state)
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
 func githubWebBase(cfg auth.ProviderConfig) string {
 	webBase := strings.TrimRight(cfg.WebBaseURL, "/")
 	if webBase != "" {
@@ -168,6 +236,53 @@ func gitlabWebBase(cfg auth.ProviderConfig) string {
 	return webBase
 }
 
+func giteaWebBase(cfg auth.ProviderConfig) string {
+	webBase := strings.TrimRight(cfg.WebBaseURL, "/")
+	if webBase != "" {
+		return webBase
+	}
+	webBase = strings.TrimSuffix(strings.TrimRight(cfg.BaseURL, "/"), "/api/v1")
+	if webBase == "" {
+		return "https://gitea.com"
+	}
+	return webBase
+}
+
+func giteaAPIBase(cfg auth.ProviderConfig) string {
+	base := strings.TrimRight(cfg.BaseURL, "/")
+	if base == "" {
+		return giteaWebBase(cfg) + "/api/v1"
+	}
+	if !strings.HasSuffix(base, "/api/v1") {
+		base += "/api/v1"
+	}
+	return base
+}
+
+// bitbucketServerWebBase returns the Bitbucket Data Center root URL. There is
+// no public default; base_url must point at the on-prem instance.
+func bitbucketServerWebBase(cfg auth.ProviderConfig) string {
+	webBase := strings.TrimRight(cfg.WebBaseURL, "/")
+	if webBase != "" {
+		return webBase
+	}
+	return strings.TrimSuffix(strings.TrimRight(cfg.BaseURL, "/"), "/rest/api/1.0")
+}
+
+func bitbucketServerAPIBase(cfg auth.ProviderConfig) string {
+	base := strings.TrimRight(cfg.BaseURL, "/")
+	if base == "" {
+		base = bitbucketServerWebBase(cfg)
+	}
+	if base == "" {
+		return ""
+	}
+	if !strings.HasSuffix(base, "/rest/api/1.0") {
+		base += "/rest/api/1.0"
+	}
+	return base
+}
+
 func addQueryParam(rawURL, key, value string) (string, error) {
 	u, err := url.Parse(rawURL)
 	if err != nil {
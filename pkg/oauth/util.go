@@ -52,6 +52,10 @@ func providerFromPath(path string) string {
 		return "gitlab"
 	case strings.HasSuffix(path, "/oauth/bitbucket/callback"):
 		return "bitbucket"
+	case strings.HasSuffix(path, "/oauth/gitea/callback"):
+		return "gitea"
+	case strings.HasSuffix(path, "/oauth/bitbucket_server/callback"):
+		return "bitbucket_server"
 	default:
 		return ""
 	}
@@ -0,0 +1,31 @@
+package azuredevops
+
+import (
+	"errors"
+	"strings"
+
+	"githooks/pkg/auth"
+
+	ado "github.com/microsoft/azure-devops-go-api/azuredevops/v7"
+)
+
+// Client is the Azure DevOps SDK connection. Area clients (git, build, ...)
+// are created from it, e.g. git.NewClient(ctx, conn).
+type Client = ado.Connection
+
+// NewTokenClient returns an Azure DevOps connection authenticated with a personal access token.
+// BaseURL must point at the organization, e.g. https://dev.azure.com/acme.
+func NewTokenClient(cfg auth.ProviderConfig, token string) (*Client, error) {
+	if token == "" {
+		return nil, errors.New("azure devops access token is required")
+	}
+	base := normalizeBaseURL(cfg.BaseURL)
+	if base == "" {
+		return nil, errors.New("azure devops base_url (organization url) is required")
+	}
+	return ado.NewPatConnection(base, token), nil
+}
+
+func normalizeBaseURL(base string) string {
+	return strings.TrimRight(strings.TrimSpace(base), "/")
+}
@@ -0,0 +1,35 @@
+package gitea
+
+import (
+	"errors"
+	"strings"
+
+	"githooks/pkg/auth"
+
+	gt "code.gitea.io/sdk/gitea"
+)
+
+// Client is the official Gitea SDK client. It also works against Forgejo.
+type Client = gt.Client
+
+// NewTokenClient returns a Gitea SDK client using an OAuth or personal access token.
+func NewTokenClient(cfg auth.ProviderConfig, token string) (*Client, error) {
+	if token == "" {
+		return nil, errors.New("gitea access token is required")
+	}
+	return gt.NewClient(
+		normalizeBaseURL(cfg.BaseURL),
+		gt.SetToken(token),
+		gt.SetGiteaVersion(""),
+	)
+}
+
+// normalizeBaseURL returns the server root expected by the SDK, which appends
+// /api/v1 on its own.
+func normalizeBaseURL(base string) string {
+	base = strings.TrimRight(strings.TrimSpace(base), "/")
+	if base == "" {
+		return "https://gitea.com"
+	}
+	return strings.TrimSuffix(base, "/api/v1")
+}
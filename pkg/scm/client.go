@@ -5,7 +5,9 @@ import (
 	"errors"
 
 	"githooks/pkg/auth"
+	"githooks/pkg/providers/azuredevops"
 	"githooks/pkg/providers/bitbucket"
+	"githooks/pkg/providers/gitea"
 	"githooks/pkg/providers/github"
 	"githooks/pkg/providers/gitlab"
 )
@@ -38,6 +40,10 @@ func (f *Factory) NewClient(ctx context.Context, authCtx auth.AuthContext) (Clie
 		return gitlab.NewTokenClient(f.cfg.GitLab, authCtx.Token)
 	case "bitbucket":
 		return bitbucket.NewTokenClient(f.cfg.Bitbucket, authCtx.Token)
+	case "gitea":
+		return gitea.NewTokenClient(f.cfg.Gitea, authCtx.Token)
+	case "azuredevops":
+		return azuredevops.NewTokenClient(f.cfg.AzureDevOps, authCtx.Token)
 	default:
 		return nil, errors.New("unsupported provider for scm client")
 	}
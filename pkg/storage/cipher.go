@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+const cipherPrefix = "v1:"
+
+// SecretCipher encrypts secrets at rest with AES-256-GCM.
+type SecretCipher struct {
+	aead cipher.AEAD
+}
+
+// NewSecretCipher derives an AES-256 key from key with SHA-256. Use a long
+// random value; the key cannot change without re-encrypting stored secrets.
+func NewSecretCipher(key string) (*SecretCipher, error) {
+	if key == "" {
+		return nil, errors.New("encryption key is required")
+	}
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &SecretCipher{aead: aead}, nil
+}
+
+// Encrypt returns a versioned, base64-encoded ciphertext for plaintext.
+func (c *SecretCipher) Encrypt(plaintext string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return cipherPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt reverses Encrypt.
+func (c *SecretCipher) Decrypt(value string) (string, error) {
+	if !strings.HasPrefix(value, cipherPrefix) {
+		return "", errors.New("unsupported ciphertext format")
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, cipherPrefix))
+	if err != nil {
+		return "", err
+	}
+	size := c.aead.NonceSize()
+	if len(raw) < size {
+		return "", errors.New("ciphertext too short")
+	}
+	plaintext, err := c.aead.Open(nil, raw[:size], raw[size:], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
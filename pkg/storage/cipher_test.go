@@ -0,0 +1,33 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestSecretCipherRoundTrip tests that secrets decrypt with the same key and not with another.
+func TestSecretCipherRoundTrip(t *testing.T) {
+	c, err := NewSecretCipher("test-key")
+	if err != nil {
+		t.Fatalf("cipher: %v", err)
+	}
+	sealed, err := c.Encrypt("s3cret")
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	if strings.Contains(sealed, "s3cret") {
+		t.Fatalf("ciphertext contains plaintext: %q", sealed)
+	}
+	plain, err := c.Decrypt(sealed)
+	if err != nil || plain != "s3cret" {
+		t.Fatalf("decrypt: %q %v", plain, err)
+	}
+
+	other, err := NewSecretCipher("other-key")
+	if err != nil {
+		t.Fatalf("cipher: %v", err)
+	}
+	if _, err := other.Decrypt(sealed); err == nil {
+		t.Fatalf("expected decrypt with a different key to fail")
+	}
+}
@@ -0,0 +1,129 @@
+package dedupe
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// Memory implements storage.DedupeStore with an in-process LRU bounded by size and TTL.
+type Memory struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	items      map[string]*list.Element
+	order      *list.List
+	now        func() time.Time
+}
+
+type memoryEntry struct {
+	key        string
+	expiresAt  time.Time
+	reservedAt time.Time
+	pending    bool
+}
+
+// NewMemory creates an in-memory dedupe store. A zero ttl keeps entries until
+// they are evicted by size; a zero maxEntries disables size-based eviction.
+func NewMemory(maxEntries int, ttl time.Duration) *Memory {
+	return &Memory{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		items:      make(map[string]*list.Element),
+		order:      list.New(),
+		now:        time.Now,
+	}
+}
+
+// Reserve records a delivery and reports whether it was seen for the first time.
+func (m *Memory) Reserve(ctx context.Context, provider, deliveryID string) (bool, error) {
+	key := memoryKey(provider, deliveryID)
+	now := m.now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if el, ok := m.items[key]; ok {
+		entry := el.Value.(*memoryEntry)
+		m.order.MoveToFront(el)
+		if m.expired(entry, now) || (entry.pending && m.abandoned(entry, now)) {
+			entry.reservedAt = now
+			entry.pending = true
+			if m.ttl > 0 {
+				entry.expiresAt = now.Add(m.ttl)
+			}
+			return true, nil
+		}
+		return false, nil
+	}
+
+	entry := &memoryEntry{key: key, reservedAt: now, pending: true}
+	if m.ttl > 0 {
+		entry.expiresAt = now.Add(m.ttl)
+	}
+	m.items[key] = m.order.PushFront(entry)
+	for m.maxEntries > 0 && m.order.Len() > m.maxEntries {
+		m.removeElement(m.order.Back())
+	}
+	return true, nil
+}
+
+// Release forgets a delivery.
+func (m *Memory) Release(ctx context.Context, provider, deliveryID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if el, ok := m.items[memoryKey(provider, deliveryID)]; ok {
+		m.removeElement(el)
+	}
+	return nil
+}
+
+// Complete marks a reserved delivery as processed.
+func (m *Memory) Complete(ctx context.Context, provider, deliveryID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if el, ok := m.items[memoryKey(provider, deliveryID)]; ok {
+		el.Value.(*memoryEntry).pending = false
+	}
+	return nil
+}
+
+// InFlight reports whether a delivery is reserved but not yet completed or
+// released. Reservations older than InFlightLease are considered abandoned.
+func (m *Memory) InFlight(ctx context.Context, provider, deliveryID string) (bool, error) {
+	now := m.now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	el, ok := m.items[memoryKey(provider, deliveryID)]
+	if !ok {
+		return false, nil
+	}
+	entry := el.Value.(*memoryEntry)
+	return entry.pending && !m.expired(entry, now) && !m.abandoned(entry, now), nil
+}
+
+func (m *Memory) expired(entry *memoryEntry, now time.Time) bool {
+	return m.ttl > 0 && !now.Before(entry.expiresAt)
+}
+
+func (m *Memory) abandoned(entry *memoryEntry, now time.Time) bool {
+	return !now.Before(entry.reservedAt.Add(InFlightLease))
+}
+
+// Close is a no-op for the in-memory store.
+func (m *Memory) Close() error {
+	return nil
+}
+
+func (m *Memory) removeElement(el *list.Element) {
+	if el == nil {
+		return
+	}
+	m.order.Remove(el)
+	delete(m.items, el.Value.(*memoryEntry).key)
+}
+
+func memoryKey(provider, deliveryID string) string {
+	return provider + "\x00" + deliveryID
+}
@@ -0,0 +1,86 @@
+package dedupe
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestMemoryReserveDetectsDuplicates ensures a delivery is only reserved once per provider.
+func TestMemoryReserveDetectsDuplicates(t *testing.T) {
+	store := NewMemory(10, time.Hour)
+	ctx := context.Background()
+
+	first, err := store.Reserve(ctx, "github", "abc")
+	if err != nil || !first {
+		t.Fatalf("expected first reservation, got first=%v err=%v", first, err)
+	}
+	again, _ := store.Reserve(ctx, "github", "abc")
+	if again {
+		t.Fatalf("expected duplicate reservation to be rejected")
+	}
+	other, _ := store.Reserve(ctx, "gitlab", "abc")
+	if !other {
+		t.Fatalf("expected delivery ids to be scoped per provider")
+	}
+	if err := store.Release(ctx, "github", "abc"); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if released, _ := store.Reserve(ctx, "github", "abc"); !released {
+		t.Fatalf("expected released delivery to be reservable again")
+	}
+}
+
+// TestMemoryReserveExpiresAndEvicts ensures TTL expiry and LRU eviction.
+func TestMemoryReserveExpiresAndEvicts(t *testing.T) {
+	store := NewMemory(2, time.Minute)
+	now := time.Unix(1700000000, 0)
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	_, _ = store.Reserve(ctx, "github", "a")
+	now = now.Add(2 * time.Minute)
+	if first, _ := store.Reserve(ctx, "github", "a"); !first {
+		t.Fatalf("expected expired delivery to be treated as new")
+	}
+
+	_, _ = store.Reserve(ctx, "github", "b")
+	_, _ = store.Reserve(ctx, "github", "c")
+	if first, _ := store.Reserve(ctx, "github", "a"); !first {
+		t.Fatalf("expected least recently used delivery to be evicted")
+	}
+	if first, _ := store.Reserve(ctx, "github", "c"); first {
+		t.Fatalf("expected recent delivery to be retained")
+	}
+}
+
+// TestMemoryInFlight ensures reservations are in flight until completed or abandoned.
+func TestMemoryInFlight(t *testing.T) {
+	store := NewMemory(10, time.Hour)
+	now := time.Unix(1700000000, 0)
+	store.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	_, _ = store.Reserve(ctx, "github", "a")
+	if inFlight, _ := store.InFlight(ctx, "github", "a"); !inFlight {
+		t.Fatalf("expected reserved delivery to be in flight")
+	}
+	if err := store.Complete(ctx, "github", "a"); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if inFlight, _ := store.InFlight(ctx, "github", "a"); inFlight {
+		t.Fatalf("expected completed delivery not to be in flight")
+	}
+	if first, _ := store.Reserve(ctx, "github", "a"); first {
+		t.Fatalf("expected completed delivery to stay a duplicate")
+	}
+
+	_, _ = store.Reserve(ctx, "github", "b")
+	now = now.Add(InFlightLease)
+	if inFlight, _ := store.InFlight(ctx, "github", "b"); inFlight {
+		t.Fatalf("expected abandoned reservation not to be in flight")
+	}
+	if first, _ := store.Reserve(ctx, "github", "b"); !first {
+		t.Fatalf("expected abandoned reservation to be taken over")
+	}
+}
@@ -0,0 +1,227 @@
+package dedupe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// pruneEvery controls how many first-seen reservations trigger a cleanup of expired rows.
+const pruneEvery = 1000
+
+// InFlightLease is how long a reservation that was neither completed nor
+// released blocks redeliveries. After it, the process that reserved the
+// delivery is assumed to have died and a redelivery may take it over.
+const InFlightLease = 5 * time.Minute
+
+// Config mirrors the storage configuration for the delivery dedupe table.
+type Config struct {
+	Driver      string
+	DSN         string
+	Dialect     string
+	Table       string
+	AutoMigrate bool
+	TTL         time.Duration
+}
+
+// Store implements storage.DedupeStore on top of GORM.
+type Store struct {
+	db       *gorm.DB
+	table    string
+	ttl      time.Duration
+	reserved atomic.Int64
+}
+
+type row struct {
+	Provider   string    `gorm:"column:provider;size:64;not null;uniqueIndex:idx_delivery_dedupe,priority:1"`
+	DeliveryID string    `gorm:"column:delivery_id;size:128;not null;uniqueIndex:idx_delivery_dedupe,priority:2"`
+	SeenAt     time.Time `gorm:"column:seen_at;not null;index"`
+	// Pending is set from Reserve until Complete or Release.
+	Pending bool `gorm:"column:pending;not null;default:false"`
+}
+
+// Open creates a GORM-backed dedupe store.
+func Open(cfg Config) (*Store, error) {
+	if cfg.Driver == "" && cfg.Dialect == "" {
+		return nil, errors.New("storage driver or dialect is required")
+	}
+	if cfg.DSN == "" {
+		return nil, errors.New("storage dsn is required")
+	}
+	driver := normalizeDriver(cfg.Driver)
+	if driver == "" {
+		driver = normalizeDriver(cfg.Dialect)
+	}
+	if driver == "" {
+		return nil, errors.New("unsupported storage driver")
+	}
+
+	gormDB, err := openGorm(driver, cfg.DSN)
+	if err != nil {
+		return nil, err
+	}
+
+	table := cfg.Table
+	if table == "" {
+		table = "githooks_delivery_dedupe"
+	}
+	store := &Store{
+		db:    gormDB,
+		table: table,
+		ttl:   cfg.TTL,
+	}
+	if cfg.AutoMigrate {
+		if err := store.migrate(); err != nil {
+			return nil, err
+		}
+	}
+	return store, nil
+}
+
+// Close closes the underlying DB connection.
+func (s *Store) Close() error {
+	if s == nil || s.db == nil {
+		return nil
+	}
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// Reserve records a delivery and reports whether it was seen for the first time.
+// Rows older than the TTL are treated as unseen and refreshed.
+func (s *Store) Reserve(ctx context.Context, provider, deliveryID string) (bool, error) {
+	if s == nil || s.db == nil {
+		return false, errors.New("store is not initialized")
+	}
+	now := time.Now().UTC()
+	data := row{Provider: provider, DeliveryID: deliveryID, SeenAt: now, Pending: true}
+	result := s.tableDB().
+		WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&data)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 1 {
+		if s.ttl > 0 && s.reserved.Add(1)%pruneEvery == 0 {
+			_, _ = s.Prune(ctx)
+		}
+		return true, nil
+	}
+	// Take over rows past the TTL and reservations abandoned mid-flight.
+	stale := s.tableDB().Where("pending = ? AND seen_at < ?", true, now.Add(-InFlightLease))
+	if s.ttl > 0 {
+		stale = stale.Or("seen_at < ?", now.Add(-s.ttl))
+	}
+	result = s.tableDB().
+		WithContext(ctx).
+		Where("provider = ? AND delivery_id = ?", provider, deliveryID).
+		Where(stale).
+		Updates(map[string]interface{}{"seen_at": now, "pending": true})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// Release forgets a delivery.
+func (s *Store) Release(ctx context.Context, provider, deliveryID string) error {
+	if s == nil || s.db == nil {
+		return errors.New("store is not initialized")
+	}
+	return s.tableDB().
+		WithContext(ctx).
+		Where("provider = ? AND delivery_id = ?", provider, deliveryID).
+		Delete(&row{}).Error
+}
+
+// Complete marks a reserved delivery as processed.
+func (s *Store) Complete(ctx context.Context, provider, deliveryID string) error {
+	if s == nil || s.db == nil {
+		return errors.New("store is not initialized")
+	}
+	return s.tableDB().
+		WithContext(ctx).
+		Where("provider = ? AND delivery_id = ?", provider, deliveryID).
+		Update("pending", false).Error
+}
+
+// InFlight reports whether a delivery is reserved but not yet completed or
+// released. Reservations older than InFlightLease are considered abandoned.
+func (s *Store) InFlight(ctx context.Context, provider, deliveryID string) (bool, error) {
+	if s == nil || s.db == nil {
+		return false, errors.New("store is not initialized")
+	}
+	since := time.Now().UTC().Add(-InFlightLease)
+	if s.ttl > 0 && s.ttl < InFlightLease {
+		since = time.Now().UTC().Add(-s.ttl)
+	}
+	var count int64
+	err := s.tableDB().
+		WithContext(ctx).
+		Where("provider = ? AND delivery_id = ? AND pending = ? AND seen_at >= ?", provider, deliveryID, true, since).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// Prune deletes rows older than the TTL and returns the number removed.
+func (s *Store) Prune(ctx context.Context) (int64, error) {
+	if s == nil || s.db == nil {
+		return 0, errors.New("store is not initialized")
+	}
+	if s.ttl <= 0 {
+		return 0, nil
+	}
+	result := s.tableDB().
+		WithContext(ctx).
+		Where("seen_at < ?", time.Now().UTC().Add(-s.ttl)).
+		Delete(&row{})
+	return result.RowsAffected, result.Error
+}
+
+func (s *Store) migrate() error {
+	return s.tableDB().AutoMigrate(&row{})
+}
+
+func (s *Store) tableDB() *gorm.DB {
+	return s.db.Table(s.table)
+}
+
+func normalizeDriver(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch value {
+	case "postgres", "postgresql", "pgx":
+		return "postgres"
+	case "mysql":
+		return "mysql"
+	case "sqlite", "sqlite3":
+		return "sqlite"
+	default:
+		return ""
+	}
+}
+
+func openGorm(driver, dsn string) (*gorm.DB, error) {
+	switch driver {
+	case "postgres":
+		return gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	case "mysql":
+		return gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	case "sqlite":
+		return gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	default:
+		return nil, fmt.Errorf("unsupported storage driver: %s", driver)
+	}
+}
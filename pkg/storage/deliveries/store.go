@@ -0,0 +1,276 @@
+package deliveries
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"githooks/pkg/storage"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+const defaultListLimit = 50
+
+// Config mirrors the storage configuration for the deliveries table.
+type Config struct {
+	Driver      string
+	DSN         string
+	Dialect     string
+	Table       string
+	AutoMigrate bool
+}
+
+// Store implements storage.DeliveryStore on top of GORM.
+type Store struct {
+	db    *gorm.DB
+	table string
+}
+
+type row struct {
+	ID            string    `gorm:"column:id;size:64;primaryKey"`
+	Provider      string    `gorm:"column:provider;size:64;not null;index"`
+	EventName     string    `gorm:"column:event_name;size:128"`
+	RequestID     string    `gorm:"column:request_id;size:128"`
+	DeliveryID    string    `gorm:"column:delivery_id;size:128;index"`
+	StateID       string    `gorm:"column:state_id;size:128"`
+	HeadersJSON   string    `gorm:"column:headers_json;type:text"`
+	Payload       []byte    `gorm:"column:payload"`
+	MatchedTopics string    `gorm:"column:matched_topics;type:text"`
+	Status        string    `gorm:"column:status;size:16"`
+	Error         string    `gorm:"column:error;type:text"`
+	ReplayOf      string    `gorm:"column:replay_of;size:64"`
+	CreatedAt     time.Time `gorm:"column:created_at;index"`
+}
+
+// Open creates a GORM-backed deliveries store.
+func Open(cfg Config) (*Store, error) {
+	if cfg.Driver == "" && cfg.Dialect == "" {
+		return nil, errors.New("storage driver or dialect is required")
+	}
+	if cfg.DSN == "" {
+		return nil, errors.New("storage dsn is required")
+	}
+	driver := normalizeDriver(cfg.Driver)
+	if driver == "" {
+		driver = normalizeDriver(cfg.Dialect)
+	}
+	if driver == "" {
+		return nil, errors.New("unsupported storage driver")
+	}
+
+	gormDB, err := openGorm(driver, cfg.DSN)
+	if err != nil {
+		return nil, err
+	}
+
+	table := cfg.Table
+	if table == "" {
+		table = "githooks_deliveries"
+	}
+	store := &Store{
+		db:    gormDB,
+		table: table,
+	}
+	if cfg.AutoMigrate {
+		if err := store.migrate(); err != nil {
+			return nil, err
+		}
+	}
+	return store, nil
+}
+
+// Close closes the underlying DB connection.
+func (s *Store) Close() error {
+	if s == nil || s.db == nil {
+		return nil
+	}
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// InsertDelivery archives a delivery.
+func (s *Store) InsertDelivery(ctx context.Context, record storage.DeliveryRecord) error {
+	if s == nil || s.db == nil {
+		return errors.New("store is not initialized")
+	}
+	if record.ID == "" {
+		return errors.New("id is required")
+	}
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = time.Now().UTC()
+	}
+	data, err := toRow(record)
+	if err != nil {
+		return err
+	}
+	return s.tableDB().WithContext(ctx).Create(&data).Error
+}
+
+// GetDelivery fetches a single archived delivery.
+func (s *Store) GetDelivery(ctx context.Context, id string) (*storage.DeliveryRecord, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store is not initialized")
+	}
+	var data row
+	err := s.tableDB().
+		WithContext(ctx).
+		Where("id = ?", id).
+		Take(&data).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	record := fromRow(data)
+	return &record, nil
+}
+
+// ListDeliveries lists archived deliveries, newest first.
+func (s *Store) ListDeliveries(ctx context.Context, filter storage.DeliveryFilter) ([]storage.DeliveryRecord, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store is not initialized")
+	}
+	query := s.tableDB().WithContext(ctx)
+	if filter.Provider != "" {
+		query = query.Where("provider = ?", filter.Provider)
+	}
+	if filter.EventName != "" {
+		query = query.Where("event_name = ?", filter.EventName)
+	}
+	if filter.StateID != "" {
+		query = query.Where("state_id = ?", filter.StateID)
+	}
+	if filter.DeliveryID != "" {
+		query = query.Where("delivery_id = ?", filter.DeliveryID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Since != nil {
+		query = query.Where("created_at >= ?", filter.Since.UTC())
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+	var data []row
+	err := query.
+		Order("created_at desc").
+		Limit(limit).
+		Offset(filter.Offset).
+		Find(&data).Error
+	if err != nil {
+		return nil, err
+	}
+	records := make([]storage.DeliveryRecord, 0, len(data))
+	for _, item := range data {
+		records = append(records, fromRow(item))
+	}
+	return records, nil
+}
+
+// DeleteDeliveriesBefore removes archived deliveries created before cutoff.
+func (s *Store) DeleteDeliveriesBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	if s == nil || s.db == nil {
+		return 0, errors.New("store is not initialized")
+	}
+	result := s.tableDB().
+		WithContext(ctx).
+		Where("created_at < ?", cutoff.UTC()).
+		Delete(&row{})
+	return result.RowsAffected, result.Error
+}
+
+func (s *Store) migrate() error {
+	return s.tableDB().AutoMigrate(&row{})
+}
+
+func (s *Store) tableDB() *gorm.DB {
+	return s.db.Table(s.table)
+}
+
+func toRow(record storage.DeliveryRecord) (row, error) {
+	headers, err := json.Marshal(record.Headers)
+	if err != nil {
+		return row{}, err
+	}
+	topics, err := json.Marshal(record.MatchedTopics)
+	if err != nil {
+		return row{}, err
+	}
+	return row{
+		ID:            record.ID,
+		Provider:      record.Provider,
+		EventName:     record.EventName,
+		RequestID:     record.RequestID,
+		DeliveryID:    record.DeliveryID,
+		StateID:       record.StateID,
+		HeadersJSON:   string(headers),
+		Payload:       record.Payload,
+		MatchedTopics: string(topics),
+		Status:        record.Status,
+		Error:         record.Error,
+		ReplayOf:      record.ReplayOf,
+		CreatedAt:     record.CreatedAt,
+	}, nil
+}
+
+func fromRow(data row) storage.DeliveryRecord {
+	var headers map[string]string
+	_ = json.Unmarshal([]byte(data.HeadersJSON), &headers)
+	var topics []string
+	_ = json.Unmarshal([]byte(data.MatchedTopics), &topics)
+	return storage.DeliveryRecord{
+		ID:            data.ID,
+		Provider:      data.Provider,
+		EventName:     data.EventName,
+		RequestID:     data.RequestID,
+		DeliveryID:    data.DeliveryID,
+		StateID:       data.StateID,
+		Headers:       headers,
+		Payload:       data.Payload,
+		MatchedTopics: topics,
+		Status:        data.Status,
+		Error:         data.Error,
+		ReplayOf:      data.ReplayOf,
+		CreatedAt:     data.CreatedAt,
+	}
+}
+
+func normalizeDriver(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch value {
+	case "postgres", "postgresql", "pgx":
+		return "postgres"
+	case "mysql":
+		return "mysql"
+	case "sqlite", "sqlite3":
+		return "sqlite"
+	default:
+		return ""
+	}
+}
+
+func openGorm(driver, dsn string) (*gorm.DB, error) {
+	switch driver {
+	case "postgres":
+		return gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	case "mysql":
+		return gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	case "sqlite":
+		return gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	default:
+		return nil, fmt.Errorf("unsupported storage driver: %s", driver)
+	}
+}
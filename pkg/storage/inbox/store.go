@@ -0,0 +1,304 @@
+package inbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"githooks/pkg/storage"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Config mirrors the storage configuration for the inbox table.
+type Config struct {
+	Driver      string
+	DSN         string
+	Dialect     string
+	Table       string
+	AutoMigrate bool
+}
+
+// Store implements storage.InboxStore on top of GORM.
+type Store struct {
+	db    *gorm.DB
+	table string
+}
+
+type row struct {
+	ID            string     `gorm:"column:id;size:64;primaryKey"`
+	Provider      string     `gorm:"column:provider;size:64;not null"`
+	EventName     string     `gorm:"column:event_name;size:128"`
+	RequestID     string     `gorm:"column:request_id;size:128"`
+	DeliveryID    string     `gorm:"column:delivery_id;size:128"`
+	StateID       string     `gorm:"column:state_id;size:128"`
+	Duplicate     bool       `gorm:"column:duplicate"`
+	HeadersJSON   string     `gorm:"column:headers_json;type:text"`
+	Payload       []byte     `gorm:"column:payload"`
+	Status        string     `gorm:"column:status;size:16;not null;index:idx_inbox_due,priority:1"`
+	Attempts      int        `gorm:"column:attempts"`
+	LastError     string     `gorm:"column:last_error;type:text"`
+	PublishedJSON string     `gorm:"column:published_json;type:text"`
+	NextAttemptAt time.Time  `gorm:"column:next_attempt_at;index:idx_inbox_due,priority:2"`
+	LockedUntil   *time.Time `gorm:"column:locked_until"`
+	CreatedAt     time.Time  `gorm:"column:created_at;autoCreateTime"`
+	UpdatedAt     time.Time  `gorm:"column:updated_at;autoUpdateTime"`
+}
+
+// Open creates a GORM-backed inbox store.
+func Open(cfg Config) (*Store, error) {
+	if cfg.Driver == "" && cfg.Dialect == "" {
+		return nil, errors.New("storage driver or dialect is required")
+	}
+	if cfg.DSN == "" {
+		return nil, errors.New("storage dsn is required")
+	}
+	driver := normalizeDriver(cfg.Driver)
+	if driver == "" {
+		driver = normalizeDriver(cfg.Dialect)
+	}
+	if driver == "" {
+		return nil, errors.New("unsupported storage driver")
+	}
+
+	gormDB, err := openGorm(driver, cfg.DSN)
+	if err != nil {
+		return nil, err
+	}
+
+	table := cfg.Table
+	if table == "" {
+		table = "githooks_inbox"
+	}
+	store := &Store{
+		db:    gormDB,
+		table: table,
+	}
+	if cfg.AutoMigrate {
+		if err := store.migrate(); err != nil {
+			return nil, err
+		}
+	}
+	return store, nil
+}
+
+// Close closes the underlying DB connection.
+func (s *Store) Close() error {
+	if s == nil || s.db == nil {
+		return nil
+	}
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// EnqueueInbox inserts a pending inbox record.
+func (s *Store) EnqueueInbox(ctx context.Context, record storage.InboxRecord) error {
+	if s == nil || s.db == nil {
+		return errors.New("store is not initialized")
+	}
+	if record.ID == "" {
+		return errors.New("id is required")
+	}
+	if record.Provider == "" {
+		return errors.New("provider is required")
+	}
+	now := time.Now().UTC()
+	if record.Status == "" {
+		record.Status = storage.InboxStatusPending
+	}
+	if record.NextAttemptAt.IsZero() {
+		record.NextAttemptAt = now
+	}
+	record.CreatedAt = now
+	record.UpdatedAt = now
+	data, err := toRow(record)
+	if err != nil {
+		return err
+	}
+	return s.tableDB().WithContext(ctx).Create(&data).Error
+}
+
+// ClaimInbox leases up to limit due pending rows. Each row is claimed with a
+// conditional update so concurrent dispatchers never process the same row.
+func (s *Store) ClaimInbox(ctx context.Context, limit int, lease time.Duration) ([]storage.InboxRecord, error) {
+	if s == nil || s.db == nil {
+		return nil, errors.New("store is not initialized")
+	}
+	if limit <= 0 {
+		limit = 1
+	}
+	now := time.Now().UTC()
+	var candidates []row
+	err := s.tableDB().
+		WithContext(ctx).
+		Where("status = ? AND next_attempt_at <= ? AND (locked_until IS NULL OR locked_until < ?)", storage.InboxStatusPending, now, now).
+		Order("next_attempt_at asc").
+		Limit(limit).
+		Find(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+
+	lockedUntil := now.Add(lease)
+	records := make([]storage.InboxRecord, 0, len(candidates))
+	for _, item := range candidates {
+		result := s.tableDB().
+			WithContext(ctx).
+			Where("id = ? AND status = ? AND (locked_until IS NULL OR locked_until < ?)", item.ID, storage.InboxStatusPending, now).
+			Updates(map[string]interface{}{"locked_until": lockedUntil, "updated_at": now})
+		if result.Error != nil {
+			return records, result.Error
+		}
+		if result.RowsAffected != 1 {
+			continue
+		}
+		item.LockedUntil = &lockedUntil
+		records = append(records, fromRow(item))
+	}
+	return records, nil
+}
+
+// MarkInboxSent marks a record as published.
+func (s *Store) MarkInboxSent(ctx context.Context, id string) error {
+	return s.update(ctx, id, map[string]interface{}{
+		"status":       storage.InboxStatusSent,
+		"locked_until": nil,
+		"last_error":   "",
+	})
+}
+
+// MarkInboxRetry schedules a record for another attempt and records the
+// rule matches that were published so far.
+func (s *Store) MarkInboxRetry(ctx context.Context, id string, attempts int, nextAttemptAt time.Time, lastError string, publishedMatches []string) error {
+	published, err := json.Marshal(publishedMatches)
+	if err != nil {
+		return err
+	}
+	return s.update(ctx, id, map[string]interface{}{
+		"attempts":        attempts,
+		"next_attempt_at": nextAttemptAt.UTC(),
+		"locked_until":    nil,
+		"last_error":      lastError,
+		"published_json":  string(published),
+	})
+}
+
+// MarkInboxFailed marks a record as permanently failed.
+func (s *Store) MarkInboxFailed(ctx context.Context, id string, attempts int, lastError string) error {
+	return s.update(ctx, id, map[string]interface{}{
+		"status":       storage.InboxStatusFailed,
+		"attempts":     attempts,
+		"locked_until": nil,
+		"last_error":   lastError,
+	})
+}
+
+func (s *Store) update(ctx context.Context, id string, values map[string]interface{}) error {
+	if s == nil || s.db == nil {
+		return errors.New("store is not initialized")
+	}
+	values["updated_at"] = time.Now().UTC()
+	return s.tableDB().WithContext(ctx).Where("id = ?", id).Updates(values).Error
+}
+
+func (s *Store) migrate() error {
+	return s.tableDB().AutoMigrate(&row{})
+}
+
+func (s *Store) tableDB() *gorm.DB {
+	return s.db.Table(s.table)
+}
+
+func toRow(record storage.InboxRecord) (row, error) {
+	headers, err := json.Marshal(record.Headers)
+	if err != nil {
+		return row{}, err
+	}
+	published, err := json.Marshal(record.PublishedMatches)
+	if err != nil {
+		return row{}, err
+	}
+	return row{
+		ID:            record.ID,
+		Provider:      record.Provider,
+		EventName:     record.EventName,
+		RequestID:     record.RequestID,
+		DeliveryID:    record.DeliveryID,
+		StateID:       record.StateID,
+		Duplicate:     record.Duplicate,
+		HeadersJSON:   string(headers),
+		Payload:       record.Payload,
+		Status:        record.Status,
+		Attempts:      record.Attempts,
+		LastError:     record.LastError,
+		PublishedJSON: string(published),
+		NextAttemptAt: record.NextAttemptAt,
+		LockedUntil:   record.LockedUntil,
+		CreatedAt:     record.CreatedAt,
+		UpdatedAt:     record.UpdatedAt,
+	}, nil
+}
+
+func fromRow(data row) storage.InboxRecord {
+	var headers map[string]string
+	_ = json.Unmarshal([]byte(data.HeadersJSON), &headers)
+	var published []string
+	if data.PublishedJSON != "" {
+		_ = json.Unmarshal([]byte(data.PublishedJSON), &published)
+	}
+	return storage.InboxRecord{
+		ID:               data.ID,
+		Provider:         data.Provider,
+		EventName:        data.EventName,
+		RequestID:        data.RequestID,
+		DeliveryID:       data.DeliveryID,
+		StateID:          data.StateID,
+		Duplicate:        data.Duplicate,
+		Headers:          headers,
+		Payload:          data.Payload,
+		Status:           data.Status,
+		Attempts:         data.Attempts,
+		LastError:        data.LastError,
+		PublishedMatches: published,
+		NextAttemptAt:    data.NextAttemptAt,
+		LockedUntil:      data.LockedUntil,
+		CreatedAt:        data.CreatedAt,
+		UpdatedAt:        data.UpdatedAt,
+	}
+}
+
+func normalizeDriver(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch value {
+	case "postgres", "postgresql", "pgx":
+		return "postgres"
+	case "mysql":
+		return "mysql"
+	case "sqlite", "sqlite3":
+		return "sqlite"
+	default:
+		return ""
+	}
+}
+
+func openGorm(driver, dsn string) (*gorm.DB, error) {
+	switch driver {
+	case "postgres":
+		return gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	case "mysql":
+		return gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	case "sqlite":
+		return gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	default:
+		return nil, fmt.Errorf("unsupported storage driver: %s", driver)
+	}
+}
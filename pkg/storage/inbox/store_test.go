@@ -0,0 +1,89 @@
+package inbox
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"githooks/pkg/storage"
+)
+
+func openTestStore(t *testing.T) *Store {
+	t.Helper()
+	store, err := Open(Config{
+		Driver:      "sqlite",
+		DSN:         filepath.Join(t.TempDir(), "inbox.db"),
+		AutoMigrate: true,
+	})
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	return store
+}
+
+// TestStoreClaimLease tests that claimed rows stay invisible until their lease expires.
+func TestStoreClaimLease(t *testing.T) {
+	store := openTestStore(t)
+	ctx := context.Background()
+	if err := store.EnqueueInbox(ctx, storage.InboxRecord{ID: "a", Provider: "github", Headers: map[string]string{"X-Github-Event": "push"}}); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+
+	claimed, err := store.ClaimInbox(ctx, 10, 50*time.Millisecond)
+	if err != nil || len(claimed) != 1 {
+		t.Fatalf("expected one claimed row, got %d err=%v", len(claimed), err)
+	}
+	if claimed[0].Headers["X-Github-Event"] != "push" || claimed[0].LockedUntil == nil {
+		t.Fatalf("unexpected claimed row: %+v", claimed[0])
+	}
+	if again, _ := store.ClaimInbox(ctx, 10, time.Minute); len(again) != 0 {
+		t.Fatalf("expected leased row to be skipped, got %d", len(again))
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	reclaimed, err := store.ClaimInbox(ctx, 10, time.Minute)
+	if err != nil || len(reclaimed) != 1 || reclaimed[0].ID != "a" {
+		t.Fatalf("expected expired lease to be reclaimed, got %+v err=%v", reclaimed, err)
+	}
+}
+
+// TestStoreRetryAndFailure tests retry scheduling, published matches and the failed state.
+func TestStoreRetryAndFailure(t *testing.T) {
+	store := openTestStore(t)
+	ctx := context.Background()
+	if err := store.EnqueueInbox(ctx, storage.InboxRecord{ID: "a", Provider: "github"}); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	if _, err := store.ClaimInbox(ctx, 1, time.Minute); err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+
+	next := time.Now().UTC().Add(50 * time.Millisecond)
+	if err := store.MarkInboxRetry(ctx, "a", 1, next, "boom", []string{"pr.opened"}); err != nil {
+		t.Fatalf("mark retry: %v", err)
+	}
+	if due, _ := store.ClaimInbox(ctx, 1, time.Minute); len(due) != 0 {
+		t.Fatalf("expected row to wait for its next attempt")
+	}
+	time.Sleep(100 * time.Millisecond)
+	due, err := store.ClaimInbox(ctx, 1, time.Minute)
+	if err != nil || len(due) != 1 {
+		t.Fatalf("expected row to be due, got %d err=%v", len(due), err)
+	}
+	if due[0].Attempts != 1 || due[0].LastError != "boom" {
+		t.Fatalf("unexpected retry state: %+v", due[0])
+	}
+	if len(due[0].PublishedMatches) != 1 || due[0].PublishedMatches[0] != "pr.opened" {
+		t.Fatalf("expected published matches to persist, got %v", due[0].PublishedMatches)
+	}
+
+	if err := store.MarkInboxFailed(ctx, "a", 2, "boom"); err != nil {
+		t.Fatalf("mark failed: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if failed, _ := store.ClaimInbox(ctx, 1, time.Minute); len(failed) != 0 {
+		t.Fatalf("expected failed row to never be claimed again")
+	}
+}
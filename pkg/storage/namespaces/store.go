@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
 	"strings"
 	"time"
 
@@ -23,12 +24,18 @@ type Config struct {
 	Dialect     string
 	Table       string
 	AutoMigrate bool
+	// EncryptionKey encrypts per-namespace webhook secrets at rest.
+	EncryptionKey string
+	// Logger reports rows whose webhook secret cannot be read. Defaults to log.Default().
+	Logger *log.Logger
 }
 
 // Store implements storage.NamespaceStore on top of GORM.
 type Store struct {
-	db    *gorm.DB
-	table string
+	db     *gorm.DB
+	table  string
+	cipher *storage.SecretCipher
+	logger *log.Logger
 }
 
 type row struct {
@@ -44,6 +51,7 @@ type row struct {
 	HTTPURL        string    `gorm:"column:http_url;size:512"`
 	SSHURL         string    `gorm:"column:ssh_url;size:512"`
 	WebhooksEnabled bool     `gorm:"column:webhooks_enabled"`
+	WebhookSecret  string    `gorm:"column:webhook_secret;size:255"`
 	CreatedAt      time.Time `gorm:"column:created_at;autoCreateTime"`
 	UpdatedAt      time.Time `gorm:"column:updated_at;autoUpdateTime"`
 }
@@ -73,9 +81,21 @@ func Open(cfg Config) (*Store, error) {
 	if table == "" {
 		table = "git_namespaces"
 	}
+	logger := cfg.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
 	store := &Store{
-		db:    gormDB,
-		table: table,
+		db:     gormDB,
+		table:  table,
+		logger: logger,
+	}
+	if cfg.EncryptionKey != "" {
+		secretCipher, err := storage.NewSecretCipher(cfg.EncryptionKey)
+		if err != nil {
+			return nil, err
+		}
+		store.cipher = secretCipher
 	}
 	if cfg.AutoMigrate {
 		if err := store.migrate(); err != nil {
@@ -112,16 +132,30 @@ func (s *Store) UpsertNamespace(ctx context.Context, record storage.NamespaceRec
 	record.UpdatedAt = now
 
 	data := toRow(record)
+	columns := []string{"account_id", "installation_id", "owner", "repo_name", "full_name", "visibility", "default_branch", "http_url", "ssh_url", "webhooks_enabled", "updated_at"}
+	// Syncs upsert without a secret; only overwrite the stored one when a new one is set.
+	if record.WebhookSecret != "" {
+		if s.cipher == nil {
+			return errors.New("storage encryption key is required to store webhook secrets")
+		}
+		sealed, err := s.cipher.Encrypt(record.WebhookSecret)
+		if err != nil {
+			return err
+		}
+		data.WebhookSecret = sealed
+		columns = append(columns, "webhook_secret")
+	}
 	return s.tableDB().
 		WithContext(ctx).
 		Clauses(clause.OnConflict{
 			Columns:   []clause.Column{{Name: "provider"}, {Name: "repo_id"}},
-			DoUpdates: clause.AssignmentColumns([]string{"account_id", "installation_id", "owner", "repo_name", "full_name", "visibility", "default_branch", "http_url", "ssh_url", "webhooks_enabled", "updated_at"}),
+			DoUpdates: clause.AssignmentColumns(columns),
 		}).
 		Create(&data).Error
 }
 
-// GetNamespace fetches a namespace by provider/repo ID.
+// GetNamespace fetches a namespace by provider/repo ID. It fails when the row's
+// webhook secret cannot be decrypted, so webhook verification fails closed.
 func (s *Store) GetNamespace(ctx context.Context, provider, repoID string) (*storage.NamespaceRecord, error) {
 	if s == nil || s.db == nil {
 		return nil, errors.New("store is not initialized")
@@ -137,11 +171,15 @@ func (s *Store) GetNamespace(ctx context.Context, provider, repoID string) (*sto
 	if err != nil {
 		return nil, err
 	}
-	record := fromRow(data)
+	record, err := s.decode(data)
+	if err != nil {
+		return nil, err
+	}
 	return &record, nil
 }
 
-// ListNamespaces lists namespaces by filter.
+// ListNamespaces lists namespaces by filter. Rows whose webhook secret cannot
+// be decrypted are logged and returned without it.
 func (s *Store) ListNamespaces(ctx context.Context, filter storage.NamespaceFilter) ([]storage.NamespaceRecord, error) {
 	if s == nil || s.db == nil {
 		return nil, errors.New("store is not initialized")
@@ -172,7 +210,13 @@ func (s *Store) ListNamespaces(ctx context.Context, filter storage.NamespaceFilt
 	}
 	records := make([]storage.NamespaceRecord, 0, len(data))
 	for _, item := range data {
-		records = append(records, fromRow(item))
+		record, err := s.decode(item)
+		if err != nil {
+			// One unreadable secret must not hide every other namespace; the
+			// row is listed without its secret.
+			s.logger.Printf("namespace %s/%s: %v", item.Provider, item.RepoID, err)
+		}
+		records = append(records, record)
 	}
 	return records, nil
 }
@@ -185,6 +229,23 @@ func (s *Store) tableDB() *gorm.DB {
 	return s.db.Table(s.table)
 }
 
+// decode converts a row and decrypts its webhook secret.
+func (s *Store) decode(data row) (storage.NamespaceRecord, error) {
+	record := fromRow(data)
+	if data.WebhookSecret == "" {
+		return record, nil
+	}
+	if s.cipher == nil {
+		return record, errors.New("storage encryption key is required to read webhook secrets")
+	}
+	secret, err := s.cipher.Decrypt(data.WebhookSecret)
+	if err != nil {
+		return record, fmt.Errorf("decrypt webhook secret: %w", err)
+	}
+	record.WebhookSecret = secret
+	return record, nil
+}
+
 func toRow(record storage.NamespaceRecord) row {
 	return row{
 		Provider:       record.Provider,
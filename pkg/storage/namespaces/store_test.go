@@ -0,0 +1,57 @@
+package namespaces
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"githooks/pkg/storage"
+)
+
+// TestListNamespacesUnreadableSecret tests that a row whose secret fails to decrypt is listed without it.
+func TestListNamespacesUnreadableSecret(t *testing.T) {
+	var logs bytes.Buffer
+	store, err := Open(Config{
+		Driver:        "sqlite",
+		DSN:           filepath.Join(t.TempDir(), "namespaces.db"),
+		AutoMigrate:   true,
+		EncryptionKey: "current-key",
+		Logger:        log.New(&logs, "", 0),
+	})
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	defer store.Close()
+	ctx := context.Background()
+	for _, repoID := range []string{"1", "2"} {
+		record := storage.NamespaceRecord{Provider: "gitlab", RepoID: repoID, AccountID: "acme", WebhookSecret: "secret-" + repoID}
+		if err := store.UpsertNamespace(ctx, record); err != nil {
+			t.Fatalf("upsert: %v", err)
+		}
+	}
+	if err := store.tableDB().Where("repo_id = ?", "2").Update("webhook_secret", "corrupted").Error; err != nil {
+		t.Fatalf("corrupt secret: %v", err)
+	}
+
+	records, err := store.ListNamespaces(ctx, storage.NamespaceFilter{Provider: "gitlab"})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	secrets := map[string]string{}
+	for _, record := range records {
+		secrets[record.RepoID] = record.WebhookSecret
+	}
+	if len(records) != 2 || secrets["1"] != "secret-1" || secrets["2"] != "" {
+		t.Fatalf("unexpected secrets: %v", secrets)
+	}
+	if !strings.Contains(logs.String(), "gitlab/2") {
+		t.Fatalf("expected unreadable secret to be logged, got %q", logs.String())
+	}
+
+	if _, err := store.GetNamespace(ctx, "gitlab", "2"); err == nil {
+		t.Fatalf("expected lookup of the unreadable row to fail")
+	}
+}
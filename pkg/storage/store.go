@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
@@ -33,6 +34,9 @@ type NamespaceRecord struct {
 	HTTPURL        string
 	SSHURL         string
 	WebhooksEnabled bool
+	// WebhookSecret is the generated per-repository webhook secret. It is
+	// encrypted at rest and never serialized.
+	WebhookSecret string `json:"-"`
 	CreatedAt      time.Time
 	UpdatedAt      time.Time
 }
@@ -63,3 +67,109 @@ type NamespaceStore interface {
 	ListNamespaces(ctx context.Context, filter NamespaceFilter) ([]NamespaceRecord, error)
 	Close() error
 }
+
+// DedupeStore records webhook delivery ids so provider redeliveries can be detected.
+type DedupeStore interface {
+	// Reserve records a delivery and reports whether it was seen for the first time.
+	Reserve(ctx context.Context, provider, deliveryID string) (bool, error)
+	// Release forgets a delivery so a later redelivery is processed again.
+	Release(ctx context.Context, provider, deliveryID string) error
+	// Complete marks a reserved delivery as processed.
+	Complete(ctx context.Context, provider, deliveryID string) error
+	// InFlight reports whether a delivery is reserved but not yet completed or
+	// released.
+	InFlight(ctx context.Context, provider, deliveryID string) (bool, error)
+	Close() error
+}
+
+// Inbox statuses.
+const (
+	InboxStatusPending = "pending"
+	InboxStatusSent    = "sent"
+	InboxStatusFailed  = "failed"
+)
+
+// InboxRecord stores a received webhook awaiting rule evaluation and publishing.
+type InboxRecord struct {
+	ID         string
+	Provider   string
+	EventName  string
+	RequestID  string
+	DeliveryID string
+	StateID    string
+	Duplicate  bool
+	Headers    map[string]string
+	Payload    []byte
+	Status     string
+	Attempts   int
+	LastError  string
+	// PublishedMatches lists the rule matches already published by earlier
+	// attempts; retries skip them.
+	PublishedMatches []string
+	NextAttemptAt    time.Time
+	LockedUntil      *time.Time
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+// InboxStore persists received webhooks for asynchronous dispatch.
+type InboxStore interface {
+	EnqueueInbox(ctx context.Context, record InboxRecord) error
+	// ClaimInbox leases up to limit pending rows that are due. Rows whose lease
+	// expired (e.g., after a crash) are claimable again.
+	ClaimInbox(ctx context.Context, limit int, lease time.Duration) ([]InboxRecord, error)
+	MarkInboxSent(ctx context.Context, id string) error
+	// MarkInboxRetry schedules another attempt and records the matches published so far.
+	MarkInboxRetry(ctx context.Context, id string, attempts int, nextAttemptAt time.Time, lastError string, publishedMatches []string) error
+	MarkInboxFailed(ctx context.Context, id string, attempts int, lastError string) error
+	Close() error
+}
+
+// Delivery archive statuses.
+const (
+	DeliveryStatusPublished = "published"
+	DeliveryStatusUnmatched = "unmatched"
+	DeliveryStatusFailed    = "failed"
+)
+
+// ErrDeliveryNotFound is returned when an archived delivery does not exist.
+var ErrDeliveryNotFound = errors.New("delivery not found")
+
+// DeliveryRecord archives a received webhook and the outcome of publishing it.
+type DeliveryRecord struct {
+	ID            string
+	Provider      string
+	EventName     string
+	RequestID     string
+	DeliveryID    string
+	StateID       string
+	Headers       map[string]string
+	Payload       []byte
+	MatchedTopics []string
+	Status        string
+	Error         string
+	// ReplayOf is the id of the archived delivery this record replays.
+	ReplayOf  string
+	CreatedAt time.Time
+}
+
+// DeliveryFilter selects archived deliveries, newest first.
+type DeliveryFilter struct {
+	Provider   string
+	EventName  string
+	StateID    string
+	DeliveryID string
+	Status     string
+	Since      *time.Time
+	Limit      int
+	Offset     int
+}
+
+// DeliveryStore persists the webhook delivery archive.
+type DeliveryStore interface {
+	InsertDelivery(ctx context.Context, record DeliveryRecord) error
+	GetDelivery(ctx context.Context, id string) (*DeliveryRecord, error)
+	ListDeliveries(ctx context.Context, filter DeliveryFilter) ([]DeliveryRecord, error)
+	DeleteDeliveriesBefore(ctx context.Context, cutoff time.Time) (int64, error)
+	Close() error
+}
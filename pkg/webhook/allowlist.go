@@ -0,0 +1,253 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IPAllowlist restricts webhook requests to known source ranges. The client
+// address is taken from X-Forwarded-For only when the request comes through a
+// trusted proxy.
+type IPAllowlist struct {
+	static         []netip.Prefix
+	trustedProxies []netip.Prefix
+	logger         *log.Logger
+
+	// metaRetry is the first delay between GitHub meta fetches while no
+	// ranges have been loaded; it doubles up to a minute.
+	metaRetry time.Duration
+
+	mu      sync.RWMutex
+	dynamic []netip.Prefix
+	// metaErr is the last GitHub meta fetch error, reported when a request is
+	// rejected before any ranges were loaded.
+	metaErr error
+}
+
+// NewIPAllowlist creates an allowlist from static CIDRs and trusted proxy CIDRs.
+// Bare addresses are accepted as single-host prefixes.
+func NewIPAllowlist(cidrs []string, trustedProxies []string, logger *log.Logger) (*IPAllowlist, error) {
+	static, err := parsePrefixes(cidrs)
+	if err != nil {
+		return nil, err
+	}
+	proxies, err := parsePrefixes(trustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("trusted proxies: %w", err)
+	}
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &IPAllowlist{
+		static:         static,
+		trustedProxies: proxies,
+		logger:         logger,
+		metaRetry:      time.Second,
+	}, nil
+}
+
+// SetDynamic replaces the dynamically refreshed ranges.
+func (a *IPAllowlist) SetDynamic(prefixes []netip.Prefix) {
+	a.mu.Lock()
+	a.dynamic = prefixes
+	a.mu.Unlock()
+}
+
+// Allowed reports whether the request's client address is in the allowlist.
+func (a *IPAllowlist) Allowed(r *http.Request) (netip.Addr, bool) {
+	addr, ok := a.clientAddr(r)
+	if !ok {
+		return addr, false
+	}
+	if containsAddr(a.static, addr) {
+		return addr, true
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return addr, containsAddr(a.dynamic, addr)
+}
+
+// allow rejects requests from addresses outside the allowlist with 403 and
+// logs why. A nil allowlist allows everything.
+func (a *IPAllowlist) allow(w http.ResponseWriter, r *http.Request, logger *log.Logger) bool {
+	if a == nil {
+		return true
+	}
+	addr, ok := a.Allowed(r)
+	if ok {
+		return true
+	}
+	logger.Printf("ip allowlist error: webhook rejected: %s", a.rejectReason(r, addr))
+	w.WriteHeader(http.StatusForbidden)
+	return false
+}
+
+func (a *IPAllowlist) rejectReason(r *http.Request, addr netip.Addr) string {
+	if !addr.IsValid() {
+		return fmt.Sprintf("no client address in remote=%q x-forwarded-for=%q", r.RemoteAddr, r.Header.Values("X-Forwarded-For"))
+	}
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if len(a.dynamic) == 0 && a.metaErr != nil {
+		return fmt.Sprintf("source ip %s not allowed; github meta ranges not loaded: %v", addr, a.metaErr)
+	}
+	return fmt.Sprintf("source ip %s not allowed", addr)
+}
+
+// clientAddr returns the remote address, or when the peer is a trusted proxy,
+// the right-most X-Forwarded-For entry that is not itself a trusted proxy.
+func (a *IPAllowlist) clientAddr(r *http.Request) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	addr = addr.Unmap()
+	if !containsAddr(a.trustedProxies, addr) {
+		return addr, true
+	}
+
+	var hops []string
+	for _, value := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(value, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			return netip.Addr{}, false
+		}
+		hop = hop.Unmap()
+		addr = hop
+		if !containsAddr(a.trustedProxies, hop) {
+			break
+		}
+	}
+	return addr, true
+}
+
+// RefreshGitHubMeta loads the "hooks" ranges from the GitHub /meta endpoint.
+func (a *IPAllowlist) RefreshGitHubMeta(ctx context.Context, baseURL string) error {
+	err := a.refreshGitHubMeta(ctx, baseURL)
+	a.mu.Lock()
+	a.metaErr = err
+	a.mu.Unlock()
+	return err
+}
+
+func (a *IPAllowlist) refreshGitHubMeta(ctx context.Context, baseURL string) error {
+	baseURL = strings.TrimRight(baseURL, "/")
+	if baseURL == "" {
+		baseURL = "https://api.github.com"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+"/meta", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("github meta failed: %s body=%s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	var payload struct {
+		Hooks []string `json:"hooks"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return err
+	}
+	prefixes, err := parsePrefixes(payload.Hooks)
+	if err != nil {
+		return err
+	}
+	if len(prefixes) == 0 {
+		return fmt.Errorf("github meta returned no hook ranges")
+	}
+	a.SetDynamic(prefixes)
+	return nil
+}
+
+// RunGitHubMetaRefresh refreshes the GitHub hook ranges every interval until
+// ctx is cancelled. The last good ranges are kept when a refresh fails. Until
+// ranges have been loaded, failed fetches are retried with a short backoff
+// instead of waiting a full interval. Call RefreshGitHubMeta first to load the
+// initial ranges.
+func (a *IPAllowlist) RunGitHubMetaRefresh(ctx context.Context, baseURL string, interval time.Duration) {
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	retry := a.metaRetry
+	next := func() time.Duration {
+		a.mu.RLock()
+		loaded := len(a.dynamic) > 0
+		a.mu.RUnlock()
+		if loaded || retry <= 0 {
+			return interval
+		}
+		delay := min(retry, interval)
+		retry = min(retry*2, time.Minute)
+		return delay
+	}
+	timer := time.NewTimer(next())
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+		if err := a.RefreshGitHubMeta(ctx, baseURL); err != nil && ctx.Err() == nil {
+			a.logger.Printf("github meta refresh failed: %v", err)
+		}
+		timer.Reset(next())
+	}
+}
+
+func parsePrefixes(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if !strings.Contains(value, "/") {
+			addr, err := netip.ParseAddr(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid address %q: %w", value, err)
+			}
+			addr = addr.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cidr %q: %w", value, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,128 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestIPAllowlistTrustedProxies tests that X-Forwarded-For is only honored behind a trusted proxy.
+func TestIPAllowlistTrustedProxies(t *testing.T) {
+	allowlist, err := NewIPAllowlist([]string{"192.30.252.0/22", "2001:db8::1"}, []string{"10.0.0.0/8"}, nil)
+	if err != nil {
+		t.Fatalf("allowlist: %v", err)
+	}
+	cases := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		want       bool
+	}{
+		{name: "direct allowed", remoteAddr: "192.30.252.10:443", want: true},
+		{name: "direct ipv6 host", remoteAddr: "[2001:db8::1]:443", want: true},
+		{name: "direct denied", remoteAddr: "203.0.113.5:443", want: false},
+		{name: "untrusted peer spoofs header", remoteAddr: "203.0.113.5:443", forwarded: "192.30.252.10", want: false},
+		{name: "trusted proxy forwards allowed", remoteAddr: "10.1.2.3:443", forwarded: "192.30.252.10", want: true},
+		{name: "trusted proxy chain", remoteAddr: "10.1.2.3:443", forwarded: "192.30.252.10, 10.9.9.9", want: true},
+		{name: "client prepends spoofed hop", remoteAddr: "10.1.2.3:443", forwarded: "192.30.252.10, 203.0.113.5", want: false},
+		{name: "trusted proxy without header", remoteAddr: "10.1.2.3:443", want: false},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/github", nil)
+		req.RemoteAddr = tc.remoteAddr
+		if tc.forwarded != "" {
+			req.Header.Set("X-Forwarded-For", tc.forwarded)
+		}
+		if _, got := allowlist.Allowed(req); got != tc.want {
+			t.Fatalf("%s: expected %v, got %v", tc.name, tc.want, got)
+		}
+	}
+}
+
+// TestIPAllowlistGitHubMeta tests loading hook ranges from the GitHub meta endpoint.
+func TestIPAllowlistGitHubMeta(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/meta" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"hooks":["140.82.112.0/20"],"web":["1.1.1.1/32"]}`))
+	}))
+	defer server.Close()
+
+	allowlist, err := NewIPAllowlist(nil, nil, nil)
+	if err != nil {
+		t.Fatalf("allowlist: %v", err)
+	}
+	if err := allowlist.RefreshGitHubMeta(context.Background(), server.URL); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/github", nil)
+	req.RemoteAddr = "140.82.115.1:443"
+	if _, ok := allowlist.Allowed(req); !ok {
+		t.Fatalf("expected hook range to be allowed")
+	}
+	req.RemoteAddr = "1.1.1.1:443"
+	if _, ok := allowlist.Allowed(req); ok {
+		t.Fatalf("expected non-hook range to be rejected")
+	}
+}
+
+// TestIPAllowlistGitHubMetaRetry tests that a failed initial meta fetch is
+// retried quickly and that rejections log the fetch error meanwhile.
+func TestIPAllowlistGitHubMetaRetry(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"hooks":["140.82.112.0/20"]}`))
+	}))
+	defer server.Close()
+
+	var logs bytes.Buffer
+	logger := log.New(&logs, "", 0)
+	allowlist, err := NewIPAllowlist(nil, nil, logger)
+	if err != nil {
+		t.Fatalf("allowlist: %v", err)
+	}
+	allowlist.metaRetry = 10 * time.Millisecond
+	if err := allowlist.RefreshGitHubMeta(context.Background(), server.URL); err == nil {
+		t.Fatalf("expected first fetch to fail")
+	}
+
+	send := func() int {
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/github", nil)
+		req.RemoteAddr = "140.82.115.1:443"
+		rec := httptest.NewRecorder()
+		allowlist.allow(rec, req, logger)
+		if rec.Code == 0 {
+			return http.StatusOK
+		}
+		return rec.Code
+	}
+	if code := send(); code != http.StatusForbidden {
+		t.Fatalf("expected 403 before ranges are loaded, got %d", code)
+	}
+	if !strings.Contains(logs.String(), "ip allowlist error") || !strings.Contains(logs.String(), "503") {
+		t.Fatalf("expected rejection to log the meta fetch error, got %q", logs.String())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go allowlist.RunGitHubMetaRefresh(ctx, server.URL, time.Hour)
+	deadline := time.Now().Add(2 * time.Second)
+	for send() != http.StatusOK {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected meta fetch to be retried before the refresh interval, calls=%d", calls.Load())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,201 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+
+	"githooks/internal"
+	"githooks/pkg/storage"
+)
+
+// redactedHeaders are never written to the archive because they carry
+// credentials rather than signatures over the payload. Handlers add their own
+// credential headers (e.g., X-Gitlab-Token) through handlerOptions.
+var redactedHeaders = map[string]bool{
+	"Authorization": true,
+	"Cookie":        true,
+}
+
+// Archive records received deliveries and their publish outcome so they can be
+// inspected and replayed later.
+type Archive struct {
+	store     storage.DeliveryStore
+	rules     *internal.RuleEngine
+	publisher internal.Publisher
+	retention time.Duration
+	logger    *log.Logger
+}
+
+// NewArchive creates a delivery archive backed by store. A non-positive retention
+// keeps deliveries forever.
+func NewArchive(store storage.DeliveryStore, rules *internal.RuleEngine, publisher internal.Publisher, retention time.Duration, logger *log.Logger) (*Archive, error) {
+	if store == nil {
+		return nil, errors.New("delivery store is required")
+	}
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &Archive{
+		store:     store,
+		rules:     rules,
+		publisher: publisher,
+		retention: retention,
+		logger:    logger,
+	}, nil
+}
+
+// Record archives event together with its matched topics and publish outcome.
+// Archive failures are logged and never fail the webhook.
+func (a *Archive) Record(ctx context.Context, headers map[string]string, event internal.Event, matches []internal.RuleMatch, publishErr error) {
+	a.record(ctx, headers, event, matches, publishErr, "")
+}
+
+func (a *Archive) record(ctx context.Context, headers map[string]string, event internal.Event, matches []internal.RuleMatch, publishErr error, replayOf string) *storage.DeliveryRecord {
+	if a == nil {
+		return nil
+	}
+	record := storage.DeliveryRecord{
+		ID:            watermill.NewUUID(),
+		Provider:      event.Provider,
+		EventName:     event.Name,
+		RequestID:     event.RequestID,
+		DeliveryID:    event.DeliveryID,
+		StateID:       event.StateID,
+		Headers:       headers,
+		Payload:       event.RawPayload,
+		MatchedTopics: matchTopics(matches),
+		Status:        deliveryStatus(matches, publishErr),
+		ReplayOf:      replayOf,
+		CreatedAt:     time.Now().UTC(),
+	}
+	if publishErr != nil {
+		record.Error = publishErr.Error()
+	}
+	if err := a.store.InsertDelivery(ctx, record); err != nil {
+		a.logger.Printf("archive delivery failed provider=%s name=%s: %v", event.Provider, event.Name, err)
+	}
+	return &record
+}
+
+// Replay re-runs the rule engine against an archived delivery and publishes the
+// matches again. A non-empty topic limits publishing to that matched topic and a
+// non-empty driver overrides the drivers of every match. The replay itself is
+// archived and returned.
+func (a *Archive) Replay(ctx context.Context, id string, driver string, topic string) (*storage.DeliveryRecord, error) {
+	original, err := a.store.GetDelivery(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if original == nil {
+		return nil, storage.ErrDeliveryNotFound
+	}
+
+	rawObject, data := rawObjectAndFlatten(original.Payload)
+	event := withNormalized(internal.Event{
+		Provider:   original.Provider,
+		Name:       original.EventName,
+		RequestID:  watermill.NewUUID(),
+		Data:       data,
+		RawPayload: original.Payload,
+		RawObject:  rawObject,
+		StateID:    original.StateID,
+		DeliveryID: original.DeliveryID,
+	})
+	logger := internal.WithRequestID(a.logger, event.RequestID)
+	logger.Printf("replay delivery id=%s driver=%s topic=%s", original.ID, driver, topic)
+
+	matches := a.rules.EvaluateWithLogger(event, logger)
+	if topic != "" {
+		filtered := matches[:0]
+		for _, match := range matches {
+			if match.Topic == topic {
+				filtered = append(filtered, match)
+			}
+		}
+		matches = filtered
+	}
+	if driver != "" {
+		for i := range matches {
+			matches[i].Drivers = []string{driver}
+		}
+	}
+	publishErr := publishMatches(ctx, a.publisher, logger, event, matches)
+	return a.record(ctx, original.Headers, event, matches, publishErr, original.ID), nil
+}
+
+// RunRetention deletes deliveries older than the retention period every
+// interval until ctx is cancelled.
+func (a *Archive) RunRetention(ctx context.Context, interval time.Duration) {
+	if a == nil || a.retention <= 0 {
+		return
+	}
+	if interval <= 0 {
+		interval = time.Hour
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		a.prune(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (a *Archive) prune(ctx context.Context) {
+	cutoff := time.Now().UTC().Add(-a.retention)
+	deleted, err := a.store.DeleteDeliveriesBefore(ctx, cutoff)
+	if err != nil {
+		if ctx.Err() == nil {
+			a.logger.Printf("archive prune failed: %v", err)
+		}
+		return
+	}
+	if deleted > 0 {
+		a.logger.Printf("archive pruned %d deliveries older than %s", deleted, cutoff.Format(time.RFC3339))
+	}
+}
+
+// archiveHeaders flattens request headers for the archive, dropping
+// redactedHeaders and the handler's credentials headers.
+func archiveHeaders(header http.Header, credentials []string) map[string]string {
+	out := make(map[string]string, len(header))
+	for key, values := range header {
+		key = http.CanonicalHeaderKey(key)
+		if redactedHeaders[key] || slices.ContainsFunc(credentials, func(name string) bool {
+			return http.CanonicalHeaderKey(name) == key
+		}) {
+			continue
+		}
+		out[key] = strings.Join(values, ", ")
+	}
+	return out
+}
+
+func matchTopics(matches []internal.RuleMatch) []string {
+	topics := make([]string, 0, len(matches))
+	for _, match := range matches {
+		topics = append(topics, match.Topic)
+	}
+	return topics
+}
+
+func deliveryStatus(matches []internal.RuleMatch, publishErr error) string {
+	switch {
+	case publishErr != nil:
+		return storage.DeliveryStatusFailed
+	case len(matches) == 0:
+		return storage.DeliveryStatusUnmatched
+	default:
+		return storage.DeliveryStatusPublished
+	}
+}
@@ -0,0 +1,249 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"githooks/internal"
+	"githooks/pkg/api"
+	"githooks/pkg/auth"
+	"githooks/pkg/storage"
+	"githooks/pkg/storage/deliveries"
+)
+
+func openTestDeliveryStore(t *testing.T) *deliveries.Store {
+	t.Helper()
+	store, err := deliveries.Open(deliveries.Config{
+		Driver:      "sqlite",
+		DSN:         filepath.Join(t.TempDir(), "deliveries.db"),
+		AutoMigrate: true,
+	})
+	if err != nil {
+		t.Fatalf("open delivery store: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	return store
+}
+
+func newArchiveTestRules(t *testing.T, rules ...internal.Rule) *internal.RuleEngine {
+	t.Helper()
+	engine, err := internal.NewRuleEngine(internal.RulesConfig{Rules: rules, Logger: log.New(io.Discard, "", 0)})
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+	return engine
+}
+
+func archivedEvent(provider, name, raw string) internal.Event {
+	rawObject, data := rawObjectAndFlatten([]byte(raw))
+	return internal.Event{Provider: provider, Name: name, RequestID: "req-" + name, RawPayload: []byte(raw), RawObject: rawObject, Data: data}
+}
+
+// TestArchiveRedactsBearerHeader tests that a generic provider's bearer header is not archived or served.
+func TestArchiveRedactsBearerHeader(t *testing.T) {
+	store := openTestDeliveryStore(t)
+	rules := newArchiveTestRules(t, internal.Rule{When: `status == "synced"`, Emit: internal.EmitList{"argocd.synced"}})
+	publisher := &recordingPublisher{}
+	archive, err := NewArchive(store, rules, publisher, 0, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatalf("archive: %v", err)
+	}
+	handler, err := NewGenericHandler(auth.GenericConfig{
+		Name:      "argocd",
+		Signature: auth.GenericSignatureConfig{Type: "bearer", Header: "X-Api-Key", Secret: "s3cret"},
+	}, rules, publisher, log.New(io.Discard, "", 0), 0, false, WithArchive(archive))
+	if err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/generic/argocd", strings.NewReader(`{"status":"synced"}`))
+	req.Header.Set("X-Api-Key", "Bearer s3cret")
+	req.Header.Set("X-Trace-Id", "trace-1")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+
+	records, err := store.ListDeliveries(context.Background(), storage.DeliveryFilter{})
+	if err != nil || len(records) != 1 {
+		t.Fatalf("expected one archived delivery, got %d err=%v", len(records), err)
+	}
+	if _, ok := records[0].Headers["X-Api-Key"]; ok {
+		t.Fatalf("expected bearer header to be redacted, got %v", records[0].Headers)
+	}
+	if records[0].Headers["X-Trace-Id"] != "trace-1" {
+		t.Fatalf("expected other headers to be archived, got %v", records[0].Headers)
+	}
+
+	deliveriesAPI := &api.DeliveriesHandler{Store: store}
+	rec = httptest.NewRecorder()
+	deliveriesAPI.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/deliveries/"+records[0].ID, nil))
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "s3cret") {
+		t.Fatalf("expected delivery without the secret, got %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+// TestArchiveRedactsBitbucketHookUUID tests that the Bitbucket hook UUID used as the secret is not archived.
+func TestArchiveRedactsBitbucketHookUUID(t *testing.T) {
+	store := openTestDeliveryStore(t)
+	rules := newArchiveTestRules(t, internal.Rule{When: `push.changes != nil`, Emit: internal.EmitList{"bitbucket.push"}})
+	publisher := &recordingPublisher{}
+	archive, err := NewArchive(store, rules, publisher, 0, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatalf("archive: %v", err)
+	}
+	handler, err := NewBitbucketHandler("hook-uuid-secret", rules, publisher, log.New(io.Discard, "", 0), 0, false, nil, WithArchive(archive))
+	if err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/webhooks/bitbucket", strings.NewReader(`{"push":{"changes":[]}}`))
+	req.Header.Set("X-Event-Key", "repo:push")
+	req.Header.Set("X-Hook-UUID", "hook-uuid-secret")
+	req.Header.Set("X-Request-UUID", "delivery-1")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+
+	records, err := store.ListDeliveries(context.Background(), storage.DeliveryFilter{})
+	if err != nil || len(records) != 1 {
+		t.Fatalf("expected one archived delivery, got %d err=%v", len(records), err)
+	}
+	if _, ok := records[0].Headers["X-Hook-Uuid"]; ok {
+		t.Fatalf("expected hook uuid to be redacted, got %v", records[0].Headers)
+	}
+	if records[0].Headers["X-Event-Key"] != "repo:push" {
+		t.Fatalf("expected other headers to be archived, got %v", records[0].Headers)
+	}
+
+	deliveriesAPI := &api.DeliveriesHandler{Store: store}
+	rec = httptest.NewRecorder()
+	deliveriesAPI.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/deliveries/"+records[0].ID, nil))
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), "hook-uuid-secret") {
+		t.Fatalf("expected delivery without the hook uuid, got %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+// TestArchiveRecordAndList tests archived statuses and list filters served by the deliveries API.
+func TestArchiveRecordAndList(t *testing.T) {
+	store := openTestDeliveryStore(t)
+	archive, err := NewArchive(store, nil, nil, 0, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatalf("archive: %v", err)
+	}
+	ctx := context.Background()
+	headers := map[string]string{"X-Github-Event": "pull_request"}
+	archive.Record(ctx, headers, archivedEvent("github", "pull_request", `{"action":"opened"}`), []internal.RuleMatch{{Topic: "pr.opened"}}, nil)
+	archive.Record(ctx, headers, archivedEvent("github", "push", `{"ref":"main"}`), nil, nil)
+	archive.Record(ctx, nil, archivedEvent("gitlab", "Merge Request Hook", `{}`), []internal.RuleMatch{{Topic: "mr"}}, errors.New("broker down"))
+
+	deliveriesAPI := &api.DeliveriesHandler{Store: store}
+	list := func(query string) []map[string]interface{} {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		deliveriesAPI.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/deliveries"+query, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("list %s: expected 200, got %d", query, rec.Code)
+		}
+		var out []map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+			t.Fatalf("decode list: %v", err)
+		}
+		return out
+	}
+
+	if all := list(""); len(all) != 3 {
+		t.Fatalf("expected 3 deliveries, got %d", len(all))
+	}
+	if github := list("?provider=github"); len(github) != 2 {
+		t.Fatalf("expected 2 github deliveries, got %d", len(github))
+	}
+	unmatched := list("?status=unmatched")
+	if len(unmatched) != 1 || unmatched[0]["event_name"] != "push" {
+		t.Fatalf("unexpected unmatched deliveries: %v", unmatched)
+	}
+	failed := list("?provider=gitlab&status=failed")
+	if len(failed) != 1 || failed[0]["error"] != "broker down" {
+		t.Fatalf("unexpected failed deliveries: %v", failed)
+	}
+	if _, ok := failed[0]["payload"]; ok {
+		t.Fatalf("expected list to omit payloads")
+	}
+	if page := list("?limit=1&offset=1"); len(page) != 1 {
+		t.Fatalf("expected one delivery per page, got %d", len(page))
+	}
+
+	rec := httptest.NewRecorder()
+	deliveriesAPI.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/deliveries?since=yesterday", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid since, got %d", rec.Code)
+	}
+}
+
+// TestArchiveReplay tests replaying a delivery to a topic subset and against the current rules.
+func TestArchiveReplay(t *testing.T) {
+	store := openTestDeliveryStore(t)
+	rules := newArchiveTestRules(t,
+		internal.Rule{When: `action == "opened"`, Emit: internal.EmitList{"pr.opened"}},
+		internal.Rule{When: `action == "opened"`, Emit: internal.EmitList{"audit.pr"}},
+	)
+	publisher := &recordingPublisher{}
+	archive, err := NewArchive(store, rules, publisher, 0, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatalf("archive: %v", err)
+	}
+	ctx := context.Background()
+	archive.Record(ctx, map[string]string{"X-Github-Event": "pull_request"}, archivedEvent("github", "pull_request", `{"action":"opened"}`), []internal.RuleMatch{{Topic: "pr.opened"}, {Topic: "audit.pr"}}, nil)
+	records, err := store.ListDeliveries(ctx, storage.DeliveryFilter{})
+	if err != nil || len(records) != 1 {
+		t.Fatalf("expected one archived delivery, got %d err=%v", len(records), err)
+	}
+	originalID := records[0].ID
+
+	replay, err := archive.Replay(ctx, originalID, "", "audit.pr")
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if len(publisher.topics) != 1 || publisher.topics[0] != "audit.pr" {
+		t.Fatalf("expected only audit.pr to be replayed, got %v", publisher.topics)
+	}
+	if replay.ReplayOf != originalID || replay.Status != storage.DeliveryStatusPublished || replay.Headers["X-Github-Event"] != "pull_request" {
+		t.Fatalf("unexpected replay record: %+v", replay)
+	}
+	stored, err := store.GetDelivery(ctx, replay.ID)
+	if err != nil || stored == nil || stored.ReplayOf != originalID {
+		t.Fatalf("expected replay to be archived, got %+v err=%v", stored, err)
+	}
+
+	if err := rules.Update(internal.RulesConfig{
+		Rules:  []internal.Rule{{When: `action == "opened"`, Emit: internal.EmitList{"pr.triage"}}},
+		Logger: log.New(io.Discard, "", 0),
+	}); err != nil {
+		t.Fatalf("update rules: %v", err)
+	}
+	replay, err = archive.Replay(ctx, originalID, "", "")
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if len(replay.MatchedTopics) != 1 || replay.MatchedTopics[0] != "pr.triage" {
+		t.Fatalf("expected replay to use the current rules, got %v", replay.MatchedTopics)
+	}
+	if publisher.topics[len(publisher.topics)-1] != "pr.triage" {
+		t.Fatalf("expected pr.triage to be published, got %v", publisher.topics)
+	}
+
+	if _, err := archive.Replay(ctx, "missing", "", ""); !errors.Is(err, storage.ErrDeliveryNotFound) {
+		t.Fatalf("expected ErrDeliveryNotFound, got %v", err)
+	}
+}
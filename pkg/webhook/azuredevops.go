@@ -0,0 +1,155 @@
+package webhook
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"githooks/internal"
+	"githooks/pkg/storage"
+)
+
+// azureDevOpsSecretHeader carries the shared secret when a service hook is
+// configured with a custom HTTP header instead of basic authentication.
+const azureDevOpsSecretHeader = "X-Githooks-Secret"
+
+// AzureDevOpsHandler handles incoming service hooks from Azure DevOps.
+type AzureDevOpsHandler struct {
+	secret      string
+	rules       *internal.RuleEngine
+	publisher   internal.Publisher
+	logger      *log.Logger
+	maxBody     int64
+	debugEvents bool
+	namespaces  storage.NamespaceStore
+	options     handlerOptions
+}
+
+// NewAzureDevOpsHandler creates a new AzureDevOpsHandler.
+// When secret is set, requests must carry it either as the basic-auth password
+// or in the X-Githooks-Secret header.
+func NewAzureDevOpsHandler(secret string, rules *internal.RuleEngine, publisher internal.Publisher, logger *log.Logger, maxBody int64, debugEvents bool, namespaces storage.NamespaceStore, opts ...HandlerOption) (*AzureDevOpsHandler, error) {
+	if logger == nil {
+		logger = log.Default()
+	}
+	options := applyHandlerOptions(opts)
+	options.credentialHeaders = []string{azureDevOpsSecretHeader}
+	return &AzureDevOpsHandler{secret: secret, rules: rules, publisher: publisher, logger: logger, maxBody: maxBody, debugEvents: debugEvents, namespaces: namespaces, options: options}, nil
+}
+
+// ServeHTTP handles an incoming HTTP request.
+func (h *AzureDevOpsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if h.maxBody > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, h.maxBody)
+	}
+	reqID := requestID(r)
+	w.Header().Set("X-Request-Id", reqID)
+	logger := internal.WithRequestID(h.logger, reqID)
+	if !h.options.allowlist.allow(w, r, logger) {
+		return
+	}
+	rawBody, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if !h.authorized(r) {
+		logger.Printf("azuredevops auth failed")
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var envelope struct {
+		EventType string `json:"eventType"`
+	}
+	if err := json.Unmarshal(rawBody, &envelope); err != nil || envelope.EventType == "" {
+		logger.Printf("azuredevops parse failed: missing eventType")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if h.debugEvents {
+		logDebugEvent(logger, "azuredevops", envelope.EventType, rawBody)
+	}
+
+	rawObject, data := rawObjectAndFlatten(rawBody)
+	stateID := h.resolveStateID(r.Context(), rawBody)
+	if err := h.emit(r, logger, internal.Event{
+		Provider:   "azuredevops",
+		Name:       envelope.EventType,
+		RequestID:  reqID,
+		Data:       data,
+		RawPayload: rawBody,
+		RawObject:  rawObject,
+		StateID:    stateID,
+		DeliveryID: h.deliveryID(r, rawBody),
+		Duplicate:  duplicateDelivery(r),
+	}); err != nil {
+		writeEmitError(w, logger, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *AzureDevOpsHandler) authorized(r *http.Request) bool {
+	if h.secret == "" {
+		return true
+	}
+	if _, password, ok := r.BasicAuth(); ok {
+		return subtle.ConstantTimeCompare([]byte(password), []byte(h.secret)) == 1
+	}
+	if header := strings.TrimSpace(r.Header.Get(azureDevOpsSecretHeader)); header != "" {
+		return subtle.ConstantTimeCompare([]byte(header), []byte(h.secret)) == 1
+	}
+	return false
+}
+
+func (h *AzureDevOpsHandler) resolveStateID(ctx context.Context, raw []byte) string {
+	if h.namespaces == nil {
+		return ""
+	}
+	var payload struct {
+		Resource struct {
+			Repository struct {
+				ID string `json:"id"`
+			} `json:"repository"`
+		} `json:"resource"`
+	}
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return ""
+	}
+	repoID := strings.TrimSpace(payload.Resource.Repository.ID)
+	if repoID == "" {
+		return ""
+	}
+	record, err := h.namespaces.GetNamespace(ctx, "azuredevops", repoID)
+	if err != nil || record == nil {
+		return ""
+	}
+	return record.AccountID
+}
+
+// deliveryID uses the service hook event id, which Azure DevOps keeps across retries.
+func (h *AzureDevOpsHandler) deliveryID(r *http.Request, body []byte) string {
+	var payload struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return ""
+	}
+	return strings.TrimSpace(payload.ID)
+}
+
+func (h *AzureDevOpsHandler) emit(r *http.Request, logger *log.Logger, event internal.Event) error {
+	return emitEvent(r, logger, h.rules, h.publisher, h.options, event)
+}
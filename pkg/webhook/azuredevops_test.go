@@ -0,0 +1,66 @@
+package webhook
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"githooks/internal"
+)
+
+// TestAzureDevOpsHandler tests basic-auth and header secrets and the mapping of service hooks to events.
+func TestAzureDevOpsHandler(t *testing.T) {
+	rules, err := internal.NewRuleEngine(internal.RulesConfig{
+		Rules:  []internal.Rule{{When: `resource.status == "completed"`, Emit: internal.EmitList{"ado.pr.completed"}}},
+		Logger: log.New(io.Discard, "", 0),
+	})
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+	publisher := &recordingPublisher{}
+	handler, err := NewAzureDevOpsHandler("secret", rules, publisher, log.New(io.Discard, "", 0), 0, false, nil)
+	if err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+
+	body := `{"id":"evt-1","eventType":"git.pullrequest.updated","resource":{"status":"completed","repository":{"id":"repo-1"}}}`
+	cases := []struct {
+		name   string
+		method string
+		body   string
+		auth   func(*http.Request)
+		want   int
+	}{
+		{"basic auth", http.MethodPost, body, func(r *http.Request) { r.SetBasicAuth("githooks", "secret") }, http.StatusOK},
+		{"secret header", http.MethodPost, body, func(r *http.Request) { r.Header.Set("X-Githooks-Secret", "secret") }, http.StatusOK},
+		{"wrong password", http.MethodPost, body, func(r *http.Request) { r.SetBasicAuth("githooks", "other") }, http.StatusUnauthorized},
+		{"wrong header", http.MethodPost, body, func(r *http.Request) { r.Header.Set("X-Githooks-Secret", "other") }, http.StatusUnauthorized},
+		{"basic auth before header", http.MethodPost, body, func(r *http.Request) {
+			r.SetBasicAuth("githooks", "other")
+			r.Header.Set("X-Githooks-Secret", "secret")
+		}, http.StatusUnauthorized},
+		{"no credentials", http.MethodPost, body, func(*http.Request) {}, http.StatusUnauthorized},
+		{"missing eventType", http.MethodPost, `{"id":"evt-2"}`, func(r *http.Request) { r.SetBasicAuth("githooks", "secret") }, http.StatusBadRequest},
+		{"wrong method", http.MethodGet, "", func(r *http.Request) { r.SetBasicAuth("githooks", "secret") }, http.StatusMethodNotAllowed},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, "/webhooks/azuredevops", strings.NewReader(tc.body))
+		tc.auth(req)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Fatalf("%s: expected status %d, got %d", tc.name, tc.want, rec.Code)
+		}
+	}
+
+	if len(publisher.events) != 2 {
+		t.Fatalf("expected two publishes, got %v", publisher.topics)
+	}
+	event := publisher.events[0]
+	if publisher.topics[0] != "ado.pr.completed" || event.Provider != "azuredevops" || event.Name != "git.pullrequest.updated" || event.DeliveryID != "evt-1" {
+		t.Fatalf("unexpected event %s: %+v", publisher.topics[0], event)
+	}
+}
@@ -3,12 +3,14 @@ package webhook
 import (
 	"bytes"
 	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"errors"
 	"io"
 	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"githooks/internal"
 	"githooks/pkg/storage"
@@ -19,14 +21,21 @@ import (
 // BitbucketHandler handles incoming webhooks from Bitbucket.
 type BitbucketHandler struct {
 	hook        *bitbucket.Webhook
+	secrets     secretSet
 	rules       *internal.RuleEngine
 	publisher   internal.Publisher
 	logger      *log.Logger
 	maxBody     int64
 	debugEvents bool
 	namespaces  storage.NamespaceStore
+	options     handlerOptions
 }
 
+var (
+	errMissingBitbucketSignature  = errors.New("missing X-Hub-Signature header")
+	errBitbucketSignatureMismatch = errors.New("X-Hub-Signature verification failed")
+)
+
 var bitbucketEvents = []bitbucket.Event{
 	bitbucket.RepoPushEvent,
 	bitbucket.RepoForkEvent,
@@ -49,19 +58,17 @@ var bitbucketEvents = []bitbucket.Event{
 }
 
 // NewBitbucketHandler creates a new BitbucketHandler.
-func NewBitbucketHandler(secret string, rules *internal.RuleEngine, publisher internal.Publisher, logger *log.Logger, maxBody int64, debugEvents bool, namespaces storage.NamespaceStore) (*BitbucketHandler, error) {
-	options := make([]bitbucket.Option, 0, 1)
-	if secret != "" {
-		options = append(options, bitbucket.Options.UUID(secret))
-	}
-	hook, err := bitbucket.New(options...)
+func NewBitbucketHandler(secret string, rules *internal.RuleEngine, publisher internal.Publisher, logger *log.Logger, maxBody int64, debugEvents bool, namespaces storage.NamespaceStore, opts ...HandlerOption) (*BitbucketHandler, error) {
+	hook, err := bitbucket.New()
 	if err != nil {
 		return nil, err
 	}
 	if logger == nil {
 		logger = log.Default()
 	}
-	return &BitbucketHandler{hook: hook, rules: rules, publisher: publisher, logger: logger, maxBody: maxBody, debugEvents: debugEvents, namespaces: namespaces}, nil
+	handlerOpts := applyHandlerOptions(opts)
+	handlerOpts.credentialHeaders = []string{"X-Hook-UUID"}
+	return &BitbucketHandler{hook: hook, secrets: newSecretSet(secret, handlerOpts.secrets), rules: rules, publisher: publisher, logger: logger, maxBody: maxBody, debugEvents: debugEvents, namespaces: namespaces, options: handlerOpts}, nil
 }
 
 // ServeHTTP handles an incoming HTTP request.
@@ -72,6 +79,9 @@ func (h *BitbucketHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	reqID := requestID(r)
 	w.Header().Set("X-Request-Id", reqID)
 	logger := internal.WithRequestID(h.logger, reqID)
+	if !h.options.allowlist.allow(w, r, logger) {
+		return
+	}
 	rawBody, err := io.ReadAll(r.Body)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
@@ -83,31 +93,34 @@ func (h *BitbucketHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		logDebugEvent(logger, "bitbucket", r.Header.Get("X-Event-Key"), rawBody)
 	}
 
+	namespace, err := h.lookupNamespace(r.Context(), rawBody)
+	if err != nil {
+		logger.Printf("bitbucket namespace lookup failed: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if err := h.verify(logger, r, rawBody, namespace); err != nil {
+		logger.Printf("bitbucket parse failed: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
 	payload, err := h.hook.Parse(r, bitbucketEvents...)
 	if err != nil {
-		if errors.Is(err, bitbucket.ErrMissingHookUUIDHeader) {
-			logger.Printf("bitbucket parse warning: %v; skipping UUID verification", err)
-			r.Body = io.NopCloser(bytes.NewReader(rawBody))
-			unverified, fallbackErr := bitbucket.New()
-			if fallbackErr == nil {
-				payload, err = unverified.Parse(r, bitbucketEvents...)
-			} else {
-				err = fallbackErr
-			}
-		}
-		if err != nil {
-			logger.Printf("bitbucket parse failed: %v", err)
-			w.WriteHeader(http.StatusBadRequest)
-			return
-		}
+		logger.Printf("bitbucket parse failed: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
 	}
 
 	eventName := r.Header.Get("X-Event-Key")
 	switch payload.(type) {
 	default:
 		rawObject, data := rawObjectAndFlatten(rawBody)
-		stateID := h.resolveStateID(r.Context(), rawBody)
-		h.emit(r, logger, internal.Event{
+		stateID := ""
+		if namespace != nil {
+			stateID = namespace.AccountID
+		}
+		if err := h.emit(r, logger, internal.Event{
 			Provider:   "bitbucket",
 			Name:       eventName,
 			RequestID:  reqID,
@@ -115,15 +128,21 @@ func (h *BitbucketHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			RawPayload: rawBody,
 			RawObject:  rawObject,
 			StateID:    stateID,
-		})
+			DeliveryID: h.deliveryID(r, rawBody),
+			Duplicate:  duplicateDelivery(r),
+		}); err != nil {
+			writeEmitError(w, logger, err)
+			return
+		}
 	}
 
 	w.WriteHeader(http.StatusOK)
 }
 
-func (h *BitbucketHandler) resolveStateID(ctx context.Context, raw []byte) string {
+// lookupNamespace finds the namespace for the payload's repository, if stored.
+func (h *BitbucketHandler) lookupNamespace(ctx context.Context, raw []byte) (*storage.NamespaceRecord, error) {
 	if h.namespaces == nil {
-		return ""
+		return nil, nil
 	}
 	var payload struct {
 		Repository struct {
@@ -131,25 +150,51 @@ func (h *BitbucketHandler) resolveStateID(ctx context.Context, raw []byte) strin
 		} `json:"repository"`
 	}
 	if err := json.Unmarshal(raw, &payload); err != nil {
-		return ""
+		return nil, nil
 	}
 	repoID := strings.TrimSpace(payload.Repository.UUID)
 	if repoID == "" {
-		return ""
-	}
-	record, err := h.namespaces.GetNamespace(ctx, "bitbucket", repoID)
-	if err != nil || record == nil {
-		return ""
+		return nil, nil
 	}
-	return record.AccountID
+	return h.namespaces.GetNamespace(ctx, "bitbucket", repoID)
 }
 
-func (h *BitbucketHandler) emit(r *http.Request, logger *log.Logger, event internal.Event) {
-	topics := h.rules.EvaluateWithLogger(event, logger)
-	logger.Printf("event provider=%s name=%s topics=%v", event.Provider, event.Name, topics)
-	for _, match := range topics {
-		if err := h.publisher.PublishForDrivers(r.Context(), match.Topic, event, match.Drivers); err != nil {
-			logger.Printf("publish %s failed: %v", match.Topic, err)
+// verify checks the X-Hub-Signature HMAC against the namespace's generated
+// secret when it has one, and otherwise X-Hook-UUID against the provider secrets.
+func (h *BitbucketHandler) verify(logger *log.Logger, r *http.Request, body []byte, namespace *storage.NamespaceRecord) error {
+	if namespace != nil && namespace.WebhookSecret != "" {
+		signature := r.Header.Get("X-Hub-Signature")
+		if signature == "" {
+			return errMissingBitbucketSignature
+		}
+		if !verifyGitHubSHA256(namespace.WebhookSecret, body, signature) {
+			return errBitbucketSignatureMismatch
 		}
+		logger.Printf("bitbucket signature verified secret=namespace")
+		return nil
 	}
+	if !h.secrets.configured() {
+		return nil
+	}
+	hookUUID := r.Header.Get("X-Hook-UUID")
+	if hookUUID == "" {
+		logger.Printf("bitbucket parse warning: %v; skipping UUID verification", bitbucket.ErrMissingHookUUIDHeader)
+		return nil
+	}
+	secretID, ok := h.secrets.match(time.Now(), func(secret string) bool {
+		return subtle.ConstantTimeCompare([]byte(hookUUID), []byte(secret)) == 1
+	})
+	if !ok {
+		return bitbucket.ErrUUIDVerificationFailed
+	}
+	logger.Printf("bitbucket hook uuid verified secret=%s", secretID)
+	return nil
+}
+
+func (h *BitbucketHandler) deliveryID(r *http.Request, body []byte) string {
+	return strings.TrimSpace(r.Header.Get("X-Request-UUID"))
+}
+
+func (h *BitbucketHandler) emit(r *http.Request, logger *log.Logger, event internal.Event) error {
+	return emitEvent(r, logger, h.rules, h.publisher, h.options, event)
 }
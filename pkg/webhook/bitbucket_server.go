@@ -0,0 +1,174 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"githooks/internal"
+	"githooks/pkg/storage"
+
+	bitbucketserver "github.com/go-playground/webhooks/v6/bitbucket-server"
+)
+
+// BitbucketServerHandler handles incoming webhooks from Bitbucket Server / Data Center.
+type BitbucketServerHandler struct {
+	hook        *bitbucketserver.Webhook
+	secret      string
+	rules       *internal.RuleEngine
+	publisher   internal.Publisher
+	logger      *log.Logger
+	maxBody     int64
+	debugEvents bool
+	namespaces  storage.NamespaceStore
+	options     handlerOptions
+}
+
+var bitbucketServerEvents = []bitbucketserver.Event{
+	bitbucketserver.RepositoryReferenceChangedEvent,
+	bitbucketserver.RepositoryModifiedEvent,
+	bitbucketserver.RepositoryForkedEvent,
+	bitbucketserver.RepositoryCommentAddedEvent,
+	bitbucketserver.RepositoryCommentEditedEvent,
+	bitbucketserver.RepositoryCommentDeletedEvent,
+	bitbucketserver.PullRequestOpenedEvent,
+	bitbucketserver.PullRequestFromReferenceUpdatedEvent,
+	bitbucketserver.PullRequestModifiedEvent,
+	bitbucketserver.PullRequestMergedEvent,
+	bitbucketserver.PullRequestDeclinedEvent,
+	bitbucketserver.PullRequestDeletedEvent,
+	bitbucketserver.PullRequestReviewerUpdatedEvent,
+	bitbucketserver.PullRequestReviewerApprovedEvent,
+	bitbucketserver.PullRequestReviewerUnapprovedEvent,
+	bitbucketserver.PullRequestReviewerNeedsWorkEvent,
+	bitbucketserver.PullRequestCommentAddedEvent,
+	bitbucketserver.PullRequestCommentEditedEvent,
+	bitbucketserver.PullRequestCommentDeletedEvent,
+	bitbucketserver.DiagnosticsPingEvent,
+}
+
+// NewBitbucketServerHandler creates a new BitbucketServerHandler.
+func NewBitbucketServerHandler(secret string, rules *internal.RuleEngine, publisher internal.Publisher, logger *log.Logger, maxBody int64, debugEvents bool, namespaces storage.NamespaceStore, opts ...HandlerOption) (*BitbucketServerHandler, error) {
+	options := make([]bitbucketserver.Option, 0, 1)
+	if secret != "" {
+		options = append(options, bitbucketserver.Options.Secret(secret))
+	}
+	hook, err := bitbucketserver.New(options...)
+	if err != nil {
+		return nil, err
+	}
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &BitbucketServerHandler{hook: hook, secret: secret, rules: rules, publisher: publisher, logger: logger, maxBody: maxBody, debugEvents: debugEvents, namespaces: namespaces, options: applyHandlerOptions(opts)}, nil
+}
+
+// ServeHTTP handles an incoming HTTP request.
+func (h *BitbucketServerHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.maxBody > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, h.maxBody)
+	}
+	reqID := requestID(r)
+	w.Header().Set("X-Request-Id", reqID)
+	logger := internal.WithRequestID(h.logger, reqID)
+	if !h.options.allowlist.allow(w, r, logger) {
+		return
+	}
+	rawBody, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(rawBody))
+
+	eventName := r.Header.Get("X-Event-Key")
+	if h.debugEvents {
+		logDebugEvent(logger, "bitbucket_server", eventName, rawBody)
+	}
+
+	// The parser slices the "sha256=" prefix off unconditionally.
+	if h.secret != "" && eventName != string(bitbucketserver.DiagnosticsPingEvent) {
+		if signature := r.Header.Get("X-Hub-Signature"); signature != "" && !strings.HasPrefix(signature, "sha256=") {
+			logger.Printf("bitbucket_server parse failed: unsupported signature format")
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	payload, err := h.hook.Parse(r, bitbucketServerEvents...)
+	if err != nil {
+		logger.Printf("bitbucket_server parse failed: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	switch payload.(type) {
+	case bitbucketserver.DiagnosticsPingPayload:
+		w.WriteHeader(http.StatusOK)
+		return
+	default:
+		rawObject, data := rawObjectAndFlatten(rawBody)
+		stateID := h.resolveStateID(r.Context(), rawBody)
+		if err := h.emit(r, logger, internal.Event{
+			Provider:   "bitbucket_server",
+			Name:       eventName,
+			RequestID:  reqID,
+			Data:       data,
+			RawPayload: rawBody,
+			RawObject:  rawObject,
+			StateID:    stateID,
+			DeliveryID: h.deliveryID(r, rawBody),
+			Duplicate:  duplicateDelivery(r),
+		}); err != nil {
+			writeEmitError(w, logger, err)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *BitbucketServerHandler) resolveStateID(ctx context.Context, raw []byte) string {
+	if h.namespaces == nil {
+		return ""
+	}
+	type repoRef struct {
+		ID int64 `json:"id"`
+	}
+	var payload struct {
+		Repository  repoRef `json:"repository"`
+		PullRequest struct {
+			ToRef struct {
+				Repository repoRef `json:"repository"`
+			} `json:"toRef"`
+		} `json:"pullRequest"`
+	}
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return ""
+	}
+	repoID := payload.Repository.ID
+	if repoID == 0 {
+		repoID = payload.PullRequest.ToRef.Repository.ID
+	}
+	if repoID == 0 {
+		return ""
+	}
+	record, err := h.namespaces.GetNamespace(ctx, "bitbucket_server", strconv.FormatInt(repoID, 10))
+	if err != nil || record == nil {
+		return ""
+	}
+	return record.AccountID
+}
+
+func (h *BitbucketServerHandler) deliveryID(r *http.Request, body []byte) string {
+	return strings.TrimSpace(r.Header.Get("X-Request-Id"))
+}
+
+func (h *BitbucketServerHandler) emit(r *http.Request, logger *log.Logger, event internal.Event) error {
+	return emitEvent(r, logger, h.rules, h.publisher, h.options, event)
+}
@@ -0,0 +1,72 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"githooks/internal"
+)
+
+// TestBitbucketServerHandler tests sha256= signature verification, pings and the mapping of deliveries to events.
+func TestBitbucketServerHandler(t *testing.T) {
+	rules, err := internal.NewRuleEngine(internal.RulesConfig{
+		Rules:  []internal.Rule{{When: `repository.id == 7`, Emit: internal.EmitList{"bbs.push"}}},
+		Logger: log.New(io.Discard, "", 0),
+	})
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+	publisher := &recordingPublisher{}
+	handler, err := NewBitbucketServerHandler("secret", rules, publisher, log.New(io.Discard, "", 0), 0, false, nil)
+	if err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+
+	body := `{"eventKey":"repo:refs_changed","repository":{"id":7,"slug":"app"},"changes":[]}`
+	sign := func(secret string) string {
+		mac := hmac.New(sha256.New, []byte(secret))
+		_, _ = mac.Write([]byte(body))
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+	cases := []struct {
+		name      string
+		event     string
+		signature string
+		want      int
+	}{
+		{"valid", "repo:refs_changed", "sha256=" + sign("secret"), http.StatusOK},
+		{"wrong secret", "repo:refs_changed", "sha256=" + sign("other"), http.StatusBadRequest},
+		{"missing prefix", "repo:refs_changed", sign("secret"), http.StatusBadRequest},
+		{"sha1 prefix", "repo:refs_changed", "sha1=" + sign("secret"), http.StatusBadRequest},
+		{"missing signature", "repo:refs_changed", "", http.StatusBadRequest},
+		{"ping", "diagnostics:ping", "", http.StatusOK},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/bitbucket-server", strings.NewReader(body))
+		req.Header.Set("X-Event-Key", tc.event)
+		req.Header.Set("X-Request-Id", "request-1")
+		if tc.signature != "" {
+			req.Header.Set("X-Hub-Signature", tc.signature)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Fatalf("%s: expected status %d, got %d", tc.name, tc.want, rec.Code)
+		}
+	}
+
+	if len(publisher.events) != 1 || publisher.topics[0] != "bbs.push" {
+		t.Fatalf("expected one bbs.push publish, got %v", publisher.topics)
+	}
+	event := publisher.events[0]
+	if event.Provider != "bitbucket_server" || event.Name != "repo:refs_changed" || event.DeliveryID != "request-1" {
+		t.Fatalf("unexpected event: %+v", event)
+	}
+}
@@ -1,7 +1,9 @@
 package webhook
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 
@@ -47,3 +49,70 @@ func logDebugEvent(logger *log.Logger, provider string, event string, body []byt
 	}
 	logger.Printf("debug event provider=%s name=%s payload=%s", provider, event, string(body))
 }
+
+// publishEvent normalizes event, evaluates the rules for it and publishes every
+// match. Publish failures are logged and joined into the returned error.
+func publishEvent(ctx context.Context, rules *internal.RuleEngine, publisher internal.Publisher, logger *log.Logger, event internal.Event) ([]internal.RuleMatch, error) {
+	event = withNormalized(event)
+	matches := rules.EvaluateWithLogger(event, logger)
+	logger.Printf("event provider=%s name=%s topics=%v", event.Provider, event.Name, matches)
+	return matches, publishMatches(ctx, publisher, logger, event, matches)
+}
+
+func publishMatches(ctx context.Context, publisher internal.Publisher, logger *log.Logger, event internal.Event, matches []internal.RuleMatch) error {
+	var publishErr error
+	for _, match := range matches {
+		published := event
+		if len(match.Payload) > 0 {
+			// Publish the rule's projection; the archive keeps the raw body.
+			published.RawPayload = match.Payload
+		}
+		if err := publisher.PublishForDrivers(ctx, match.Topic, published, match.Drivers); err != nil {
+			logger.Printf("publish %s failed: %v", match.Topic, err)
+			publishErr = errors.Join(publishErr, err)
+		}
+	}
+	return publishErr
+}
+
+// emitEvent applies the rate limiter, then enqueues event to the inbox or
+// publishes it inline and records it in the archive. A failed inline publish
+// is returned so the provider gets a 5xx and redelivers.
+func emitEvent(r *http.Request, logger *log.Logger, rules *internal.RuleEngine, publisher internal.Publisher, options handlerOptions, event internal.Event) error {
+	ctx := r.Context()
+	headers := archiveHeaders(r.Header, options.credentialHeaders)
+	if limiter := options.limiter; limiter != nil && !limiter.Allow(event) {
+		switch limiter.cfg.Mode {
+		case RateLimitModeShed:
+			logger.Printf("rate limit shed provider=%s name=%s", event.Provider, event.Name)
+			return nil
+		case RateLimitModeSpill:
+			logger.Printf("rate limit spill provider=%s name=%s topic=%s", event.Provider, event.Name, limiter.cfg.SpillTopic)
+			matches := []internal.RuleMatch{{Topic: limiter.cfg.SpillTopic}}
+			err := publishMatches(ctx, publisher, logger, withNormalized(event), matches)
+			options.archive.Record(ctx, headers, event, matches, err)
+			return err
+		default:
+			logger.Printf("rate limit reject provider=%s name=%s", event.Provider, event.Name)
+			return &rateLimitedError{retryAfter: limiter.retryAfter()}
+		}
+	}
+	if options.inbox != nil {
+		return options.inbox.Enqueue(ctx, event, headers)
+	}
+	matches, err := publishEvent(ctx, rules, publisher, logger, event)
+	options.archive.Record(ctx, headers, event, matches, err)
+	return err
+}
+
+// writeEmitError maps an emitEvent error to a response.
+func writeEmitError(w http.ResponseWriter, logger *log.Logger, err error) {
+	var limited *rateLimitedError
+	if errors.As(err, &limited) {
+		w.Header().Set("Retry-After", limited.retryAfterSeconds())
+		w.WriteHeader(http.StatusTooManyRequests)
+		return
+	}
+	logger.Printf("emit failed: %v", err)
+	w.WriteHeader(http.StatusInternalServerError)
+}
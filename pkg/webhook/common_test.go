@@ -0,0 +1,37 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"log"
+	"testing"
+
+	"githooks/internal"
+)
+
+// TestPublishEventTransform tests that transformed rules publish their projection and leave the event raw.
+func TestPublishEventTransform(t *testing.T) {
+	rules, err := internal.NewRuleEngine(internal.RulesConfig{
+		Rules: []internal.Rule{
+			{When: `action == "opened"`, Emit: internal.EmitList{"pr.slim"}, Transform: internal.Transform{"number": "$.number"}},
+			{When: `action == "opened"`, Emit: internal.EmitList{"pr.full"}},
+		},
+		Logger: log.New(io.Discard, "", 0),
+	})
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+	raw := `{"action":"opened","number":7,"body":"large"}`
+	rawObject, data := rawObjectAndFlatten([]byte(raw))
+	event := internal.Event{Provider: "github", Name: "pull_request", RawPayload: []byte(raw), RawObject: rawObject, Data: data}
+	publisher := &recordingPublisher{}
+	if _, err := publishEvent(context.Background(), rules, publisher, log.New(io.Discard, "", 0), event); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	if len(publisher.payloads) != 2 || publisher.payloads[0] != `{"number":7}` || publisher.payloads[1] != raw {
+		t.Fatalf("unexpected payloads: %q", publisher.payloads)
+	}
+	if string(event.RawPayload) != raw {
+		t.Fatalf("expected event to keep the raw payload")
+	}
+}
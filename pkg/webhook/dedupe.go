@@ -0,0 +1,151 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"githooks/internal"
+	"githooks/pkg/storage"
+)
+
+const (
+	// DedupeModeDrop acknowledges duplicate deliveries without evaluating rules.
+	DedupeModeDrop = "drop"
+	// DedupeModeFlag processes duplicates but marks them in message metadata.
+	DedupeModeFlag = "flag"
+)
+
+// deliveryIdentifier is implemented by provider handlers that can extract the
+// provider-assigned delivery id from a request.
+type deliveryIdentifier interface {
+	deliveryID(r *http.Request, body []byte) string
+}
+
+type duplicateKey struct{}
+
+// inFlightRetryAfter is the Retry-After, in seconds, sent with the 409 for a
+// redelivery whose first attempt is still running.
+const inFlightRetryAfter = "5"
+
+// DedupeHandler wraps a provider handler and detects redelivered webhooks.
+type DedupeHandler struct {
+	next     http.Handler
+	ids      deliveryIdentifier
+	provider string
+	store    storage.DedupeStore
+	mode     string
+	maxBody  int64
+	logger   *log.Logger
+}
+
+// NewDedupeHandler wraps next with delivery-id deduplication. The delivery is
+// only kept as seen when next answers with a 2xx status, so rejected requests
+// cannot poison the store and failed deliveries are retried normally. A
+// redelivery that arrives while the first attempt is still running is answered
+// with 409 so the provider retries it instead of it being lost if that attempt
+// fails.
+func NewDedupeHandler(next http.Handler, provider string, store storage.DedupeStore, mode string, maxBody int64, logger *log.Logger) (*DedupeHandler, error) {
+	ids, ok := next.(deliveryIdentifier)
+	if !ok {
+		return nil, fmt.Errorf("dedupe: %s handler does not expose delivery ids", provider)
+	}
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	switch mode {
+	case "":
+		mode = DedupeModeDrop
+	case DedupeModeDrop, DedupeModeFlag:
+	default:
+		return nil, fmt.Errorf("dedupe: unsupported mode %q", mode)
+	}
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &DedupeHandler{next: next, ids: ids, provider: provider, store: store, mode: mode, maxBody: maxBody, logger: logger}, nil
+}
+
+// ServeHTTP handles an incoming HTTP request.
+func (h *DedupeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil || r.Method != http.MethodPost {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+	if h.maxBody > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, h.maxBody)
+	}
+	rawBody, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(rawBody))
+
+	deliveryID := h.ids.deliveryID(r, rawBody)
+	if deliveryID == "" {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+
+	logger := internal.WithRequestID(h.logger, requestID(r))
+	first, err := h.store.Reserve(r.Context(), h.provider, deliveryID)
+	if err != nil {
+		// Fail open: a broken dedupe backend must not drop webhooks.
+		logger.Printf("dedupe reserve failed provider=%s delivery_id=%s: %v", h.provider, deliveryID, err)
+		h.next.ServeHTTP(w, r)
+		return
+	}
+	if !first {
+		inFlight, err := h.store.InFlight(r.Context(), h.provider, deliveryID)
+		if err != nil {
+			logger.Printf("dedupe in-flight check failed provider=%s delivery_id=%s: %v", h.provider, deliveryID, err)
+		}
+		if inFlight {
+			logger.Printf("duplicate delivery in flight provider=%s delivery_id=%s", h.provider, deliveryID)
+			w.Header().Set("Retry-After", inFlightRetryAfter)
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		logger.Printf("duplicate delivery provider=%s delivery_id=%s mode=%s", h.provider, deliveryID, h.mode)
+		w.Header().Set("X-Githooks-Duplicate", "true")
+		if h.mode == DedupeModeDrop {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		r = r.WithContext(context.WithValue(r.Context(), duplicateKey{}, true))
+	}
+
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	h.next.ServeHTTP(rec, r)
+	if !first {
+		return
+	}
+	if rec.status < 200 || rec.status >= 300 {
+		if err := h.store.Release(r.Context(), h.provider, deliveryID); err != nil {
+			logger.Printf("dedupe release failed provider=%s delivery_id=%s: %v", h.provider, deliveryID, err)
+		}
+		return
+	}
+	if err := h.store.Complete(r.Context(), h.provider, deliveryID); err != nil {
+		logger.Printf("dedupe complete failed provider=%s delivery_id=%s: %v", h.provider, deliveryID, err)
+	}
+}
+
+// duplicateDelivery reports whether the dedupe layer flagged the request as a redelivery.
+func duplicateDelivery(r *http.Request) bool {
+	duplicate, _ := r.Context().Value(duplicateKey{}).(bool)
+	return duplicate
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
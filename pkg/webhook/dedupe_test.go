@@ -0,0 +1,114 @@
+package webhook
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"githooks/internal"
+	"githooks/pkg/storage/dedupe"
+)
+
+// blockingHandler answers with status once release is closed.
+type blockingHandler struct {
+	started chan struct{}
+	release chan struct{}
+	status  int
+	calls   int
+}
+
+func (h *blockingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.calls++
+	if h.started != nil {
+		close(h.started)
+		h.started = nil
+		<-h.release
+	}
+	w.WriteHeader(h.status)
+}
+
+func (h *blockingHandler) deliveryID(r *http.Request, body []byte) string {
+	return r.Header.Get("X-Delivery")
+}
+
+// TestDedupeHandlerInFlight tests that redeliveries during the first attempt get 409 and are processed after it fails.
+func TestDedupeHandlerInFlight(t *testing.T) {
+	next := &blockingHandler{started: make(chan struct{}), release: make(chan struct{}), status: http.StatusInternalServerError}
+	handler, err := NewDedupeHandler(next, "github", dedupe.NewMemory(10, time.Hour), DedupeModeDrop, 0, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatalf("dedupe handler: %v", err)
+	}
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/github", strings.NewReader(`{}`))
+		req.Header.Set("X-Delivery", "d-1")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	started := next.started
+	firstDone := make(chan *httptest.ResponseRecorder)
+	go func() { firstDone <- send() }()
+	<-started
+
+	if rec := send(); rec.Code != http.StatusConflict || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected 409 with Retry-After for in-flight redelivery, got %d", rec.Code)
+	}
+	close(next.release)
+	if rec := <-firstDone; rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected first attempt to fail, got %d", rec.Code)
+	}
+
+	next.status = http.StatusOK
+	if rec := send(); rec.Code != http.StatusOK || next.calls != 2 {
+		t.Fatalf("expected redelivery after failure to be processed, got %d calls=%d", rec.Code, next.calls)
+	}
+	if rec := send(); rec.Code != http.StatusOK || rec.Header().Get("X-Githooks-Duplicate") != "true" || next.calls != 2 {
+		t.Fatalf("expected completed delivery to be dropped as duplicate, got %d calls=%d", rec.Code, next.calls)
+	}
+}
+
+// TestDedupeHandlerPublishFailure tests that a delivery whose publish failed is answered with 500 and published on redelivery.
+func TestDedupeHandlerPublishFailure(t *testing.T) {
+	rules, err := internal.NewRuleEngine(internal.RulesConfig{
+		Rules:  []internal.Rule{{When: `repository.name == "demo"`, Emit: internal.EmitList{"bitbucket.push"}}},
+		Logger: log.New(io.Discard, "", 0),
+	})
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+	publisher := &failingPublisher{failures: map[string]bool{"bitbucket.push": true}}
+	next, err := NewBitbucketHandler("", rules, publisher, log.New(io.Discard, "", 0), 0, false, nil)
+	if err != nil {
+		t.Fatalf("bitbucket handler: %v", err)
+	}
+	handler, err := NewDedupeHandler(next, "bitbucket", dedupe.NewMemory(10, time.Hour), DedupeModeDrop, 0, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatalf("dedupe handler: %v", err)
+	}
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/bitbucket", strings.NewReader(`{"repository":{"name":"demo"}}`))
+		req.Header.Set("X-Event-Key", "repo:push")
+		req.Header.Set("X-Request-UUID", "d-1")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := send(); rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected publish failure to return 500, got %d", rec.Code)
+	}
+	publisher.mu.Lock()
+	delete(publisher.failures, "bitbucket.push")
+	publisher.mu.Unlock()
+	if rec := send(); rec.Code != http.StatusOK || rec.Header().Get("X-Githooks-Duplicate") == "true" {
+		t.Fatalf("expected redelivery to be processed, got %d", rec.Code)
+	}
+	if len(publisher.topics) != 1 || publisher.topics[0] != "bitbucket.push" {
+		t.Fatalf("expected redelivery to be published, got %v", publisher.topics)
+	}
+}
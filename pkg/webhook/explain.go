@@ -0,0 +1,39 @@
+package webhook
+
+import (
+	"githooks/internal"
+)
+
+// Explainer evaluates rules against a payload the way the webhook handlers do,
+// without publishing anything.
+type Explainer struct {
+	rules *internal.RuleEngine
+}
+
+// NewExplainer creates an Explainer for rules.
+func NewExplainer(rules *internal.RuleEngine) *Explainer {
+	return &Explainer{rules: rules}
+}
+
+// Explain explains the rules against the event for a provider payload.
+func (e *Explainer) Explain(provider, eventName string, payload []byte) internal.Explanation {
+	return e.rules.Explain(EventFromPayload(provider, eventName, payload))
+}
+
+// EventFromPayload builds the event the webhook handlers would evaluate for a
+// provider payload, including its flattened data and normalized model.
+func EventFromPayload(provider, eventName string, payload []byte) internal.Event {
+	rawObject, data := rawObjectAndFlatten(payload)
+	return withNormalized(internal.Event{
+		Provider:   provider,
+		Name:       eventName,
+		Data:       data,
+		RawPayload: payload,
+		RawObject:  rawObject,
+	})
+}
+
+// RulesVersion returns the version of the active rule set.
+func (e *Explainer) RulesVersion() int64 {
+	return e.rules.Version()
+}
@@ -0,0 +1,26 @@
+package webhook
+
+import (
+	"io"
+	"log"
+	"testing"
+
+	"githooks/internal"
+)
+
+// TestExplainerNormalized tests that explained events carry the normalized model like published ones.
+func TestExplainerNormalized(t *testing.T) {
+	engine, err := internal.NewRuleEngine(internal.RulesConfig{
+		Rules:  []internal.Rule{{When: "$.normalized.pull_request.state == \"open\"", Emit: internal.EmitList{"pr.open"}}},
+		Logger: log.New(io.Discard, "", 0),
+	})
+	if err != nil {
+		t.Fatalf("new rule engine: %v", err)
+	}
+	payload := []byte(`{"action":"opened","pull_request":{"number":7,"state":"open"},"repository":{"full_name":"acme/app"}}`)
+
+	rules := NewExplainer(engine).Explain("github", "pull_request", payload).Rules
+	if len(rules) != 1 || !rules[0].Matched || rules[0].Params["$.normalized.pull_request.state"] != "open" {
+		t.Fatalf("expected normalized rule to match, got %+v", rules)
+	}
+}
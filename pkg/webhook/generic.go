@@ -0,0 +1,210 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"githooks/internal"
+	"githooks/pkg/auth"
+
+	"github.com/PaesslerAG/jsonpath"
+)
+
+const (
+	genericSignatureHMAC   = "hmac-sha256"
+	genericSignatureBearer = "bearer"
+	genericSignatureNone   = "none"
+)
+
+// GenericHandler handles signed JSON webhooks from arbitrary sources such as CI
+// systems, Jira, or ArgoCD notifications.
+type GenericHandler struct {
+	cfg         auth.GenericConfig
+	rules       *internal.RuleEngine
+	publisher   internal.Publisher
+	logger      *log.Logger
+	maxBody     int64
+	debugEvents bool
+	options     handlerOptions
+}
+
+// NewGenericHandler creates a new GenericHandler for a single configured source.
+func NewGenericHandler(cfg auth.GenericConfig, rules *internal.RuleEngine, publisher internal.Publisher, logger *log.Logger, maxBody int64, debugEvents bool, opts ...HandlerOption) (*GenericHandler, error) {
+	cfg.Name = strings.TrimSpace(cfg.Name)
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("generic provider name is required")
+	}
+	sig := &cfg.Signature
+	sig.Type = strings.ToLower(strings.TrimSpace(sig.Type))
+	switch sig.Type {
+	case "", genericSignatureNone:
+		sig.Type = genericSignatureNone
+	case genericSignatureHMAC:
+		if sig.Secret == "" || sig.Header == "" {
+			return nil, fmt.Errorf("generic provider %s: hmac-sha256 requires secret and header", cfg.Name)
+		}
+		switch strings.ToLower(sig.Encoding) {
+		case "", "hex", "base64":
+		default:
+			return nil, fmt.Errorf("generic provider %s: unsupported signature encoding %q", cfg.Name, sig.Encoding)
+		}
+	case genericSignatureBearer:
+		if sig.Secret == "" {
+			return nil, fmt.Errorf("generic provider %s: bearer requires secret", cfg.Name)
+		}
+		if sig.Header == "" {
+			sig.Header = "Authorization"
+		}
+	default:
+		return nil, fmt.Errorf("generic provider %s: unsupported signature type %q", cfg.Name, sig.Type)
+	}
+	if logger == nil {
+		logger = log.Default()
+	}
+	options := applyHandlerOptions(opts)
+	if sig.Type == genericSignatureBearer {
+		// The bearer header holds the secret itself, not a signature.
+		options.credentialHeaders = []string{sig.Header}
+	}
+	return &GenericHandler{cfg: cfg, rules: rules, publisher: publisher, logger: logger, maxBody: maxBody, debugEvents: debugEvents, options: options}, nil
+}
+
+// ServeHTTP handles an incoming HTTP request.
+func (h *GenericHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if h.maxBody > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, h.maxBody)
+	}
+	reqID := requestID(r)
+	w.Header().Set("X-Request-Id", reqID)
+	logger := internal.WithRequestID(h.logger, reqID)
+	if !h.options.allowlist.allow(w, r, logger) {
+		return
+	}
+	rawBody, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if !h.verify(r, rawBody) {
+		logger.Printf("%s signature verification failed", h.cfg.Name)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	eventName := h.cfg.DefaultEvent
+	if h.cfg.EventHeader != "" {
+		if value := strings.TrimSpace(r.Header.Get(h.cfg.EventHeader)); value != "" {
+			eventName = value
+		}
+	}
+	if h.debugEvents {
+		logDebugEvent(logger, h.cfg.Name, eventName, rawBody)
+	}
+
+	rawObject, data := rawObjectAndFlatten(rawBody)
+	if rawObject == nil {
+		logger.Printf("%s parse failed: invalid json", h.cfg.Name)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if err := h.emit(r, logger, internal.Event{
+		Provider:   h.cfg.Name,
+		Name:       eventName,
+		RequestID:  reqID,
+		Data:       data,
+		RawPayload: rawBody,
+		RawObject:  rawObject,
+		StateID:    h.resolveStateID(rawObject),
+		DeliveryID: h.deliveryID(r, rawBody),
+		Duplicate:  duplicateDelivery(r),
+	}); err != nil {
+		writeEmitError(w, logger, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *GenericHandler) verify(r *http.Request, body []byte) bool {
+	sig := h.cfg.Signature
+	switch sig.Type {
+	case genericSignatureHMAC:
+		value := strings.TrimSpace(r.Header.Get(sig.Header))
+		if value == "" {
+			return false
+		}
+		if sig.Prefix != "" {
+			if !strings.HasPrefix(value, sig.Prefix) {
+				return false
+			}
+			value = strings.TrimPrefix(value, sig.Prefix)
+		}
+		mac := hmac.New(sha256.New, []byte(sig.Secret))
+		_, _ = mac.Write(body)
+		sum := mac.Sum(nil)
+		var expected string
+		if strings.EqualFold(sig.Encoding, "base64") {
+			expected = base64.StdEncoding.EncodeToString(sum)
+		} else {
+			expected = hex.EncodeToString(sum)
+			value = strings.ToLower(value)
+		}
+		return hmac.Equal([]byte(value), []byte(expected))
+	case genericSignatureBearer:
+		value := strings.TrimSpace(r.Header.Get(sig.Header))
+		if len(value) > 7 && strings.EqualFold(value[:7], "bearer ") {
+			value = strings.TrimSpace(value[7:])
+		}
+		if value == "" {
+			return false
+		}
+		return subtle.ConstantTimeCompare([]byte(value), []byte(sig.Secret)) == 1
+	default:
+		return true
+	}
+}
+
+func (h *GenericHandler) resolveStateID(rawObject interface{}) string {
+	if h.cfg.StateIDPath == "" {
+		return ""
+	}
+	value, err := jsonpath.Get(h.cfg.StateIDPath, rawObject)
+	if err != nil {
+		return ""
+	}
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return ""
+	}
+}
+
+func (h *GenericHandler) deliveryID(r *http.Request, body []byte) string {
+	if h.cfg.DeliveryHeader == "" {
+		return ""
+	}
+	return strings.TrimSpace(r.Header.Get(h.cfg.DeliveryHeader))
+}
+
+func (h *GenericHandler) emit(r *http.Request, logger *log.Logger, event internal.Event) error {
+	return emitEvent(r, logger, h.rules, h.publisher, h.options, event)
+}
@@ -0,0 +1,149 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"githooks/internal"
+	"githooks/pkg/storage"
+
+	"github.com/go-playground/webhooks/v6/gitea"
+)
+
+// GiteaHandler handles incoming webhooks from Gitea and Forgejo.
+type GiteaHandler struct {
+	hook        *gitea.Webhook
+	rules       *internal.RuleEngine
+	publisher   internal.Publisher
+	logger      *log.Logger
+	maxBody     int64
+	debugEvents bool
+	namespaces  storage.NamespaceStore
+	options     handlerOptions
+}
+
+var giteaEvents = []gitea.Event{
+	gitea.CreateEvent,
+	gitea.DeleteEvent,
+	gitea.ForkEvent,
+	gitea.IssuesEvent,
+	gitea.IssueAssignEvent,
+	gitea.IssueLabelEvent,
+	gitea.IssueMilestoneEvent,
+	gitea.IssueCommentEvent,
+	gitea.PushEvent,
+	gitea.PullRequestEvent,
+	gitea.PullRequestAssignEvent,
+	gitea.PullRequestLabelEvent,
+	gitea.PullRequestMilestoneEvent,
+	gitea.PullRequestCommentEvent,
+	gitea.PullRequestReviewEvent,
+	gitea.PullRequestSyncEvent,
+	gitea.RepositoryEvent,
+	gitea.ReleaseEvent,
+}
+
+// NewGiteaHandler creates a new GiteaHandler.
+func NewGiteaHandler(secret string, rules *internal.RuleEngine, publisher internal.Publisher, logger *log.Logger, maxBody int64, debugEvents bool, namespaces storage.NamespaceStore, opts ...HandlerOption) (*GiteaHandler, error) {
+	options := make([]gitea.Option, 0, 1)
+	if secret != "" {
+		options = append(options, gitea.Options.Secret(secret))
+	}
+	hook, err := gitea.New(options...)
+	if err != nil {
+		return nil, err
+	}
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &GiteaHandler{hook: hook, rules: rules, publisher: publisher, logger: logger, maxBody: maxBody, debugEvents: debugEvents, namespaces: namespaces, options: applyHandlerOptions(opts)}, nil
+}
+
+// ServeHTTP handles an incoming HTTP request.
+func (h *GiteaHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.maxBody > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, h.maxBody)
+	}
+	reqID := requestID(r)
+	w.Header().Set("X-Request-Id", reqID)
+	logger := internal.WithRequestID(h.logger, reqID)
+	if !h.options.allowlist.allow(w, r, logger) {
+		return
+	}
+	rawBody, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(rawBody))
+
+	if h.debugEvents {
+		logDebugEvent(logger, "gitea", r.Header.Get("X-Gitea-Event"), rawBody)
+	}
+
+	payload, err := h.hook.Parse(r, giteaEvents...)
+	if err != nil {
+		logger.Printf("gitea parse failed: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	eventName := r.Header.Get("X-Gitea-Event")
+	switch payload.(type) {
+	default:
+		rawObject, data := rawObjectAndFlatten(rawBody)
+		stateID := h.resolveStateID(r.Context(), rawBody)
+		if err := h.emit(r, logger, internal.Event{
+			Provider:   "gitea",
+			Name:       eventName,
+			RequestID:  reqID,
+			Data:       data,
+			RawPayload: rawBody,
+			RawObject:  rawObject,
+			StateID:    stateID,
+			DeliveryID: h.deliveryID(r, rawBody),
+			Duplicate:  duplicateDelivery(r),
+		}); err != nil {
+			writeEmitError(w, logger, err)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *GiteaHandler) resolveStateID(ctx context.Context, raw []byte) string {
+	if h.namespaces == nil {
+		return ""
+	}
+	var payload struct {
+		Repository struct {
+			ID int64 `json:"id"`
+		} `json:"repository"`
+	}
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return ""
+	}
+	if payload.Repository.ID == 0 {
+		return ""
+	}
+	record, err := h.namespaces.GetNamespace(ctx, "gitea", strconv.FormatInt(payload.Repository.ID, 10))
+	if err != nil || record == nil {
+		return ""
+	}
+	return record.AccountID
+}
+
+func (h *GiteaHandler) deliveryID(r *http.Request, body []byte) string {
+	return strings.TrimSpace(r.Header.Get("X-Gitea-Delivery"))
+}
+
+func (h *GiteaHandler) emit(r *http.Request, logger *log.Logger, event internal.Event) error {
+	return emitEvent(r, logger, h.rules, h.publisher, h.options, event)
+}
@@ -0,0 +1,94 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"githooks/internal"
+)
+
+type recordingPublisher struct {
+	mu       sync.Mutex
+	topics   []string
+	payloads []string
+	events   []internal.Event
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, topic string, event internal.Event) error {
+	return p.PublishForDrivers(ctx, topic, event, nil)
+}
+
+func (p *recordingPublisher) PublishForDrivers(ctx context.Context, topic string, event internal.Event, drivers []string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.topics = append(p.topics, topic)
+	p.payloads = append(p.payloads, string(event.RawPayload))
+	p.events = append(p.events, event)
+	return nil
+}
+
+func (p *recordingPublisher) Close() error { return nil }
+
+// TestGiteaHandler tests signature verification and the mapping of Gitea deliveries to events.
+func TestGiteaHandler(t *testing.T) {
+	rules, err := internal.NewRuleEngine(internal.RulesConfig{
+		Rules:  []internal.Rule{{When: `ref == "refs/heads/main"`, Emit: internal.EmitList{"gitea.push"}}},
+		Logger: log.New(io.Discard, "", 0),
+	})
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+	publisher := &recordingPublisher{}
+	handler, err := NewGiteaHandler("secret", rules, publisher, log.New(io.Discard, "", 0), 0, false, nil)
+	if err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+
+	body := `{"ref":"refs/heads/main","repository":{"id":7,"full_name":"acme/app"}}`
+	sign := func(secret string) string {
+		mac := hmac.New(sha256.New, []byte(secret))
+		_, _ = mac.Write([]byte(body))
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+	cases := []struct {
+		name      string
+		event     string
+		signature string
+		want      int
+	}{
+		{"valid", "push", sign("secret"), http.StatusOK},
+		{"wrong secret", "push", sign("other"), http.StatusBadRequest},
+		{"missing signature", "push", "", http.StatusBadRequest},
+		{"unknown event", "wiki", sign("secret"), http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/gitea", strings.NewReader(body))
+		req.Header.Set("X-Gitea-Event", tc.event)
+		req.Header.Set("X-Gitea-Delivery", "delivery-1")
+		if tc.signature != "" {
+			req.Header.Set("X-Gitea-Signature", tc.signature)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Fatalf("%s: expected status %d, got %d", tc.name, tc.want, rec.Code)
+		}
+	}
+
+	if len(publisher.events) != 1 || publisher.topics[0] != "gitea.push" {
+		t.Fatalf("expected one gitea.push publish, got %v", publisher.topics)
+	}
+	event := publisher.events[0]
+	if event.Provider != "gitea" || event.Name != "push" || event.DeliveryID != "delivery-1" || string(event.RawPayload) != body {
+		t.Fatalf("unexpected event: %+v", event)
+	}
+}
@@ -5,15 +5,16 @@ import (
 	"context"
 	"crypto/hmac"
 	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"githooks/internal"
 	"githooks/pkg/storage"
@@ -25,8 +26,7 @@ import (
 // GitHubHandler handles incoming webhooks from GitHub.
 type GitHubHandler struct {
 	hook         *github.Webhook
-	fallbackHook *github.Webhook
-	secret       string
+	secrets      secretSet
 	rules        *internal.RuleEngine
 	publisher    internal.Publisher
 	logger       *log.Logger
@@ -34,6 +34,7 @@ type GitHubHandler struct {
 	debugEvents  bool
 	store        storage.Store
 	namespaces   storage.NamespaceStore
+	options     handlerOptions
 }
 
 var githubEvents = []github.Event{
@@ -86,12 +87,8 @@ var githubEvents = []github.Event{
 }
 
 // NewGitHubHandler creates a new GitHubHandler.
-func NewGitHubHandler(secret string, rules *internal.RuleEngine, publisher internal.Publisher, logger *log.Logger, maxBody int64, debugEvents bool, store storage.Store, namespaces storage.NamespaceStore) (*GitHubHandler, error) {
-	hook, err := github.New(github.Options.Secret(secret))
-	if err != nil {
-		return nil, err
-	}
-	fallbackHook, err := github.New()
+func NewGitHubHandler(secret string, rules *internal.RuleEngine, publisher internal.Publisher, logger *log.Logger, maxBody int64, debugEvents bool, store storage.Store, namespaces storage.NamespaceStore, opts ...HandlerOption) (*GitHubHandler, error) {
+	hook, err := github.New()
 	if err != nil {
 		return nil, err
 	}
@@ -99,10 +96,10 @@ func NewGitHubHandler(secret string, rules *internal.RuleEngine, publisher inter
 	if logger == nil {
 		logger = log.Default()
 	}
+	handlerOpts := applyHandlerOptions(opts)
 	return &GitHubHandler{
 		hook:         hook,
-		fallbackHook: fallbackHook,
-		secret:       secret,
+		secrets:      newSecretSet(secret, handlerOpts.secrets),
 		rules:        rules,
 		publisher:    publisher,
 		logger:       logger,
@@ -110,6 +107,7 @@ func NewGitHubHandler(secret string, rules *internal.RuleEngine, publisher inter
 		debugEvents:  debugEvents,
 		store:        store,
 		namespaces:   namespaces,
+		options:     handlerOpts,
 	}, nil
 }
 
@@ -121,6 +119,9 @@ func (h *GitHubHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	reqID := requestID(r)
 	w.Header().Set("X-Request-Id", reqID)
 	logger := internal.WithRequestID(h.logger, reqID)
+	if !h.options.allowlist.allow(w, r, logger) {
+		return
+	}
 	rawBody, err := io.ReadAll(r.Body)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
@@ -132,21 +133,21 @@ func (h *GitHubHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		logDebugEvent(logger, "github", r.Header.Get("X-GitHub-Event"), rawBody)
 	}
 
-	payload, err := h.hook.Parse(r, githubEvents...)
-	if err != nil {
-		if errors.Is(err, github.ErrMissingHubSignatureHeader) && h.secret != "" {
-			sha1Header := r.Header.Get("X-Hub-Signature")
-			if sha1Header != "" && verifyGitHubSHA1(h.secret, rawBody, sha1Header) {
-				logger.Printf("github parse warning: %v; accepted sha1 signature", err)
-				r.Body = io.NopCloser(bytes.NewReader(rawBody))
-				payload, err = h.fallbackHook.Parse(r, githubEvents...)
-			}
-		}
+	if h.secrets.configured() {
+		secretID, err := h.verifySignature(logger, r, rawBody)
 		if err != nil {
 			logger.Printf("github parse failed: %v", err)
 			w.WriteHeader(http.StatusBadRequest)
 			return
 		}
+		logger.Printf("github signature verified secret=%s", secretID)
+	}
+
+	payload, err := h.hook.Parse(r, githubEvents...)
+	if err != nil {
+		logger.Printf("github parse failed: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
 	}
 
 	eventName := r.Header.Get("X-GitHub-Event")
@@ -160,7 +161,7 @@ func (h *GitHubHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			logger.Printf("github install sync failed: %v", err)
 		}
 		stateID := h.resolveStateID(r.Context(), rawBody)
-		h.emit(r, logger, internal.Event{
+		if err := h.emit(r, logger, internal.Event{
 			Provider:   "github",
 			Name:       eventName,
 			RequestID:  reqID,
@@ -168,7 +169,12 @@ func (h *GitHubHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			RawPayload: rawBody,
 			RawObject:  rawObject,
 			StateID:    stateID,
-		})
+			DeliveryID: h.deliveryID(r, rawBody),
+			Duplicate:  duplicateDelivery(r),
+		}); err != nil {
+			writeEmitError(w, logger, err)
+			return
+		}
 	}
 
 	w.WriteHeader(http.StatusOK)
@@ -336,14 +342,47 @@ func recordAccountName(record *storage.InstallRecord, providerName string) strin
 	return providerName
 }
 
-func (h *GitHubHandler) emit(r *http.Request, logger *log.Logger, event internal.Event) {
-	topics := h.rules.EvaluateWithLogger(event, logger)
-	logger.Printf("event provider=%s name=%s topics=%v", event.Provider, event.Name, topics)
-	for _, match := range topics {
-		if err := h.publisher.PublishForDrivers(r.Context(), match.Topic, event, match.Drivers); err != nil {
-			logger.Printf("publish %s failed: %v", match.Topic, err)
+func (h *GitHubHandler) deliveryID(r *http.Request, body []byte) string {
+	return strings.TrimSpace(r.Header.Get("X-GitHub-Delivery"))
+}
+
+func (h *GitHubHandler) emit(r *http.Request, logger *log.Logger, event internal.Event) error {
+	return emitEvent(r, logger, h.rules, h.publisher, h.options, event)
+}
+
+// verifySignature checks X-Hub-Signature-256 against every active secret,
+// falling back to the legacy SHA-1 header when the SHA-256 one is absent.
+func (h *GitHubHandler) verifySignature(logger *log.Logger, r *http.Request, body []byte) (string, error) {
+	now := time.Now()
+	if signature := r.Header.Get("X-Hub-Signature-256"); signature != "" {
+		if id, ok := h.secrets.match(now, func(secret string) bool {
+			return verifyGitHubSHA256(secret, body, signature)
+		}); ok {
+			return id, nil
+		}
+		return "", github.ErrHMACVerificationFailed
+	}
+	if signature := r.Header.Get("X-Hub-Signature"); signature != "" {
+		if id, ok := h.secrets.match(now, func(secret string) bool {
+			return verifyGitHubSHA1(secret, body, signature)
+		}); ok {
+			logger.Printf("github parse warning: %v; accepted sha1 signature", github.ErrMissingHubSignatureHeader)
+			return id, nil
 		}
+		return "", github.ErrHMACVerificationFailed
 	}
+	return "", github.ErrMissingHubSignatureHeader
+}
+
+func verifyGitHubSHA256(secret string, body []byte, signature string) bool {
+	if secret == "" || signature == "" {
+		return false
+	}
+	signature = strings.TrimPrefix(signature, "sha256=")
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write(body)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(signature), []byte(expected))
 }
 
 func verifyGitHubSHA1(secret string, body []byte, signature string) bool {
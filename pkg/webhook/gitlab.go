@@ -3,11 +3,14 @@ package webhook
 import (
 	"bytes"
 	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"io"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"githooks/internal"
 	"githooks/pkg/storage"
@@ -18,12 +21,14 @@ import (
 // GitLabHandler handles incoming webhooks from GitLab.
 type GitLabHandler struct {
 	hook        *gitlab.Webhook
+	secrets     secretSet
 	rules       *internal.RuleEngine
 	publisher   internal.Publisher
 	logger      *log.Logger
 	maxBody     int64
 	debugEvents bool
 	namespaces  storage.NamespaceStore
+	options     handlerOptions
 }
 
 var gitlabEvents = []gitlab.Event{
@@ -43,19 +48,17 @@ var gitlabEvents = []gitlab.Event{
 }
 
 // NewGitLabHandler creates a new GitLabHandler.
-func NewGitLabHandler(secret string, rules *internal.RuleEngine, publisher internal.Publisher, logger *log.Logger, maxBody int64, debugEvents bool, namespaces storage.NamespaceStore) (*GitLabHandler, error) {
-	options := make([]gitlab.Option, 0, 1)
-	if secret != "" {
-		options = append(options, gitlab.Options.Secret(secret))
-	}
-	hook, err := gitlab.New(options...)
+func NewGitLabHandler(secret string, rules *internal.RuleEngine, publisher internal.Publisher, logger *log.Logger, maxBody int64, debugEvents bool, namespaces storage.NamespaceStore, opts ...HandlerOption) (*GitLabHandler, error) {
+	hook, err := gitlab.New()
 	if err != nil {
 		return nil, err
 	}
 	if logger == nil {
 		logger = log.Default()
 	}
-	return &GitLabHandler{hook: hook, rules: rules, publisher: publisher, logger: logger, maxBody: maxBody, debugEvents: debugEvents, namespaces: namespaces}, nil
+	handlerOpts := applyHandlerOptions(opts)
+	handlerOpts.credentialHeaders = []string{"X-Gitlab-Token"}
+	return &GitLabHandler{hook: hook, secrets: newSecretSet(secret, handlerOpts.secrets), rules: rules, publisher: publisher, logger: logger, maxBody: maxBody, debugEvents: debugEvents, namespaces: namespaces, options: handlerOpts}, nil
 }
 
 // ServeHTTP handles an incoming HTTP request.
@@ -66,6 +69,9 @@ func (h *GitLabHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	reqID := requestID(r)
 	w.Header().Set("X-Request-Id", reqID)
 	logger := internal.WithRequestID(h.logger, reqID)
+	if !h.options.allowlist.allow(w, r, logger) {
+		return
+	}
 	rawBody, err := io.ReadAll(r.Body)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
@@ -77,6 +83,18 @@ func (h *GitLabHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		logDebugEvent(logger, "gitlab", r.Header.Get("X-Gitlab-Event"), rawBody)
 	}
 
+	namespace, err := h.lookupNamespace(r.Context(), rawBody)
+	if err != nil {
+		logger.Printf("gitlab namespace lookup failed: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !h.verifyToken(logger, r, namespace) {
+		logger.Printf("gitlab parse failed: %v", gitlab.ErrGitLabTokenVerificationFailed)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
 	payload, err := h.hook.Parse(r, gitlabEvents...)
 	if err != nil {
 		logger.Printf("gitlab parse failed: %v", err)
@@ -88,8 +106,11 @@ func (h *GitLabHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	switch payload.(type) {
 	default:
 		rawObject, data := rawObjectAndFlatten(rawBody)
-		stateID := h.resolveStateID(r.Context(), rawBody)
-		h.emit(r, logger, internal.Event{
+		stateID := ""
+		if namespace != nil {
+			stateID = namespace.AccountID
+		}
+		if err := h.emit(r, logger, internal.Event{
 			Provider:   "gitlab",
 			Name:       eventName,
 			RequestID:  reqID,
@@ -97,15 +118,21 @@ func (h *GitLabHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			RawPayload: rawBody,
 			RawObject:  rawObject,
 			StateID:    stateID,
-		})
+			DeliveryID: h.deliveryID(r, rawBody),
+			Duplicate:  duplicateDelivery(r),
+		}); err != nil {
+			writeEmitError(w, logger, err)
+			return
+		}
 	}
 
 	w.WriteHeader(http.StatusOK)
 }
 
-func (h *GitLabHandler) resolveStateID(ctx context.Context, raw []byte) string {
+// lookupNamespace finds the namespace for the payload's project, if stored.
+func (h *GitLabHandler) lookupNamespace(ctx context.Context, raw []byte) (*storage.NamespaceRecord, error) {
 	if h.namespaces == nil {
-		return ""
+		return nil, nil
 	}
 	var payload struct {
 		Project struct {
@@ -114,28 +141,45 @@ func (h *GitLabHandler) resolveStateID(ctx context.Context, raw []byte) string {
 		ProjectID int64 `json:"project_id"`
 	}
 	if err := json.Unmarshal(raw, &payload); err != nil {
-		return ""
+		return nil, nil
 	}
 	repoID := payload.Project.ID
 	if repoID == 0 {
 		repoID = payload.ProjectID
 	}
 	if repoID == 0 {
-		return ""
-	}
-	record, err := h.namespaces.GetNamespace(ctx, "gitlab", strconv.FormatInt(repoID, 10))
-	if err != nil || record == nil {
-		return ""
+		return nil, nil
 	}
-	return record.AccountID
+	return h.namespaces.GetNamespace(ctx, "gitlab", strconv.FormatInt(repoID, 10))
 }
 
-func (h *GitLabHandler) emit(r *http.Request, logger *log.Logger, event internal.Event) {
-	topics := h.rules.EvaluateWithLogger(event, logger)
-	logger.Printf("event provider=%s name=%s topics=%v", event.Provider, event.Name, topics)
-	for _, match := range topics {
-		if err := h.publisher.PublishForDrivers(r.Context(), match.Topic, event, match.Drivers); err != nil {
-			logger.Printf("publish %s failed: %v", match.Topic, err)
+// verifyToken checks X-Gitlab-Token against the namespace's generated secret
+// when it has one, and otherwise against the provider secrets.
+func (h *GitLabHandler) verifyToken(logger *log.Logger, r *http.Request, namespace *storage.NamespaceRecord) bool {
+	token := r.Header.Get("X-Gitlab-Token")
+	if namespace != nil && namespace.WebhookSecret != "" {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(namespace.WebhookSecret)) != 1 {
+			return false
 		}
+		logger.Printf("gitlab token verified secret=namespace")
+		return true
+	}
+	if !h.secrets.configured() {
+		return true
+	}
+	secretID, ok := h.secrets.match(time.Now(), func(secret string) bool {
+		return subtle.ConstantTimeCompare([]byte(token), []byte(secret)) == 1
+	})
+	if ok {
+		logger.Printf("gitlab token verified secret=%s", secretID)
 	}
+	return ok
+}
+
+func (h *GitLabHandler) deliveryID(r *http.Request, body []byte) string {
+	return strings.TrimSpace(r.Header.Get("X-Gitlab-Event-UUID"))
+}
+
+func (h *GitLabHandler) emit(r *http.Request, logger *log.Logger, event internal.Event) error {
+	return emitEvent(r, logger, h.rules, h.publisher, h.options, event)
 }
@@ -0,0 +1,240 @@
+package webhook
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+
+	"githooks/internal"
+	"githooks/pkg/storage"
+)
+
+// InboxConfig controls the background inbox dispatcher.
+type InboxConfig struct {
+	Concurrency  int
+	BatchSize    int
+	PollInterval time.Duration
+	// Lease is how long a claimed row stays invisible to other dispatchers.
+	// Rows left behind by a crashed process become claimable once it expires.
+	Lease       time.Duration
+	MaxAttempts int
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+}
+
+// Inbox persists received webhooks and dispatches them in the background.
+type Inbox struct {
+	store     storage.InboxStore
+	rules     *internal.RuleEngine
+	publisher internal.Publisher
+	archive   *Archive
+	cfg       InboxConfig
+	logger    *log.Logger
+	wake      chan struct{}
+}
+
+// NewInbox creates an inbox dispatcher backed by store. When archive is set,
+// each row is archived once it is sent or given up on.
+func NewInbox(store storage.InboxStore, rules *internal.RuleEngine, publisher internal.Publisher, archive *Archive, cfg InboxConfig, logger *log.Logger) (*Inbox, error) {
+	if store == nil {
+		return nil, errors.New("inbox store is required")
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = cfg.Concurrency
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = time.Second
+	}
+	if cfg.Lease <= 0 {
+		cfg.Lease = time.Minute
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = 1
+	}
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &Inbox{
+		store:     store,
+		rules:     rules,
+		publisher: publisher,
+		archive:   archive,
+		cfg:       cfg,
+		logger:    logger,
+		wake:      make(chan struct{}, 1),
+	}, nil
+}
+
+// Enqueue persists an event and its request headers for asynchronous dispatch.
+func (i *Inbox) Enqueue(ctx context.Context, event internal.Event, headers map[string]string) error {
+	record := storage.InboxRecord{
+		ID:         watermill.NewUUID(),
+		Provider:   event.Provider,
+		EventName:  event.Name,
+		RequestID:  event.RequestID,
+		DeliveryID: event.DeliveryID,
+		StateID:    event.StateID,
+		Duplicate:  event.Duplicate,
+		Headers:    headers,
+		Payload:    event.RawPayload,
+		Status:     storage.InboxStatusPending,
+	}
+	if err := i.store.EnqueueInbox(ctx, record); err != nil {
+		return err
+	}
+	select {
+	case i.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Run claims and dispatches inbox rows until ctx is cancelled. In-flight rows
+// are allowed to finish; claimed rows that were not started are picked up again
+// once their lease expires.
+func (i *Inbox) Run(ctx context.Context) {
+	jobs := make(chan storage.InboxRecord)
+	dispatchCtx := context.WithoutCancel(ctx)
+	var wg sync.WaitGroup
+	for n := 0; n < i.cfg.Concurrency; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for record := range jobs {
+				i.dispatch(dispatchCtx, record)
+			}
+		}()
+	}
+	defer func() {
+		close(jobs)
+		wg.Wait()
+	}()
+
+	for {
+		records, err := i.store.ClaimInbox(ctx, i.cfg.BatchSize, i.cfg.Lease)
+		if err != nil && ctx.Err() == nil {
+			i.logger.Printf("inbox claim failed: %v", err)
+		}
+		for _, record := range records {
+			select {
+			case jobs <- record:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if err == nil && len(records) == i.cfg.BatchSize {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-i.wake:
+		case <-time.After(i.cfg.PollInterval):
+		}
+	}
+}
+
+func (i *Inbox) dispatch(ctx context.Context, record storage.InboxRecord) {
+	logger := internal.WithRequestID(i.logger, record.RequestID)
+	rawObject, data := rawObjectAndFlatten(record.Payload)
+	event := internal.Event{
+		Provider:   record.Provider,
+		Name:       record.EventName,
+		RequestID:  record.RequestID,
+		Data:       data,
+		RawPayload: record.Payload,
+		RawObject:  rawObject,
+		StateID:    record.StateID,
+		DeliveryID: record.DeliveryID,
+		Duplicate:  record.Duplicate,
+	}
+
+	normalized := withNormalized(event)
+	matches := i.rules.EvaluateWithLogger(normalized, logger)
+	logger.Printf("event provider=%s name=%s topics=%v", event.Provider, event.Name, matches)
+	published, publishErr := i.publishPending(ctx, logger, normalized, matches, record.PublishedMatches)
+	if publishErr == nil {
+		i.archive.Record(ctx, record.Headers, event, matches, nil)
+		if err := i.store.MarkInboxSent(ctx, record.ID); err != nil {
+			logger.Printf("inbox mark sent failed id=%s: %v", record.ID, err)
+		}
+		return
+	}
+
+	attempts := record.Attempts + 1
+	if attempts >= i.cfg.MaxAttempts {
+		logger.Printf("inbox dispatch failed id=%s attempts=%d: giving up", record.ID, attempts)
+		i.archive.Record(ctx, record.Headers, event, matches, publishErr)
+		if err := i.store.MarkInboxFailed(ctx, record.ID, attempts, publishErr.Error()); err != nil {
+			logger.Printf("inbox mark failed id=%s: %v", record.ID, err)
+		}
+		return
+	}
+	next := time.Now().UTC().Add(i.backoff(attempts))
+	if err := i.store.MarkInboxRetry(ctx, record.ID, attempts, next, publishErr.Error(), published); err != nil {
+		logger.Printf("inbox mark retry failed id=%s: %v", record.ID, err)
+	}
+}
+
+// publishPending publishes the matches not published by an earlier attempt
+// and returns the keys of every match published so far.
+func (i *Inbox) publishPending(ctx context.Context, logger *log.Logger, event internal.Event, matches []internal.RuleMatch, done []string) ([]string, error) {
+	published := append([]string(nil), done...)
+	var publishErr error
+	for _, match := range matches {
+		key := inboxMatchKey(match)
+		if slices.Contains(published, key) {
+			continue
+		}
+		if err := publishMatches(ctx, i.publisher, logger, event, []internal.RuleMatch{match}); err != nil {
+			publishErr = errors.Join(publishErr, err)
+			continue
+		}
+		published = append(published, key)
+	}
+	return published, publishErr
+}
+
+// inboxMatchKey identifies a match across attempts by its topic, drivers and
+// payload, so rules emitting the same topic are tracked separately. A match
+// without drivers or a payload is keyed by its topic alone.
+func inboxMatchKey(match internal.RuleMatch) string {
+	key := match.Topic
+	if len(match.Drivers) > 0 {
+		key += "|" + strings.Join(match.Drivers, ",")
+	}
+	if len(match.Payload) > 0 {
+		sum := sha256.Sum256(match.Payload)
+		key += "|" + hex.EncodeToString(sum[:8])
+	}
+	return key
+}
+
+// backoff returns an exponential delay for the given attempt, capped at BackoffMax.
+func (i *Inbox) backoff(attempt int) time.Duration {
+	delay := i.cfg.BackoffBase
+	if delay <= 0 {
+		return 0
+	}
+	for n := 1; n < attempt; n++ {
+		delay *= 2
+		if i.cfg.BackoffMax > 0 && delay >= i.cfg.BackoffMax {
+			return i.cfg.BackoffMax
+		}
+	}
+	if i.cfg.BackoffMax > 0 && delay > i.cfg.BackoffMax {
+		return i.cfg.BackoffMax
+	}
+	return delay
+}
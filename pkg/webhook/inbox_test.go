@@ -0,0 +1,301 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"sync"
+	"testing"
+	"time"
+
+	"githooks/internal"
+	"githooks/pkg/storage"
+)
+
+type memoryInboxStore struct {
+	mu      sync.Mutex
+	records map[string]*storage.InboxRecord
+}
+
+func newMemoryInboxStore() *memoryInboxStore {
+	return &memoryInboxStore{records: make(map[string]*storage.InboxRecord)}
+}
+
+func (s *memoryInboxStore) EnqueueInbox(ctx context.Context, record storage.InboxRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[record.ID] = &record
+	return nil
+}
+
+func (s *memoryInboxStore) ClaimInbox(ctx context.Context, limit int, lease time.Duration) ([]storage.InboxRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now().UTC()
+	var claimed []storage.InboxRecord
+	for _, record := range s.records {
+		if len(claimed) == limit {
+			break
+		}
+		if record.Status != storage.InboxStatusPending || record.NextAttemptAt.After(now) {
+			continue
+		}
+		if record.LockedUntil != nil && record.LockedUntil.After(now) {
+			continue
+		}
+		lockedUntil := now.Add(lease)
+		record.LockedUntil = &lockedUntil
+		claimed = append(claimed, *record)
+	}
+	return claimed, nil
+}
+
+func (s *memoryInboxStore) MarkInboxSent(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records[id].Status = storage.InboxStatusSent
+	s.records[id].LockedUntil = nil
+	return nil
+}
+
+func (s *memoryInboxStore) MarkInboxRetry(ctx context.Context, id string, attempts int, nextAttemptAt time.Time, lastError string, publishedMatches []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record := s.records[id]
+	record.Attempts = attempts
+	record.NextAttemptAt = nextAttemptAt
+	record.LastError = lastError
+	record.PublishedMatches = publishedMatches
+	record.LockedUntil = nil
+	return nil
+}
+
+func (s *memoryInboxStore) MarkInboxFailed(ctx context.Context, id string, attempts int, lastError string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record := s.records[id]
+	record.Status = storage.InboxStatusFailed
+	record.Attempts = attempts
+	record.LastError = lastError
+	record.LockedUntil = nil
+	return nil
+}
+
+func (s *memoryInboxStore) Close() error { return nil }
+
+func (s *memoryInboxStore) only(t *testing.T) storage.InboxRecord {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.records) != 1 {
+		t.Fatalf("expected one inbox record, got %d", len(s.records))
+	}
+	for _, record := range s.records {
+		return *record
+	}
+	return storage.InboxRecord{}
+}
+
+// failingPublisher records published topics and fails the topics or drivers
+// in failures until they are removed.
+type failingPublisher struct {
+	recordingPublisher
+	failures map[string]bool
+}
+
+func (p *failingPublisher) PublishForDrivers(ctx context.Context, topic string, event internal.Event, drivers []string) error {
+	p.mu.Lock()
+	fail := p.failures[topic]
+	for _, driver := range drivers {
+		fail = fail || p.failures[driver]
+	}
+	p.mu.Unlock()
+	if fail {
+		return errors.New("broker unavailable")
+	}
+	return p.recordingPublisher.PublishForDrivers(ctx, topic, event, drivers)
+}
+
+func newInboxTestRules(t *testing.T) *internal.RuleEngine {
+	t.Helper()
+	rules, err := internal.NewRuleEngine(internal.RulesConfig{
+		Rules:  []internal.Rule{{When: `action == "opened"`, Emit: internal.EmitList{"pr.opened", "audit.pr"}}},
+		Logger: log.New(io.Discard, "", 0),
+	})
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+	return rules
+}
+
+func enqueueOpened(t *testing.T, inbox *Inbox) {
+	t.Helper()
+	raw := []byte(`{"action":"opened"}`)
+	event := internal.Event{Provider: "github", Name: "pull_request", RequestID: "req-1", RawPayload: raw}
+	if err := inbox.Enqueue(context.Background(), event, map[string]string{"X-Github-Event": "pull_request"}); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+}
+
+func claimOne(t *testing.T, store *memoryInboxStore) storage.InboxRecord {
+	t.Helper()
+	claimed, err := store.ClaimInbox(context.Background(), 1, time.Minute)
+	if err != nil || len(claimed) != 1 {
+		t.Fatalf("expected one claimed record, got %d err=%v", len(claimed), err)
+	}
+	return claimed[0]
+}
+
+// TestInboxRunDispatches tests that enqueued rows are published and marked sent.
+func TestInboxRunDispatches(t *testing.T) {
+	store := newMemoryInboxStore()
+	publisher := &recordingPublisher{}
+	inbox, err := NewInbox(store, newInboxTestRules(t), publisher, nil, InboxConfig{PollInterval: time.Hour}, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatalf("inbox: %v", err)
+	}
+	enqueueOpened(t, inbox)
+	record := store.only(t)
+	if record.Status != storage.InboxStatusPending || record.RequestID != "req-1" || record.Headers["X-Github-Event"] != "pull_request" {
+		t.Fatalf("unexpected enqueued record: %+v", record)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		inbox.Run(ctx)
+		close(done)
+	}()
+	deadline := time.Now().Add(2 * time.Second)
+	for store.only(t).Status != storage.InboxStatusSent {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected record to be sent")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+	if len(publisher.topics) != 2 {
+		t.Fatalf("expected both topics to be published, got %v", publisher.topics)
+	}
+}
+
+// TestInboxRetryOnlyFailedTopics tests that a retry skips topics published by earlier attempts.
+func TestInboxRetryOnlyFailedTopics(t *testing.T) {
+	store := newMemoryInboxStore()
+	publisher := &failingPublisher{failures: map[string]bool{"audit.pr": true}}
+	cfg := InboxConfig{MaxAttempts: 3, BackoffBase: time.Minute, BackoffMax: time.Hour}
+	inbox, err := NewInbox(store, newInboxTestRules(t), publisher, nil, cfg, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatalf("inbox: %v", err)
+	}
+	enqueueOpened(t, inbox)
+
+	before := time.Now().UTC()
+	inbox.dispatch(context.Background(), claimOne(t, store))
+	record := store.only(t)
+	if record.Status != storage.InboxStatusPending || record.Attempts != 1 || record.LastError == "" {
+		t.Fatalf("expected a scheduled retry, got %+v", record)
+	}
+	if record.NextAttemptAt.Before(before.Add(time.Minute)) {
+		t.Fatalf("expected retry to wait for the backoff, got %v", record.NextAttemptAt)
+	}
+	if len(record.PublishedMatches) != 1 || record.PublishedMatches[0] != "pr.opened" {
+		t.Fatalf("expected pr.opened to be recorded as published, got %v", record.PublishedMatches)
+	}
+
+	publisher.mu.Lock()
+	delete(publisher.failures, "audit.pr")
+	publisher.mu.Unlock()
+	store.mu.Lock()
+	store.records[record.ID].NextAttemptAt = time.Now().UTC()
+	store.mu.Unlock()
+	inbox.dispatch(context.Background(), claimOne(t, store))
+
+	if status := store.only(t).Status; status != storage.InboxStatusSent {
+		t.Fatalf("expected record to be sent, got %s", status)
+	}
+	if len(publisher.topics) != 2 || publisher.topics[0] != "pr.opened" || publisher.topics[1] != "audit.pr" {
+		t.Fatalf("expected each topic to be published once, got %v", publisher.topics)
+	}
+}
+
+// TestInboxRetrySameTopicDrivers tests that matches sharing a topic but not drivers are retried separately.
+func TestInboxRetrySameTopicDrivers(t *testing.T) {
+	store := newMemoryInboxStore()
+	publisher := &failingPublisher{failures: map[string]bool{"nats": true}}
+	rules, err := internal.NewRuleEngine(internal.RulesConfig{
+		Rules: []internal.Rule{
+			{When: `action == "opened"`, Emit: internal.EmitList{"pr.opened"}, Drivers: []string{"kafka"}},
+			{When: `action == "opened"`, Emit: internal.EmitList{"pr.opened"}, Drivers: []string{"nats"}},
+		},
+		Logger: log.New(io.Discard, "", 0),
+	})
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+	inbox, err := NewInbox(store, rules, publisher, nil, InboxConfig{MaxAttempts: 3}, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatalf("inbox: %v", err)
+	}
+	enqueueOpened(t, inbox)
+
+	inbox.dispatch(context.Background(), claimOne(t, store))
+	if record := store.only(t); record.Status != storage.InboxStatusPending || len(record.PublishedMatches) != 1 {
+		t.Fatalf("expected only the kafka match to be recorded, got %+v", record)
+	}
+
+	publisher.mu.Lock()
+	delete(publisher.failures, "nats")
+	publisher.mu.Unlock()
+	inbox.dispatch(context.Background(), claimOne(t, store))
+
+	if status := store.only(t).Status; status != storage.InboxStatusSent {
+		t.Fatalf("expected record to be sent, got %s", status)
+	}
+	if len(publisher.topics) != 2 {
+		t.Fatalf("expected pr.opened to be published once per driver, got %v", publisher.topics)
+	}
+}
+
+// TestInboxPermanentFailure tests that a row is marked failed once MaxAttempts is reached.
+func TestInboxPermanentFailure(t *testing.T) {
+	store := newMemoryInboxStore()
+	publisher := &failingPublisher{failures: map[string]bool{"pr.opened": true, "audit.pr": true}}
+	inbox, err := NewInbox(store, newInboxTestRules(t), publisher, nil, InboxConfig{MaxAttempts: 2}, log.New(io.Discard, "", 0))
+	if err != nil {
+		t.Fatalf("inbox: %v", err)
+	}
+	enqueueOpened(t, inbox)
+
+	inbox.dispatch(context.Background(), claimOne(t, store))
+	if record := store.only(t); record.Status != storage.InboxStatusPending || record.Attempts != 1 {
+		t.Fatalf("expected first failure to be retried, got %+v", record)
+	}
+	inbox.dispatch(context.Background(), claimOne(t, store))
+	record := store.only(t)
+	if record.Status != storage.InboxStatusFailed || record.Attempts != 2 || record.LastError == "" {
+		t.Fatalf("expected record to be marked failed, got %+v", record)
+	}
+	if claimed, _ := store.ClaimInbox(context.Background(), 1, time.Minute); len(claimed) != 0 {
+		t.Fatalf("expected failed record to stay failed")
+	}
+}
+
+// TestInboxBackoff tests exponential backoff capped at BackoffMax.
+func TestInboxBackoff(t *testing.T) {
+	inbox := &Inbox{cfg: InboxConfig{BackoffBase: time.Second, BackoffMax: 5 * time.Second}}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for n, expected := range want {
+		if got := inbox.backoff(n + 1); got != expected {
+			t.Fatalf("attempt %d: expected %v, got %v", n+1, expected, got)
+		}
+	}
+	if got := (&Inbox{}).backoff(3); got != 0 {
+		t.Fatalf("expected no backoff without a base, got %v", got)
+	}
+}
@@ -0,0 +1,392 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"githooks/internal"
+	"githooks/pkg/normalized"
+)
+
+// normalizeEvent builds the provider-independent model for event. It returns
+// nil for providers and events without a normalized form.
+func normalizeEvent(event internal.Event) *normalized.Event {
+	if len(event.RawPayload) == 0 {
+		return nil
+	}
+	var model *normalized.Event
+	switch event.Provider {
+	case "github", "gitea":
+		model = normalizeGitHub(event.Name, event.RawPayload)
+	case "gitlab":
+		model = normalizeGitLab(event.RawPayload)
+	case "bitbucket":
+		model = normalizeBitbucket(event.Name, event.RawPayload)
+	case "bitbucket_server":
+		model = normalizeBitbucketServer(event.Name, event.RawPayload)
+	case "azuredevops":
+		model = normalizeAzureDevOps(event.Name, event.RawPayload)
+	}
+	if model != nil {
+		model.Provider = event.Provider
+	}
+	return model
+}
+
+// withNormalized sets event.Normalized from the normalized model, keeping an
+// already normalized event unchanged.
+func withNormalized(event internal.Event) internal.Event {
+	if event.Normalized != nil {
+		return event
+	}
+	model := normalizeEvent(event)
+	if model == nil {
+		return event
+	}
+	encoded, err := json.Marshal(model)
+	if err != nil {
+		return event
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal(encoded, &out); err != nil {
+		return event
+	}
+	event.Normalized = out
+	return event
+}
+
+// flexID decodes ids that providers send either as JSON numbers or strings.
+type flexID string
+
+func (f *flexID) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var value string
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+		*f = flexID(value)
+		return nil
+	}
+	*f = flexID(data)
+	return nil
+}
+
+// zeroSHA reports whether sha is the all-zero object id providers use for
+// created and deleted refs.
+func zeroSHA(sha string) bool {
+	return sha != "" && strings.Trim(sha, "0") == ""
+}
+
+func branchName(ref string) string {
+	return strings.TrimPrefix(ref, "refs/heads/")
+}
+
+// refModel returns a tag or push model for a ref update.
+func refModel(ref, before, after string, created, deleted, forced bool, commits []normalized.Commit) *normalized.Event {
+	if tag, ok := strings.CutPrefix(ref, "refs/tags/"); ok {
+		sha := after
+		if deleted {
+			sha = before
+		}
+		action := "created"
+		if deleted {
+			action = "deleted"
+		}
+		return &normalized.Event{
+			Kind:   normalized.KindTag,
+			Action: action,
+			Tag:    &normalized.Tag{Name: tag, Ref: ref, SHA: sha, Deleted: deleted},
+		}
+	}
+	return &normalized.Event{
+		Kind: normalized.KindPush,
+		Push: &normalized.Push{
+			Ref:     ref,
+			Branch:  branchName(ref),
+			Before:  before,
+			After:   after,
+			Created: created,
+			Deleted: deleted,
+			Forced:  forced,
+			Commits: commits,
+		},
+	}
+}
+
+type githubUser struct {
+	ID    flexID `json:"id"`
+	Login string `json:"login"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+func (u *githubUser) actor() *normalized.Actor {
+	if u == nil || (u.ID == "" && u.Login == "") {
+		return nil
+	}
+	return &normalized.Actor{ID: string(u.ID), Login: u.Login, Name: u.Name, Email: u.Email}
+}
+
+type githubRepository struct {
+	ID            flexID      `json:"id"`
+	Name          string      `json:"name"`
+	FullName      string      `json:"full_name"`
+	Owner         *githubUser `json:"owner"`
+	HTMLURL       string      `json:"html_url"`
+	DefaultBranch string      `json:"default_branch"`
+	Private       bool        `json:"private"`
+}
+
+func (r *githubRepository) repository() *normalized.Repository {
+	if r == nil || r.FullName == "" {
+		return nil
+	}
+	repo := &normalized.Repository{
+		ID:            string(r.ID),
+		Name:          r.Name,
+		FullName:      r.FullName,
+		URL:           r.HTMLURL,
+		DefaultBranch: r.DefaultBranch,
+		Private:       r.Private,
+	}
+	if r.Owner != nil {
+		repo.Owner = r.Owner.Login
+	}
+	return repo
+}
+
+type githubPullRequest struct {
+	Number  int         `json:"number"`
+	Title   string      `json:"title"`
+	Body    string      `json:"body"`
+	State   string      `json:"state"`
+	Draft   bool        `json:"draft"`
+	Merged  bool        `json:"merged"`
+	HTMLURL string      `json:"html_url"`
+	User    *githubUser `json:"user"`
+	Head    struct {
+		Ref string `json:"ref"`
+		SHA string `json:"sha"`
+	} `json:"head"`
+	Base struct {
+		Ref string `json:"ref"`
+		SHA string `json:"sha"`
+	} `json:"base"`
+	MergeCommitSHA string `json:"merge_commit_sha"`
+	Labels         []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+}
+
+func (p *githubPullRequest) pullRequest() *normalized.PullRequest {
+	pr := &normalized.PullRequest{
+		Number:       p.Number,
+		Title:        p.Title,
+		Body:         p.Body,
+		State:        normalized.StateOpen,
+		Draft:        p.Draft,
+		Merged:       p.Merged,
+		URL:          p.HTMLURL,
+		SourceBranch: p.Head.Ref,
+		TargetBranch: p.Base.Ref,
+		HeadSHA:      p.Head.SHA,
+		BaseSHA:      p.Base.SHA,
+		Author:       p.User.actor(),
+	}
+	switch {
+	case p.Merged:
+		pr.State = normalized.StateMerged
+		pr.MergeSHA = p.MergeCommitSHA
+	case p.State == "closed":
+		pr.State = normalized.StateClosed
+	}
+	for _, label := range p.Labels {
+		pr.Labels = append(pr.Labels, label.Name)
+	}
+	return pr
+}
+
+// githubPayload covers the GitHub event payloads that have a normalized form.
+// Gitea sends the same shapes.
+type githubPayload struct {
+	Action      string             `json:"action"`
+	Repository  *githubRepository  `json:"repository"`
+	Sender      *githubUser        `json:"sender"`
+	PullRequest *githubPullRequest `json:"pull_request"`
+
+	Ref     string `json:"ref"`
+	RefType string `json:"ref_type"`
+	Before  string `json:"before"`
+	After   string `json:"after"`
+	Created bool   `json:"created"`
+	Deleted bool   `json:"deleted"`
+	Forced  bool   `json:"forced"`
+	Commits []struct {
+		ID      string `json:"id"`
+		Message string `json:"message"`
+		URL     string `json:"url"`
+		Author  struct {
+			Name     string `json:"name"`
+			Username string `json:"username"`
+		} `json:"author"`
+	} `json:"commits"`
+	Pusher *githubUser `json:"pusher"`
+
+	Comment *struct {
+		ID       flexID `json:"id"`
+		Body     string `json:"body"`
+		HTMLURL  string `json:"html_url"`
+		CommitID string `json:"commit_id"`
+	} `json:"comment"`
+	Issue *struct {
+		Number      int              `json:"number"`
+		PullRequest *json.RawMessage `json:"pull_request"`
+	} `json:"issue"`
+
+	WorkflowRun *struct {
+		ID         flexID `json:"id"`
+		Name       string `json:"name"`
+		Status     string `json:"status"`
+		Conclusion string `json:"conclusion"`
+		HeadBranch string `json:"head_branch"`
+		HeadSHA    string `json:"head_sha"`
+		HTMLURL    string `json:"html_url"`
+	} `json:"workflow_run"`
+
+	Release *struct {
+		TagName    string `json:"tag_name"`
+		Name       string `json:"name"`
+		Body       string `json:"body"`
+		HTMLURL    string `json:"html_url"`
+		Draft      bool   `json:"draft"`
+		Prerelease bool   `json:"prerelease"`
+	} `json:"release"`
+}
+
+func normalizeGitHub(eventName string, raw []byte) *normalized.Event {
+	var payload githubPayload
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return nil
+	}
+	var model *normalized.Event
+	switch eventName {
+	case "pull_request":
+		if payload.PullRequest == nil {
+			return nil
+		}
+		action := payload.Action
+		switch {
+		case action == "closed" && payload.PullRequest.Merged:
+			action = "merged"
+		case action == "synchronize" || action == "synchronized":
+			action = "updated"
+		}
+		model = &normalized.Event{Kind: normalized.KindPullRequest, Action: action, PullRequest: payload.PullRequest.pullRequest()}
+	case "push":
+		commits := make([]normalized.Commit, 0, len(payload.Commits))
+		for _, commit := range payload.Commits {
+			author := commit.Author.Username
+			if author == "" {
+				author = commit.Author.Name
+			}
+			commits = append(commits, normalized.Commit{SHA: commit.ID, Message: commit.Message, URL: commit.URL, Author: author})
+		}
+		created := payload.Created || zeroSHA(payload.Before)
+		deleted := payload.Deleted || zeroSHA(payload.After)
+		model = refModel(payload.Ref, payload.Before, payload.After, created, deleted, payload.Forced, commits)
+	case "create", "delete":
+		if payload.RefType != "tag" {
+			return nil
+		}
+		action := "created"
+		if eventName == "delete" {
+			action = "deleted"
+		}
+		model = &normalized.Event{
+			Kind:   normalized.KindTag,
+			Action: action,
+			Tag:    &normalized.Tag{Name: payload.Ref, Ref: "refs/tags/" + payload.Ref, Deleted: eventName == "delete"},
+		}
+	case "issue_comment", "pull_request_review_comment", "commit_comment":
+		if payload.Comment == nil {
+			return nil
+		}
+		comment := &normalized.Comment{ID: string(payload.Comment.ID), Body: payload.Comment.Body, URL: payload.Comment.HTMLURL}
+		switch {
+		case payload.PullRequest != nil:
+			comment.Target, comment.Number = normalized.KindPullRequest, payload.PullRequest.Number
+		case payload.Issue != nil && payload.Issue.PullRequest != nil:
+			comment.Target, comment.Number = normalized.KindPullRequest, payload.Issue.Number
+		case payload.Issue != nil:
+			comment.Target, comment.Number = "issue", payload.Issue.Number
+		default:
+			comment.Target, comment.SHA = "commit", payload.Comment.CommitID
+		}
+		model = &normalized.Event{Kind: normalized.KindComment, Action: payload.Action, Comment: comment}
+	case "workflow_run":
+		run := payload.WorkflowRun
+		if run == nil {
+			return nil
+		}
+		model = &normalized.Event{
+			Kind:   normalized.KindPipeline,
+			Action: payload.Action,
+			Pipeline: &normalized.Pipeline{
+				ID:     string(run.ID),
+				Name:   run.Name,
+				Status: githubRunStatus(run.Status, run.Conclusion),
+				Ref:    run.HeadBranch,
+				SHA:    run.HeadSHA,
+				URL:    run.HTMLURL,
+			},
+		}
+	case "release":
+		release := payload.Release
+		if release == nil {
+			return nil
+		}
+		model = &normalized.Event{
+			Kind:   normalized.KindRelease,
+			Action: payload.Action,
+			Release: &normalized.Release{
+				Tag:        release.TagName,
+				Name:       release.Name,
+				Body:       release.Body,
+				URL:        release.HTMLURL,
+				Draft:      release.Draft,
+				Prerelease: release.Prerelease,
+			},
+		}
+	default:
+		return nil
+	}
+	model.Repository = payload.Repository.repository()
+	model.Actor = payload.Sender.actor()
+	if model.Actor == nil {
+		model.Actor = payload.Pusher.actor()
+	}
+	return model
+}
+
+func githubRunStatus(status, conclusion string) string {
+	switch status {
+	case "in_progress":
+		return normalized.StatusRunning
+	case "completed":
+	default:
+		return normalized.StatusPending
+	}
+	switch conclusion {
+	case "success", "neutral":
+		return normalized.StatusSuccess
+	case "cancelled", "skipped", "stale":
+		return normalized.StatusCancelled
+	default:
+		return normalized.StatusFailure
+	}
+}
@@ -0,0 +1,239 @@
+package webhook
+
+import (
+	"encoding/json"
+	"strings"
+
+	"githooks/pkg/normalized"
+)
+
+type azureDevOpsIdentity struct {
+	ID          string `json:"id"`
+	DisplayName string `json:"displayName"`
+	UniqueName  string `json:"uniqueName"`
+}
+
+func (i *azureDevOpsIdentity) actor() *normalized.Actor {
+	if i == nil || i.ID == "" {
+		return nil
+	}
+	return &normalized.Actor{ID: i.ID, Login: i.UniqueName, Name: i.DisplayName}
+}
+
+type azureDevOpsRepository struct {
+	ID            string `json:"id"`
+	Name          string `json:"name"`
+	RemoteURL     string `json:"remoteUrl"`
+	DefaultBranch string `json:"defaultBranch"`
+	Project       struct {
+		Name       string `json:"name"`
+		Visibility string `json:"visibility"`
+	} `json:"project"`
+}
+
+func (r *azureDevOpsRepository) repository() *normalized.Repository {
+	if r == nil || r.ID == "" {
+		return nil
+	}
+	return &normalized.Repository{
+		ID:            r.ID,
+		Name:          r.Name,
+		FullName:      r.Project.Name + "/" + r.Name,
+		Owner:         r.Project.Name,
+		URL:           r.RemoteURL,
+		DefaultBranch: branchName(r.DefaultBranch),
+		Private:       r.Project.Visibility != "public",
+	}
+}
+
+type azureDevOpsCommitRef struct {
+	CommitID string `json:"commitId"`
+}
+
+type azureDevOpsPullRequest struct {
+	PullRequestID         int                    `json:"pullRequestId"`
+	Title                 string                 `json:"title"`
+	Description           string                 `json:"description"`
+	Status                string                 `json:"status"`
+	IsDraft               bool                   `json:"isDraft"`
+	SourceRefName         string                 `json:"sourceRefName"`
+	TargetRefName         string                 `json:"targetRefName"`
+	LastMergeSourceCommit *azureDevOpsCommitRef  `json:"lastMergeSourceCommit"`
+	LastMergeTargetCommit *azureDevOpsCommitRef  `json:"lastMergeTargetCommit"`
+	LastMergeCommit       *azureDevOpsCommitRef  `json:"lastMergeCommit"`
+	CreatedBy             *azureDevOpsIdentity   `json:"createdBy"`
+	Repository            *azureDevOpsRepository `json:"repository"`
+	URL                   string                 `json:"url"`
+	Labels                []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+}
+
+// azureDevOpsPayload covers the Azure DevOps service hook payloads that have a
+// normalized form.
+type azureDevOpsPayload struct {
+	Resource json.RawMessage `json:"resource"`
+}
+
+func normalizeAzureDevOps(eventName string, raw []byte) *normalized.Event {
+	var payload azureDevOpsPayload
+	if err := json.Unmarshal(raw, &payload); err != nil || len(payload.Resource) == 0 {
+		return nil
+	}
+	switch eventName {
+	case "git.pullrequest.created", "git.pullrequest.updated", "git.pullrequest.merged":
+		var src azureDevOpsPullRequest
+		if err := json.Unmarshal(payload.Resource, &src); err != nil {
+			return nil
+		}
+		action := strings.TrimPrefix(eventName, "git.pullrequest.")
+		if action == "created" {
+			action = "opened"
+		}
+		if src.Status == "abandoned" {
+			action = "closed"
+		}
+		return &normalized.Event{
+			Kind:        normalized.KindPullRequest,
+			Action:      action,
+			Repository:  src.Repository.repository(),
+			Actor:       src.CreatedBy.actor(),
+			PullRequest: azureDevOpsPullRequestModel(src),
+		}
+	case "git.push":
+		var src struct {
+			RefUpdates []struct {
+				Name        string `json:"name"`
+				OldObjectID string `json:"oldObjectId"`
+				NewObjectID string `json:"newObjectId"`
+			} `json:"refUpdates"`
+			Commits []struct {
+				CommitID string `json:"commitId"`
+				Comment  string `json:"comment"`
+				URL      string `json:"url"`
+				Author   struct {
+					Name string `json:"name"`
+				} `json:"author"`
+			} `json:"commits"`
+			PushedBy   *azureDevOpsIdentity   `json:"pushedBy"`
+			Repository *azureDevOpsRepository `json:"repository"`
+		}
+		if err := json.Unmarshal(payload.Resource, &src); err != nil || len(src.RefUpdates) == 0 {
+			return nil
+		}
+		update := src.RefUpdates[0]
+		commits := make([]normalized.Commit, 0, len(src.Commits))
+		for _, commit := range src.Commits {
+			commits = append(commits, normalized.Commit{SHA: commit.CommitID, Message: commit.Comment, URL: commit.URL, Author: commit.Author.Name})
+		}
+		model := refModel(update.Name, update.OldObjectID, update.NewObjectID, zeroSHA(update.OldObjectID), zeroSHA(update.NewObjectID), false, commits)
+		model.Repository = src.Repository.repository()
+		model.Actor = src.PushedBy.actor()
+		return model
+	case "ms.vss-code.git-pullrequest-comment-event":
+		var src struct {
+			Comment struct {
+				ID      flexID               `json:"id"`
+				Content string               `json:"content"`
+				Author  *azureDevOpsIdentity `json:"author"`
+			} `json:"comment"`
+			PullRequest azureDevOpsPullRequest `json:"pullRequest"`
+		}
+		if err := json.Unmarshal(payload.Resource, &src); err != nil {
+			return nil
+		}
+		return &normalized.Event{
+			Kind:       normalized.KindComment,
+			Action:     "created",
+			Repository: src.PullRequest.Repository.repository(),
+			Actor:      src.Comment.Author.actor(),
+			Comment: &normalized.Comment{
+				ID:     string(src.Comment.ID),
+				Body:   src.Comment.Content,
+				Target: normalized.KindPullRequest,
+				Number: src.PullRequest.PullRequestID,
+			},
+		}
+	case "build.complete":
+		var src struct {
+			ID            flexID `json:"id"`
+			BuildNumber   string `json:"buildNumber"`
+			Status        string `json:"status"`
+			Result        string `json:"result"`
+			SourceBranch  string `json:"sourceBranch"`
+			SourceVersion string `json:"sourceVersion"`
+			URL           string `json:"url"`
+			Definition    struct {
+				Name string `json:"name"`
+			} `json:"definition"`
+			RequestedFor *azureDevOpsIdentity   `json:"requestedFor"`
+			Repository   *azureDevOpsRepository `json:"repository"`
+		}
+		if err := json.Unmarshal(payload.Resource, &src); err != nil {
+			return nil
+		}
+		return &normalized.Event{
+			Kind:       normalized.KindPipeline,
+			Action:     "completed",
+			Repository: src.Repository.repository(),
+			Actor:      src.RequestedFor.actor(),
+			Pipeline: &normalized.Pipeline{
+				ID:     string(src.ID),
+				Name:   src.Definition.Name,
+				Status: azureDevOpsBuildStatus(src.Result),
+				Ref:    branchName(src.SourceBranch),
+				SHA:    src.SourceVersion,
+				URL:    src.URL,
+			},
+		}
+	default:
+		return nil
+	}
+}
+
+func azureDevOpsPullRequestModel(src azureDevOpsPullRequest) *normalized.PullRequest {
+	pr := &normalized.PullRequest{
+		Number:       src.PullRequestID,
+		Title:        src.Title,
+		Body:         src.Description,
+		State:        normalized.StateOpen,
+		Draft:        src.IsDraft,
+		URL:          src.URL,
+		SourceBranch: branchName(src.SourceRefName),
+		TargetBranch: branchName(src.TargetRefName),
+		Author:       src.CreatedBy.actor(),
+	}
+	switch src.Status {
+	case "completed":
+		pr.State = normalized.StateMerged
+		pr.Merged = true
+	case "abandoned":
+		pr.State = normalized.StateClosed
+	}
+	if src.LastMergeSourceCommit != nil {
+		pr.HeadSHA = src.LastMergeSourceCommit.CommitID
+	}
+	if src.LastMergeTargetCommit != nil {
+		pr.BaseSHA = src.LastMergeTargetCommit.CommitID
+	}
+	if src.LastMergeCommit != nil && pr.Merged {
+		pr.MergeSHA = src.LastMergeCommit.CommitID
+	}
+	for _, label := range src.Labels {
+		pr.Labels = append(pr.Labels, label.Name)
+	}
+	return pr
+}
+
+func azureDevOpsBuildStatus(result string) string {
+	switch result {
+	case "succeeded", "partiallySucceeded":
+		return normalized.StatusSuccess
+	case "failed":
+		return normalized.StatusFailure
+	case "canceled":
+		return normalized.StatusCancelled
+	default:
+		return normalized.StatusPending
+	}
+}
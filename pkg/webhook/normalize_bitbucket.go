@@ -0,0 +1,454 @@
+package webhook
+
+import (
+	"encoding/json"
+	"strings"
+
+	"githooks/pkg/normalized"
+)
+
+type bitbucketLink struct {
+	Href string `json:"href"`
+}
+
+type bitbucketUser struct {
+	UUID        string `json:"uuid"`
+	AccountID   string `json:"account_id"`
+	Nickname    string `json:"nickname"`
+	DisplayName string `json:"display_name"`
+}
+
+func (u *bitbucketUser) actor() *normalized.Actor {
+	if u == nil || (u.UUID == "" && u.Nickname == "") {
+		return nil
+	}
+	return &normalized.Actor{ID: u.UUID, Login: u.Nickname, Name: u.DisplayName}
+}
+
+type bitbucketRepository struct {
+	UUID      string `json:"uuid"`
+	Name      string `json:"name"`
+	FullName  string `json:"full_name"`
+	IsPrivate bool   `json:"is_private"`
+	Links     struct {
+		HTML bitbucketLink `json:"html"`
+	} `json:"links"`
+	Workspace *struct {
+		Slug string `json:"slug"`
+	} `json:"workspace"`
+	MainBranch *struct {
+		Name string `json:"name"`
+	} `json:"mainbranch"`
+}
+
+func (r *bitbucketRepository) repository() *normalized.Repository {
+	if r == nil || r.FullName == "" {
+		return nil
+	}
+	repo := &normalized.Repository{
+		ID:       r.UUID,
+		Name:     r.Name,
+		FullName: r.FullName,
+		URL:      r.Links.HTML.Href,
+		Private:  r.IsPrivate,
+	}
+	if owner, _, ok := strings.Cut(r.FullName, "/"); ok {
+		repo.Owner = owner
+	}
+	if r.Workspace != nil && r.Workspace.Slug != "" {
+		repo.Owner = r.Workspace.Slug
+	}
+	if r.MainBranch != nil {
+		repo.DefaultBranch = r.MainBranch.Name
+	}
+	return repo
+}
+
+type bitbucketRef struct {
+	Branch struct {
+		Name string `json:"name"`
+	} `json:"branch"`
+	Commit struct {
+		Hash string `json:"hash"`
+	} `json:"commit"`
+}
+
+// bitbucketPayload covers the Bitbucket Cloud event payloads that have a
+// normalized form.
+type bitbucketPayload struct {
+	Actor       *bitbucketUser       `json:"actor"`
+	Repository  *bitbucketRepository `json:"repository"`
+	PullRequest *struct {
+		ID          int            `json:"id"`
+		Title       string         `json:"title"`
+		Description string         `json:"description"`
+		State       string         `json:"state"`
+		Draft       bool           `json:"draft"`
+		Author      *bitbucketUser `json:"author"`
+		Source      bitbucketRef   `json:"source"`
+		Destination bitbucketRef   `json:"destination"`
+		MergeCommit *struct {
+			Hash string `json:"hash"`
+		} `json:"merge_commit"`
+		Links struct {
+			HTML bitbucketLink `json:"html"`
+		} `json:"links"`
+	} `json:"pullrequest"`
+	Push *struct {
+		Changes []struct {
+			New     *bitbucketPushRef `json:"new"`
+			Old     *bitbucketPushRef `json:"old"`
+			Created bool              `json:"created"`
+			Closed  bool              `json:"closed"`
+			Forced  bool              `json:"forced"`
+			Commits []struct {
+				Hash    string `json:"hash"`
+				Message string `json:"message"`
+				Author  struct {
+					Raw string `json:"raw"`
+				} `json:"author"`
+				Links struct {
+					HTML bitbucketLink `json:"html"`
+				} `json:"links"`
+			} `json:"commits"`
+		} `json:"changes"`
+	} `json:"push"`
+	Comment *struct {
+		ID      flexID `json:"id"`
+		Content struct {
+			Raw string `json:"raw"`
+		} `json:"content"`
+		Links struct {
+			HTML bitbucketLink `json:"html"`
+		} `json:"links"`
+	} `json:"comment"`
+	Commit *struct {
+		Hash string `json:"hash"`
+	} `json:"commit"`
+	CommitStatus *struct {
+		Key     string `json:"key"`
+		Name    string `json:"name"`
+		State   string `json:"state"`
+		URL     string `json:"url"`
+		Refname string `json:"refname"`
+		Commit  struct {
+			Hash string `json:"hash"`
+		} `json:"commit"`
+	} `json:"commit_status"`
+}
+
+type bitbucketPushRef struct {
+	Type   string `json:"type"`
+	Name   string `json:"name"`
+	Target struct {
+		Hash string `json:"hash"`
+	} `json:"target"`
+}
+
+func normalizeBitbucket(eventName string, raw []byte) *normalized.Event {
+	var payload bitbucketPayload
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return nil
+	}
+	var model *normalized.Event
+	switch {
+	case eventName == "pullrequest:comment_created" || eventName == "pullrequest:comment_updated":
+		if payload.Comment == nil || payload.PullRequest == nil {
+			return nil
+		}
+		model = &normalized.Event{
+			Kind:   normalized.KindComment,
+			Action: strings.TrimPrefix(eventName, "pullrequest:comment_"),
+			Comment: &normalized.Comment{
+				ID:     string(payload.Comment.ID),
+				Body:   payload.Comment.Content.Raw,
+				URL:    payload.Comment.Links.HTML.Href,
+				Target: normalized.KindPullRequest,
+				Number: payload.PullRequest.ID,
+			},
+		}
+	case strings.HasPrefix(eventName, "pullrequest:"):
+		src := payload.PullRequest
+		if src == nil {
+			return nil
+		}
+		pr := &normalized.PullRequest{
+			Number:       src.ID,
+			Title:        src.Title,
+			Body:         src.Description,
+			State:        bitbucketPullRequestState(src.State),
+			Draft:        src.Draft,
+			Merged:       src.State == "MERGED",
+			URL:          src.Links.HTML.Href,
+			SourceBranch: src.Source.Branch.Name,
+			TargetBranch: src.Destination.Branch.Name,
+			HeadSHA:      src.Source.Commit.Hash,
+			BaseSHA:      src.Destination.Commit.Hash,
+			Author:       src.Author.actor(),
+		}
+		if src.MergeCommit != nil {
+			pr.MergeSHA = src.MergeCommit.Hash
+		}
+		action := strings.TrimPrefix(eventName, "pullrequest:")
+		switch action {
+		case "created":
+			action = "opened"
+		case "fulfilled":
+			action = "merged"
+		case "rejected":
+			action = "closed"
+		}
+		model = &normalized.Event{Kind: normalized.KindPullRequest, Action: action, PullRequest: pr}
+	case eventName == "repo:push":
+		if payload.Push == nil || len(payload.Push.Changes) == 0 {
+			return nil
+		}
+		change := payload.Push.Changes[0]
+		ref := change.New
+		if ref == nil {
+			ref = change.Old
+		}
+		if ref == nil {
+			return nil
+		}
+		var before, after string
+		if change.Old != nil {
+			before = change.Old.Target.Hash
+		}
+		if change.New != nil {
+			after = change.New.Target.Hash
+		}
+		prefix := "refs/heads/"
+		if ref.Type == "tag" {
+			prefix = "refs/tags/"
+		}
+		commits := make([]normalized.Commit, 0, len(change.Commits))
+		for _, commit := range change.Commits {
+			commits = append(commits, normalized.Commit{SHA: commit.Hash, Message: commit.Message, URL: commit.Links.HTML.Href, Author: commit.Author.Raw})
+		}
+		model = refModel(prefix+ref.Name, before, after, change.Created, change.Closed, change.Forced, commits)
+	case eventName == "repo:commit_comment_created":
+		if payload.Comment == nil {
+			return nil
+		}
+		comment := &normalized.Comment{
+			ID:     string(payload.Comment.ID),
+			Body:   payload.Comment.Content.Raw,
+			URL:    payload.Comment.Links.HTML.Href,
+			Target: "commit",
+		}
+		if payload.Commit != nil {
+			comment.SHA = payload.Commit.Hash
+		}
+		model = &normalized.Event{Kind: normalized.KindComment, Action: "created", Comment: comment}
+	case eventName == "repo:commit_status_created" || eventName == "repo:commit_status_updated":
+		status := payload.CommitStatus
+		if status == nil {
+			return nil
+		}
+		model = &normalized.Event{
+			Kind:   normalized.KindPipeline,
+			Action: strings.TrimPrefix(eventName, "repo:commit_status_"),
+			Pipeline: &normalized.Pipeline{
+				ID:     status.Key,
+				Name:   status.Name,
+				Status: bitbucketStatus(status.State),
+				Ref:    status.Refname,
+				SHA:    status.Commit.Hash,
+				URL:    status.URL,
+			},
+		}
+	default:
+		return nil
+	}
+	model.Repository = payload.Repository.repository()
+	model.Actor = payload.Actor.actor()
+	return model
+}
+
+func bitbucketPullRequestState(state string) string {
+	switch strings.ToUpper(state) {
+	case "MERGED":
+		return normalized.StateMerged
+	case "DECLINED", "SUPERSEDED":
+		return normalized.StateClosed
+	default:
+		return normalized.StateOpen
+	}
+}
+
+func bitbucketStatus(state string) string {
+	switch strings.ToUpper(state) {
+	case "INPROGRESS":
+		return normalized.StatusRunning
+	case "SUCCESSFUL":
+		return normalized.StatusSuccess
+	case "FAILED":
+		return normalized.StatusFailure
+	case "STOPPED":
+		return normalized.StatusCancelled
+	default:
+		return normalized.StatusPending
+	}
+}
+
+type bitbucketServerUser struct {
+	ID           flexID `json:"id"`
+	Name         string `json:"name"`
+	DisplayName  string `json:"displayName"`
+	EmailAddress string `json:"emailAddress"`
+}
+
+func (u *bitbucketServerUser) actor() *normalized.Actor {
+	if u == nil || (u.ID == "" && u.Name == "") {
+		return nil
+	}
+	return &normalized.Actor{ID: string(u.ID), Login: u.Name, Name: u.DisplayName, Email: u.EmailAddress}
+}
+
+type bitbucketServerRepository struct {
+	ID      flexID `json:"id"`
+	Slug    string `json:"slug"`
+	Name    string `json:"name"`
+	Public  bool   `json:"public"`
+	Project struct {
+		Key string `json:"key"`
+	} `json:"project"`
+	Links struct {
+		Self []bitbucketLink `json:"self"`
+	} `json:"links"`
+}
+
+func (r *bitbucketServerRepository) repository() *normalized.Repository {
+	if r == nil || r.Slug == "" {
+		return nil
+	}
+	repo := &normalized.Repository{
+		ID:       string(r.ID),
+		Name:     r.Name,
+		FullName: strings.ToLower(r.Project.Key) + "/" + r.Slug,
+		Owner:    r.Project.Key,
+		Private:  !r.Public,
+	}
+	if len(r.Links.Self) > 0 {
+		repo.URL = r.Links.Self[0].Href
+	}
+	return repo
+}
+
+type bitbucketServerRef struct {
+	ID           string                     `json:"id"`
+	DisplayID    string                     `json:"displayId"`
+	LatestCommit string                     `json:"latestCommit"`
+	Repository   *bitbucketServerRepository `json:"repository"`
+}
+
+// bitbucketServerPayload covers the Bitbucket Server event payloads that have
+// a normalized form.
+type bitbucketServerPayload struct {
+	Actor       *bitbucketServerUser       `json:"actor"`
+	Repository  *bitbucketServerRepository `json:"repository"`
+	PullRequest *struct {
+		ID          int                `json:"id"`
+		Title       string             `json:"title"`
+		Description string             `json:"description"`
+		State       string             `json:"state"`
+		Draft       bool               `json:"draft"`
+		FromRef     bitbucketServerRef `json:"fromRef"`
+		ToRef       bitbucketServerRef `json:"toRef"`
+		Author      *struct {
+			User *bitbucketServerUser `json:"user"`
+		} `json:"author"`
+		Links struct {
+			Self []bitbucketLink `json:"self"`
+		} `json:"links"`
+	} `json:"pullRequest"`
+	Changes []struct {
+		Ref struct {
+			ID        string `json:"id"`
+			DisplayID string `json:"displayId"`
+			Type      string `json:"type"`
+		} `json:"ref"`
+		RefID    string `json:"refId"`
+		FromHash string `json:"fromHash"`
+		ToHash   string `json:"toHash"`
+		Type     string `json:"type"`
+	} `json:"changes"`
+	Comment *struct {
+		ID   flexID `json:"id"`
+		Text string `json:"text"`
+	} `json:"comment"`
+}
+
+func normalizeBitbucketServer(eventName string, raw []byte) *normalized.Event {
+	var payload bitbucketServerPayload
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return nil
+	}
+	var model *normalized.Event
+	repository := payload.Repository
+	switch {
+	case strings.HasPrefix(eventName, "pr:comment:"):
+		if payload.Comment == nil || payload.PullRequest == nil {
+			return nil
+		}
+		model = &normalized.Event{
+			Kind:   normalized.KindComment,
+			Action: strings.TrimPrefix(eventName, "pr:comment:"),
+			Comment: &normalized.Comment{
+				ID:     string(payload.Comment.ID),
+				Body:   payload.Comment.Text,
+				Target: normalized.KindPullRequest,
+				Number: payload.PullRequest.ID,
+			},
+		}
+		repository = payload.PullRequest.ToRef.Repository
+	case strings.HasPrefix(eventName, "pr:"):
+		src := payload.PullRequest
+		if src == nil {
+			return nil
+		}
+		pr := &normalized.PullRequest{
+			Number:       src.ID,
+			Title:        src.Title,
+			Body:         src.Description,
+			State:        bitbucketPullRequestState(src.State),
+			Draft:        src.Draft,
+			Merged:       src.State == "MERGED",
+			SourceBranch: src.FromRef.DisplayID,
+			TargetBranch: src.ToRef.DisplayID,
+			HeadSHA:      src.FromRef.LatestCommit,
+			BaseSHA:      src.ToRef.LatestCommit,
+		}
+		if len(src.Links.Self) > 0 {
+			pr.URL = src.Links.Self[0].Href
+		}
+		if src.Author != nil {
+			pr.Author = src.Author.User.actor()
+		}
+		action := strings.TrimPrefix(eventName, "pr:")
+		switch action {
+		case "modified", "from_ref_updated":
+			action = "updated"
+		case "declined":
+			action = "closed"
+		}
+		model = &normalized.Event{Kind: normalized.KindPullRequest, Action: action, PullRequest: pr}
+		repository = src.ToRef.Repository
+	case eventName == "repo:refs_changed":
+		if len(payload.Changes) == 0 {
+			return nil
+		}
+		change := payload.Changes[0]
+		ref := change.Ref.ID
+		if ref == "" {
+			ref = change.RefID
+		}
+		model = refModel(ref, change.FromHash, change.ToHash, change.Type == "ADD", change.Type == "DELETE", false, nil)
+	default:
+		return nil
+	}
+	model.Repository = repository.repository()
+	model.Actor = payload.Actor.actor()
+	return model
+}
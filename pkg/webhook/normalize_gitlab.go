@@ -0,0 +1,279 @@
+package webhook
+
+import (
+	"encoding/json"
+	"strings"
+
+	"githooks/pkg/normalized"
+)
+
+type gitlabProject struct {
+	ID                flexID `json:"id"`
+	Name              string `json:"name"`
+	PathWithNamespace string `json:"path_with_namespace"`
+	Namespace         string `json:"namespace"`
+	WebURL            string `json:"web_url"`
+	DefaultBranch     string `json:"default_branch"`
+	VisibilityLevel   int    `json:"visibility_level"`
+}
+
+func (p *gitlabProject) repository() *normalized.Repository {
+	if p == nil || p.PathWithNamespace == "" {
+		return nil
+	}
+	owner := p.Namespace
+	if index := strings.LastIndex(p.PathWithNamespace, "/"); index > 0 {
+		owner = p.PathWithNamespace[:index]
+	}
+	return &normalized.Repository{
+		ID:            string(p.ID),
+		Name:          p.Name,
+		FullName:      p.PathWithNamespace,
+		Owner:         owner,
+		URL:           p.WebURL,
+		DefaultBranch: p.DefaultBranch,
+		// Visibility level 0 is private; 10 (internal) and 20 (public) are not.
+		Private: p.VisibilityLevel == 0,
+	}
+}
+
+type gitlabUser struct {
+	ID       flexID `json:"id"`
+	Username string `json:"username"`
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+}
+
+// gitlabPayload covers the GitLab event payloads that have a normalized form.
+// The event is selected by object_kind rather than the X-Gitlab-Event header.
+type gitlabPayload struct {
+	ObjectKind string         `json:"object_kind"`
+	Project    *gitlabProject `json:"project"`
+	User       *gitlabUser    `json:"user"`
+
+	// Push and tag push events describe the user with top-level fields.
+	Ref          string `json:"ref"`
+	Before       string `json:"before"`
+	After        string `json:"after"`
+	UserID       flexID `json:"user_id"`
+	UserUsername string `json:"user_username"`
+	UserName     string `json:"user_name"`
+	UserEmail    string `json:"user_email"`
+	Commits      []struct {
+		ID      string `json:"id"`
+		Message string `json:"message"`
+		URL     string `json:"url"`
+		Author  struct {
+			Name string `json:"name"`
+		} `json:"author"`
+	} `json:"commits"`
+
+	ObjectAttributes json.RawMessage `json:"object_attributes"`
+	Labels           []struct {
+		Title string `json:"title"`
+	} `json:"labels"`
+	MergeRequest *struct {
+		IID int `json:"iid"`
+	} `json:"merge_request"`
+	Issue *struct {
+		IID int `json:"iid"`
+	} `json:"issue"`
+	Commit *struct {
+		ID string `json:"id"`
+	} `json:"commit"`
+
+	// Release events carry their attributes at the top level.
+	Tag         string `json:"tag"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	URL         string `json:"url"`
+	Action      string `json:"action"`
+}
+
+type gitlabMergeRequest struct {
+	IID            int    `json:"iid"`
+	Title          string `json:"title"`
+	Description    string `json:"description"`
+	State          string `json:"state"`
+	Action         string `json:"action"`
+	Draft          bool   `json:"draft"`
+	WorkInProgress bool   `json:"work_in_progress"`
+	URL            string `json:"url"`
+	SourceBranch   string `json:"source_branch"`
+	TargetBranch   string `json:"target_branch"`
+	MergeCommitSHA string `json:"merge_commit_sha"`
+	LastCommit     struct {
+		ID string `json:"id"`
+	} `json:"last_commit"`
+	DiffRefs *struct {
+		BaseSHA string `json:"base_sha"`
+		HeadSHA string `json:"head_sha"`
+	} `json:"diff_refs"`
+}
+
+type gitlabNote struct {
+	ID           flexID `json:"id"`
+	Note         string `json:"note"`
+	URL          string `json:"url"`
+	NoteableType string `json:"noteable_type"`
+	CommitID     string `json:"commit_id"`
+}
+
+type gitlabPipeline struct {
+	ID     flexID `json:"id"`
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Ref    string `json:"ref"`
+	SHA    string `json:"sha"`
+	URL    string `json:"url"`
+}
+
+func normalizeGitLab(raw []byte) *normalized.Event {
+	var payload gitlabPayload
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return nil
+	}
+	var model *normalized.Event
+	switch payload.ObjectKind {
+	case "merge_request":
+		var attrs gitlabMergeRequest
+		if err := json.Unmarshal(payload.ObjectAttributes, &attrs); err != nil {
+			return nil
+		}
+		pr := &normalized.PullRequest{
+			Number:       attrs.IID,
+			Title:        attrs.Title,
+			Body:         attrs.Description,
+			State:        gitlabMergeRequestState(attrs.State),
+			Draft:        attrs.Draft || attrs.WorkInProgress,
+			Merged:       attrs.State == "merged",
+			URL:          attrs.URL,
+			SourceBranch: attrs.SourceBranch,
+			TargetBranch: attrs.TargetBranch,
+			HeadSHA:      attrs.LastCommit.ID,
+			MergeSHA:     attrs.MergeCommitSHA,
+		}
+		if attrs.DiffRefs != nil {
+			pr.BaseSHA = attrs.DiffRefs.BaseSHA
+			if attrs.DiffRefs.HeadSHA != "" {
+				pr.HeadSHA = attrs.DiffRefs.HeadSHA
+			}
+		}
+		for _, label := range payload.Labels {
+			pr.Labels = append(pr.Labels, label.Title)
+		}
+		pr.Author = payload.User.actor()
+		model = &normalized.Event{Kind: normalized.KindPullRequest, Action: gitlabAction(attrs.Action), PullRequest: pr}
+	case "push", "tag_push":
+		commits := make([]normalized.Commit, 0, len(payload.Commits))
+		for _, commit := range payload.Commits {
+			commits = append(commits, normalized.Commit{SHA: commit.ID, Message: commit.Message, URL: commit.URL, Author: commit.Author.Name})
+		}
+		model = refModel(payload.Ref, payload.Before, payload.After, zeroSHA(payload.Before), zeroSHA(payload.After), false, commits)
+		if payload.UserID != "" || payload.UserUsername != "" {
+			model.Actor = &normalized.Actor{ID: string(payload.UserID), Login: payload.UserUsername, Name: payload.UserName, Email: payload.UserEmail}
+		}
+	case "note":
+		var attrs gitlabNote
+		if err := json.Unmarshal(payload.ObjectAttributes, &attrs); err != nil {
+			return nil
+		}
+		comment := &normalized.Comment{ID: string(attrs.ID), Body: attrs.Note, URL: attrs.URL}
+		switch {
+		case attrs.NoteableType == "MergeRequest" && payload.MergeRequest != nil:
+			comment.Target, comment.Number = normalized.KindPullRequest, payload.MergeRequest.IID
+		case attrs.NoteableType == "Issue" && payload.Issue != nil:
+			comment.Target, comment.Number = "issue", payload.Issue.IID
+		case attrs.NoteableType == "Commit":
+			comment.Target, comment.SHA = "commit", attrs.CommitID
+			if payload.Commit != nil {
+				comment.SHA = payload.Commit.ID
+			}
+		}
+		model = &normalized.Event{Kind: normalized.KindComment, Action: "created", Comment: comment}
+	case "pipeline":
+		var attrs gitlabPipeline
+		if err := json.Unmarshal(payload.ObjectAttributes, &attrs); err != nil {
+			return nil
+		}
+		pipeline := &normalized.Pipeline{
+			ID:     string(attrs.ID),
+			Name:   attrs.Name,
+			Status: gitlabPipelineStatus(attrs.Status),
+			Ref:    attrs.Ref,
+			SHA:    attrs.SHA,
+			URL:    attrs.URL,
+		}
+		if pipeline.URL == "" && payload.Project != nil && payload.Project.WebURL != "" && attrs.ID != "" {
+			pipeline.URL = payload.Project.WebURL + "/-/pipelines/" + string(attrs.ID)
+		}
+		model = &normalized.Event{Kind: normalized.KindPipeline, Pipeline: pipeline}
+	case "release":
+		model = &normalized.Event{
+			Kind:    normalized.KindRelease,
+			Action:  gitlabAction(payload.Action),
+			Release: &normalized.Release{Tag: payload.Tag, Name: payload.Name, Body: payload.Description, URL: payload.URL},
+		}
+	default:
+		return nil
+	}
+	model.Repository = payload.Project.repository()
+	if model.Actor == nil {
+		model.Actor = payload.User.actor()
+	}
+	return model
+}
+
+func (u *gitlabUser) actor() *normalized.Actor {
+	if u == nil || (u.ID == "" && u.Username == "") {
+		return nil
+	}
+	return &normalized.Actor{ID: string(u.ID), Login: u.Username, Name: u.Name, Email: u.Email}
+}
+
+func gitlabMergeRequestState(state string) string {
+	switch state {
+	case "merged":
+		return normalized.StateMerged
+	case "closed", "locked":
+		return normalized.StateClosed
+	default:
+		return normalized.StateOpen
+	}
+}
+
+func gitlabAction(action string) string {
+	switch action {
+	case "open":
+		return "opened"
+	case "create":
+		return "created"
+	case "close":
+		return "closed"
+	case "reopen":
+		return "reopened"
+	case "update":
+		return "updated"
+	case "merge":
+		return "merged"
+	case "delete":
+		return "deleted"
+	default:
+		return action
+	}
+}
+
+func gitlabPipelineStatus(status string) string {
+	switch status {
+	case "running":
+		return normalized.StatusRunning
+	case "success":
+		return normalized.StatusSuccess
+	case "failed":
+		return normalized.StatusFailure
+	case "canceled", "skipped":
+		return normalized.StatusCancelled
+	default:
+		return normalized.StatusPending
+	}
+}
@@ -0,0 +1,88 @@
+package webhook
+
+import (
+	"testing"
+
+	"githooks/internal"
+	"githooks/pkg/normalized"
+)
+
+// TestNormalizePullRequests tests that pull and merge requests from each provider share one model.
+func TestNormalizePullRequests(t *testing.T) {
+	cases := []struct {
+		provider string
+		name     string
+		payload  string
+	}{
+		{
+			provider: "github",
+			name:     "pull_request",
+			payload: `{"action":"closed","pull_request":{"number":7,"state":"closed","merged":true,"head":{"ref":"feature","sha":"abc"},"base":{"ref":"main","sha":"def"},"labels":[{"name":"bug"}],"user":{"id":1,"login":"octo"}},
+				"repository":{"id":10,"name":"api","full_name":"acme/api","owner":{"login":"acme"}},"sender":{"id":1,"login":"octo"}}`,
+		},
+		{
+			provider: "gitlab",
+			name:     "Merge Request Hook",
+			payload: `{"object_kind":"merge_request","user":{"id":1,"username":"octo"},"project":{"id":10,"name":"api","path_with_namespace":"acme/api"},
+				"object_attributes":{"iid":7,"state":"merged","action":"merge","source_branch":"feature","target_branch":"main","last_commit":{"id":"abc"}},"labels":[{"title":"bug"}]}`,
+		},
+		{
+			provider: "bitbucket",
+			name:     "pullrequest:fulfilled",
+			payload: `{"pullrequest":{"id":7,"state":"MERGED","source":{"branch":{"name":"feature"},"commit":{"hash":"abc"}},"destination":{"branch":{"name":"main"},"commit":{"hash":"def"}}},
+				"repository":{"uuid":"{10}","name":"api","full_name":"acme/api"},"actor":{"uuid":"{1}","nickname":"octo"}}`,
+		},
+	}
+	for _, tc := range cases {
+		model := normalizeEvent(internal.Event{Provider: tc.provider, Name: tc.name, RawPayload: []byte(tc.payload)})
+		if model == nil || model.PullRequest == nil {
+			t.Fatalf("%s: expected pull request model, got %+v", tc.provider, model)
+		}
+		pr := model.PullRequest
+		if model.Kind != normalized.KindPullRequest || model.Action != "merged" || model.Provider != tc.provider {
+			t.Fatalf("%s: unexpected kind/action/provider %q/%q/%q", tc.provider, model.Kind, model.Action, model.Provider)
+		}
+		if pr.Number != 7 || pr.State != normalized.StateMerged || !pr.Merged || pr.SourceBranch != "feature" || pr.TargetBranch != "main" || pr.HeadSHA != "abc" {
+			t.Fatalf("%s: unexpected pull request %+v", tc.provider, pr)
+		}
+		if model.Repository == nil || model.Repository.FullName != "acme/api" || model.Repository.Owner != "acme" {
+			t.Fatalf("%s: unexpected repository %+v", tc.provider, model.Repository)
+		}
+		if model.Actor == nil || model.Actor.Login != "octo" {
+			t.Fatalf("%s: unexpected actor %+v", tc.provider, model.Actor)
+		}
+	}
+}
+
+// TestNormalizePushAndTag tests that ref updates become push or tag models.
+func TestNormalizePushAndTag(t *testing.T) {
+	push := normalizeEvent(internal.Event{Provider: "gitlab", Name: "Push Hook", RawPayload: []byte(
+		`{"object_kind":"push","ref":"refs/heads/main","before":"0000000000000000000000000000000000000000","after":"abc","user_username":"octo","commits":[{"id":"abc","message":"init"}]}`,
+	)})
+	if push == nil || push.Push == nil || push.Push.Branch != "main" || !push.Push.Created || len(push.Push.Commits) != 1 {
+		t.Fatalf("unexpected push model %+v", push)
+	}
+	tag := normalizeEvent(internal.Event{Provider: "github", Name: "push", RawPayload: []byte(
+		`{"ref":"refs/tags/v1.0.0","before":"abc","after":"0000000000000000000000000000000000000000","deleted":true}`,
+	)})
+	if tag == nil || tag.Tag == nil || tag.Tag.Name != "v1.0.0" || !tag.Tag.Deleted || tag.Tag.SHA != "abc" {
+		t.Fatalf("unexpected tag model %+v", tag)
+	}
+}
+
+// TestNormalizedRules tests that rules can match on normalized fields.
+func TestNormalizedRules(t *testing.T) {
+	rules, err := internal.NewRuleEngine(internal.RulesConfig{
+		Rules: []internal.Rule{{When: `normalized.pull_request.state == "merged"`, Emit: internal.EmitList{"pr.merged"}}},
+	})
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+	payload := []byte(`{"object_kind":"merge_request","object_attributes":{"iid":1,"state":"merged"}}`)
+	rawObject, data := rawObjectAndFlatten(payload)
+	event := withNormalized(internal.Event{Provider: "gitlab", Name: "Merge Request Hook", RawPayload: payload, RawObject: rawObject, Data: data})
+	matches := rules.Evaluate(event)
+	if len(matches) != 1 || matches[0].Topic != "pr.merged" {
+		t.Fatalf("expected pr.merged match, got %v", matches)
+	}
+}
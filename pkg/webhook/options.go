@@ -0,0 +1,67 @@
+package webhook
+
+import "githooks/pkg/auth"
+
+// HandlerOption configures optional provider handler behavior.
+type HandlerOption func(*handlerOptions)
+
+type handlerOptions struct {
+	inbox     *Inbox
+	archive   *Archive
+	secrets   []auth.WebhookSecret
+	allowlist *IPAllowlist
+	limiter   *RateLimiter
+	// credentialHeaders are request headers that carry the provider's secret
+	// and are dropped from the archive. Set by the handler constructor.
+	credentialHeaders []string
+}
+
+// WithInbox makes the handler persist events to the inbox and acknowledge
+// immediately, leaving rule evaluation and publishing to the inbox dispatcher.
+func WithInbox(inbox *Inbox) HandlerOption {
+	return func(o *handlerOptions) {
+		o.inbox = inbox
+	}
+}
+
+// WithArchive records every delivery handled inline, with its matched topics
+// and publish outcome, in the delivery archive.
+func WithArchive(archive *Archive) HandlerOption {
+	return func(o *handlerOptions) {
+		o.archive = archive
+	}
+}
+
+// WithSecrets adds webhook secrets accepted alongside the provider's primary
+// secret. The GitHub, GitLab and Bitbucket handlers honor it.
+func WithSecrets(secrets ...auth.WebhookSecret) HandlerOption {
+	return func(o *handlerOptions) {
+		o.secrets = append(o.secrets, secrets...)
+	}
+}
+
+// WithIPAllowlist rejects requests whose source address is not in allowlist
+// before the payload is parsed.
+func WithIPAllowlist(allowlist *IPAllowlist) HandlerOption {
+	return func(o *handlerOptions) {
+		o.allowlist = allowlist
+	}
+}
+
+// WithRateLimiter applies limiter to every parsed event before it is enqueued
+// or published.
+func WithRateLimiter(limiter *RateLimiter) HandlerOption {
+	return func(o *handlerOptions) {
+		o.limiter = limiter
+	}
+}
+
+func applyHandlerOptions(opts []HandlerOption) handlerOptions {
+	var options handlerOptions
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&options)
+		}
+	}
+	return options
+}
@@ -0,0 +1,185 @@
+package webhook
+
+import (
+	"container/list"
+	"errors"
+	"expvar"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"githooks/internal"
+)
+
+// Rate limit bucket keys.
+const (
+	RateLimitKeyProvider   = "provider"
+	RateLimitKeyStateID    = "state_id"
+	RateLimitKeyRepository = "repository"
+)
+
+// Over-limit behaviors.
+const (
+	// RateLimitModeReject answers 429 so the provider retries later.
+	RateLimitModeReject = "reject"
+	// RateLimitModeShed acknowledges the delivery and drops it.
+	RateLimitModeShed = "shed"
+	// RateLimitModeSpill publishes the event to SpillTopic instead of the rule matches.
+	RateLimitModeSpill = "spill"
+)
+
+// rateLimitedError is returned by emitEvent when a delivery is rejected.
+type rateLimitedError struct {
+	retryAfter time.Duration
+}
+
+func (e *rateLimitedError) Error() string {
+	return "rate limited"
+}
+
+func (e *rateLimitedError) retryAfterSeconds() string {
+	seconds := int64(math.Ceil(e.retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	return strconv.FormatInt(seconds, 10)
+}
+
+// rateLimitStats counts rate limit outcomes as "<outcome>" and "<provider>.<outcome>".
+var rateLimitStats = expvar.NewMap("githooks_ratelimit")
+
+// RateLimitConfig configures inbound token-bucket rate limiting.
+type RateLimitConfig struct {
+	Key        string
+	Mode       string
+	SpillTopic string
+	// Rate is the sustained number of deliveries per second per bucket.
+	Rate  float64
+	Burst int
+	// MaxKeys bounds the number of buckets kept in memory; the least recently
+	// used bucket is evicted first.
+	MaxKeys int
+}
+
+// RateLimiter applies a token bucket per provider, tenant or repository.
+type RateLimiter struct {
+	cfg RateLimitConfig
+
+	mu      sync.Mutex
+	buckets map[string]*list.Element
+	order   *list.List
+}
+
+type rateBucket struct {
+	key     string
+	limiter *rate.Limiter
+}
+
+// NewRateLimiter validates cfg and creates a rate limiter.
+func NewRateLimiter(cfg RateLimitConfig) (*RateLimiter, error) {
+	switch cfg.Key {
+	case "":
+		cfg.Key = RateLimitKeyProvider
+	case RateLimitKeyProvider, RateLimitKeyStateID, RateLimitKeyRepository:
+	default:
+		return nil, fmt.Errorf("unsupported rate limit key %q", cfg.Key)
+	}
+	switch cfg.Mode {
+	case "":
+		cfg.Mode = RateLimitModeReject
+	case RateLimitModeReject, RateLimitModeShed:
+	case RateLimitModeSpill:
+		if cfg.SpillTopic == "" {
+			return nil, errors.New("rate limit spill mode requires spill_topic")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported rate limit mode %q", cfg.Mode)
+	}
+	if cfg.Rate <= 0 {
+		return nil, errors.New("rate limit rate must be positive")
+	}
+	if cfg.Burst <= 0 {
+		cfg.Burst = 1
+	}
+	if cfg.MaxKeys <= 0 {
+		cfg.MaxKeys = 10000
+	}
+	return &RateLimiter{
+		cfg:     cfg,
+		buckets: make(map[string]*list.Element),
+		order:   list.New(),
+	}, nil
+}
+
+// Allow takes a token from the event's bucket and records the outcome.
+func (l *RateLimiter) Allow(event internal.Event) bool {
+	return l.allowAt(event, time.Now())
+}
+
+func (l *RateLimiter) allowAt(event internal.Event, now time.Time) bool {
+	key := l.bucketKey(event)
+	l.mu.Lock()
+	var bucket *rateBucket
+	if elem, ok := l.buckets[key]; ok {
+		l.order.MoveToFront(elem)
+		bucket = elem.Value.(*rateBucket)
+	} else {
+		bucket = &rateBucket{key: key, limiter: rate.NewLimiter(rate.Limit(l.cfg.Rate), l.cfg.Burst)}
+		l.buckets[key] = l.order.PushFront(bucket)
+		for l.order.Len() > l.cfg.MaxKeys {
+			oldest := l.order.Back()
+			l.order.Remove(oldest)
+			delete(l.buckets, oldest.Value.(*rateBucket).key)
+		}
+	}
+	allowed := bucket.limiter.AllowN(now, 1)
+	l.mu.Unlock()
+
+	outcome := "allowed"
+	if !allowed {
+		outcome = l.cfg.Mode
+	}
+	rateLimitStats.Add(outcome, 1)
+	rateLimitStats.Add(event.Provider+"."+outcome, 1)
+	return allowed
+}
+
+// retryAfter is the time for a bucket to earn one token back.
+func (l *RateLimiter) retryAfter() time.Duration {
+	return time.Duration(float64(time.Second) / l.cfg.Rate)
+}
+
+func (l *RateLimiter) bucketKey(event internal.Event) string {
+	switch l.cfg.Key {
+	case RateLimitKeyStateID:
+		return event.Provider + "|" + event.StateID
+	case RateLimitKeyRepository:
+		return event.Provider + "|" + repositoryName(event)
+	default:
+		return event.Provider
+	}
+}
+
+// repositoryName extracts the repository full name from a provider payload.
+func repositoryName(event internal.Event) string {
+	for _, key := range []string{
+		"repository.full_name",
+		"project.path_with_namespace",
+		"resource.repository.name",
+	} {
+		if value, ok := event.Data[key].(string); ok && value != "" {
+			return value
+		}
+	}
+	project, _ := event.Data["repository.project.key"].(string)
+	slug, _ := event.Data["repository.slug"].(string)
+	if project != "" && slug != "" {
+		return strings.ToLower(project) + "/" + slug
+	}
+	return ""
+}
@@ -0,0 +1,98 @@
+package webhook
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"githooks/internal"
+)
+
+// TestRateLimiterKeys tests that buckets are kept per tenant and refill over time.
+func TestRateLimiterKeys(t *testing.T) {
+	limiter, err := NewRateLimiter(RateLimitConfig{Key: RateLimitKeyStateID, Rate: 1, Burst: 1})
+	if err != nil {
+		t.Fatalf("limiter: %v", err)
+	}
+	now := time.Now()
+	first := internal.Event{Provider: "github", StateID: "acme"}
+	second := internal.Event{Provider: "github", StateID: "globex"}
+
+	if !limiter.allowAt(first, now) {
+		t.Fatalf("expected first delivery to be allowed")
+	}
+	if limiter.allowAt(first, now) {
+		t.Fatalf("expected second delivery for the same tenant to be limited")
+	}
+	if !limiter.allowAt(second, now) {
+		t.Fatalf("expected other tenant to have its own bucket")
+	}
+	if !limiter.allowAt(first, now.Add(time.Second)) {
+		t.Fatalf("expected bucket to refill")
+	}
+}
+
+// TestRateLimiterRepositoryKey tests repository names from each provider payload shape.
+func TestRateLimiterRepositoryKey(t *testing.T) {
+	cases := map[string]map[string]interface{}{
+		"acme/api": {"repository.full_name": "acme/api"},
+		"acme/web": {"project.path_with_namespace": "acme/web"},
+		"ops/deploy": {
+			"repository.project.key": "OPS",
+			"repository.slug":        "deploy",
+		},
+	}
+	for want, data := range cases {
+		if got := repositoryName(internal.Event{Data: data}); got != want {
+			t.Fatalf("expected %q, got %q", want, got)
+		}
+	}
+}
+
+// TestRateLimitModes tests the reject, shed and spill over-limit behaviors.
+func TestRateLimitModes(t *testing.T) {
+	rules, err := internal.NewRuleEngine(internal.RulesConfig{
+		Rules: []internal.Rule{{When: `action == "opened"`, Emit: internal.EmitList{"pr.opened"}}},
+	})
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+	cases := []struct {
+		mode   string
+		status int
+		topics []string
+	}{
+		{mode: RateLimitModeReject, status: http.StatusTooManyRequests, topics: []string{"pr.opened"}},
+		{mode: RateLimitModeShed, status: http.StatusOK, topics: []string{"pr.opened"}},
+		{mode: RateLimitModeSpill, status: http.StatusOK, topics: []string{"pr.opened", "webhooks.overflow"}},
+	}
+	for _, tc := range cases {
+		limiter, err := NewRateLimiter(RateLimitConfig{Mode: tc.mode, SpillTopic: "webhooks.overflow", Rate: 0.5, Burst: 1})
+		if err != nil {
+			t.Fatalf("%s: limiter: %v", tc.mode, err)
+		}
+		publisher := &recordingPublisher{}
+		handler, err := NewGiteaHandler("", rules, publisher, nil, 0, false, nil, WithRateLimiter(limiter))
+		if err != nil {
+			t.Fatalf("%s: handler: %v", tc.mode, err)
+		}
+		var rec *httptest.ResponseRecorder
+		for i := 0; i < 2; i++ {
+			req := httptest.NewRequest(http.MethodPost, "/webhooks/gitea", strings.NewReader(`{"action":"opened"}`))
+			req.Header.Set("X-Gitea-Event", "pull_request")
+			rec = httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+		}
+		if rec.Code != tc.status {
+			t.Fatalf("%s: expected status %d, got %d", tc.mode, tc.status, rec.Code)
+		}
+		if tc.mode == RateLimitModeReject && rec.Header().Get("Retry-After") != "2" {
+			t.Fatalf("%s: expected Retry-After 2, got %q", tc.mode, rec.Header().Get("Retry-After"))
+		}
+		if strings.Join(publisher.topics, ",") != strings.Join(tc.topics, ",") {
+			t.Fatalf("%s: expected topics %v, got %v", tc.mode, tc.topics, publisher.topics)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package webhook
+
+import (
+	"fmt"
+	"time"
+
+	"githooks/pkg/auth"
+)
+
+type webhookSecret struct {
+	id       string
+	value    string
+	notAfter time.Time
+}
+
+// secretSet is the list of secrets a handler accepts. The provider's primary
+// secret never expires; rotated secrets stop matching after their NotAfter.
+type secretSet []webhookSecret
+
+func newSecretSet(primary string, extra []auth.WebhookSecret) secretSet {
+	var set secretSet
+	if primary != "" {
+		set = append(set, webhookSecret{id: "secret", value: primary})
+	}
+	for i, secret := range extra {
+		if secret.Value == "" {
+			continue
+		}
+		id := secret.ID
+		if id == "" {
+			id = fmt.Sprintf("secrets[%d]", i)
+		}
+		set = append(set, webhookSecret{id: id, value: secret.Value, notAfter: secret.NotAfter})
+	}
+	return set
+}
+
+// configured reports whether verification is required. A set whose secrets
+// have all expired is still configured and rejects every request.
+func (s secretSet) configured() bool {
+	return len(s) > 0
+}
+
+// match returns the id of the first secret valid at now that verify accepts.
+func (s secretSet) match(now time.Time, verify func(secret string) bool) (string, bool) {
+	for _, secret := range s {
+		if !secret.notAfter.IsZero() && now.After(secret.notAfter) {
+			continue
+		}
+		if verify(secret.value) {
+			return secret.id, true
+		}
+	}
+	return "", false
+}
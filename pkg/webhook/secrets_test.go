@@ -0,0 +1,85 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"githooks/internal"
+	"githooks/pkg/auth"
+)
+
+type nopPublisher struct{}
+
+func (nopPublisher) Publish(ctx context.Context, topic string, event internal.Event) error {
+	return nil
+}
+
+func (nopPublisher) PublishForDrivers(ctx context.Context, topic string, event internal.Event, drivers []string) error {
+	return nil
+}
+
+func (nopPublisher) Close() error { return nil }
+
+// TestSecretSetMatch tests that expired secrets are skipped and the matching id is returned.
+func TestSecretSetMatch(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	set := newSecretSet("current", []auth.WebhookSecret{
+		{ID: "expired", Value: "old", NotAfter: now.Add(-time.Hour)},
+		{Value: "next", NotAfter: now.Add(time.Hour)},
+	})
+	equals := func(value string) func(string) bool {
+		return func(secret string) bool { return secret == value }
+	}
+
+	if id, ok := set.match(now, equals("current")); !ok || id != "secret" {
+		t.Fatalf("expected primary secret to match, got %q %v", id, ok)
+	}
+	if id, ok := set.match(now, equals("next")); !ok || id != "secrets[1]" {
+		t.Fatalf("expected rotated secret to match, got %q %v", id, ok)
+	}
+	if _, ok := set.match(now, equals("old")); ok {
+		t.Fatalf("expected expired secret to be rejected")
+	}
+}
+
+// TestGitHubHandlerRotatedSecret tests that GitHub deliveries signed with any active secret are accepted.
+func TestGitHubHandlerRotatedSecret(t *testing.T) {
+	rules, err := internal.NewRuleEngine(internal.RulesConfig{})
+	if err != nil {
+		t.Fatalf("rules: %v", err)
+	}
+	handler, err := NewGitHubHandler("current", rules, nopPublisher{}, nil, 0, false, nil, nil, WithSecrets(
+		auth.WebhookSecret{ID: "previous", Value: "old", NotAfter: time.Now().Add(time.Hour)},
+		auth.WebhookSecret{ID: "retired", Value: "ancient", NotAfter: time.Now().Add(-time.Hour)},
+	))
+	if err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+
+	body := `{"ref":"refs/heads/main"}`
+	cases := map[string]int{
+		"current": http.StatusOK,
+		"old":     http.StatusOK,
+		"ancient": http.StatusBadRequest,
+		"wrong":   http.StatusBadRequest,
+	}
+	for secret, want := range cases {
+		mac := hmac.New(sha256.New, []byte(secret))
+		_, _ = mac.Write([]byte(body))
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/github", strings.NewReader(body))
+		req.Header.Set("X-GitHub-Event", "push")
+		req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != want {
+			t.Fatalf("secret %q: expected status %d, got %d", secret, want, rec.Code)
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package worker
 
 import (
+	"githooks/pkg/providers/azuredevops"
 	"githooks/pkg/providers/bitbucket"
+	"githooks/pkg/providers/gitea"
 	"githooks/pkg/providers/github"
 	"githooks/pkg/providers/gitlab"
 )
@@ -32,3 +34,21 @@ func BitbucketClient(evt *Event) (*bitbucket.Client, bool) {
 	client, ok := evt.Client.(*bitbucket.Client)
 	return client, ok
 }
+
+// GiteaClient returns the Gitea client from an event if available.
+func GiteaClient(evt *Event) (*gitea.Client, bool) {
+	if evt == nil {
+		return nil, false
+	}
+	client, ok := evt.Client.(*gitea.Client)
+	return client, ok
+}
+
+// AzureDevOpsClient returns the Azure DevOps connection from an event if available.
+func AzureDevOpsClient(evt *Event) (*azuredevops.Client, bool) {
+	if evt == nil {
+		return nil, false
+	}
+	client, ok := evt.Client.(*azuredevops.Client)
+	return client, ok
+}
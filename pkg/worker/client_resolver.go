@@ -8,7 +8,9 @@ import (
 	"strconv"
 	"strings"
 
+	"githooks/pkg/auth"
 	"githooks/pkg/providers/bitbucket"
+	"githooks/pkg/providers/gitea"
 	"githooks/pkg/providers/gitlab"
 )
 
@@ -57,7 +59,7 @@ func ResolveProviderClientWithClient(ctx context.Context, evt *Event, client *In
 		if record == nil || record.AccessToken == "" {
 			return nil, errors.New("gitlab access token missing")
 		}
-		return gitlab.NewClient(record.AccessToken)
+		return gitlab.NewTokenClient(auth.ProviderConfig{}, record.AccessToken)
 	case "bitbucket":
 		record, err := ResolveInstallation(ctx, evt, client)
 		if err != nil {
@@ -66,7 +68,16 @@ func ResolveProviderClientWithClient(ctx context.Context, evt *Event, client *In
 		if record == nil || record.AccessToken == "" {
 			return nil, errors.New("bitbucket access token missing")
 		}
-		return bitbucket.NewOAuthbearerToken(record.AccessToken), nil
+		return bitbucket.NewTokenClient(auth.ProviderConfig{}, record.AccessToken)
+	case "gitea":
+		record, err := ResolveInstallation(ctx, evt, client)
+		if err != nil {
+			return nil, err
+		}
+		if record == nil || record.AccessToken == "" {
+			return nil, errors.New("gitea access token missing")
+		}
+		return gitea.NewTokenClient(auth.ProviderConfig{}, record.AccessToken)
 	default:
 		return nil, fmt.Errorf("unsupported provider: %s", evt.Provider)
 	}
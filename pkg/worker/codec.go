@@ -1,7 +1,10 @@
 package worker
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"path"
+	"strings"
 
 	"github.com/ThreeDotsLabs/watermill/message"
 )
@@ -13,7 +16,9 @@ type Codec interface {
 }
 
 // DefaultCodec is the default implementation of the Codec interface.
-// It decodes a JSON payload into an Event.
+// It decodes a JSON payload into an Event. CloudEvents messages are unwrapped
+// transparently when they carry binary-mode ce_*/ce-* metadata or the
+// application/cloudevents+json content type; other bodies are left as is.
 type DefaultCodec struct{}
 
 // envelope is used to unmarshal the basic event properties.
@@ -25,36 +30,40 @@ type envelope struct {
 
 // Decode unmarshals a Watermill message into an Event.
 func (DefaultCodec) Decode(topic string, msg *message.Message) (*Event, error) {
-	var env envelope
-	if err := json.Unmarshal(msg.Payload, &env); err != nil {
-		return nil, err
-	}
-
 	metadata := make(map[string]string, len(msg.Metadata))
 	for key, value := range msg.Metadata {
 		metadata[key] = value
 	}
+	body, err := decodeCloudEvent(msg.Payload, metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	var env envelope
+	if err := json.Unmarshal(body, &env); err != nil {
+		return nil, err
+	}
 
 	provider := env.Provider
 	if provider == "" {
-		provider = msg.Metadata.Get("provider")
+		provider = metadata["provider"]
 	}
 	eventName := env.Name
 	if eventName == "" {
-		eventName = msg.Metadata.Get("event")
+		eventName = metadata["event"]
 	}
 
 	normalized := env.Data
 	if normalized == nil {
 		var raw interface{}
-		if err := json.Unmarshal(msg.Payload, &raw); err == nil {
+		if err := json.Unmarshal(body, &raw); err == nil {
 			if object, ok := raw.(map[string]interface{}); ok {
 				normalized = object
 			}
 		}
 	}
 
-	payload := json.RawMessage(msg.Payload)
+	payload := json.RawMessage(body)
 	return &Event{
 		Provider:   provider,
 		Type:       eventName,
@@ -62,5 +71,93 @@ func (DefaultCodec) Decode(topic string, msg *message.Message) (*Event, error) {
 		Metadata:   metadata,
 		Payload:    payload,
 		Normalized: normalized,
+		Model:      decodeModel(metadata),
 	}, nil
 }
+
+const cloudEventsContentType = "application/cloudevents+json"
+
+// cloudEvent holds the structured-mode CloudEvents attributes githooks sets.
+type cloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject"`
+	Time            string          `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	StateID         string          `json:"stateid"`
+	DeliveryID      string          `json:"deliveryid"`
+	Data            json.RawMessage `json:"data"`
+	DataBase64      string          `json:"data_base64"`
+}
+
+// decodeCloudEvent returns the event data of a CloudEvents message and fills
+// metadata with its attributes as ce_* keys and the githooks metadata keys
+// (provider, event, request_id, state_id, delivery_id) when they are missing.
+// Other messages are returned unchanged.
+func decodeCloudEvent(payload []byte, metadata map[string]string) ([]byte, error) {
+	if binaryCloudEvent(metadata) {
+		setCloudEventMetadata(metadata, metadata["ce_source"], metadata["ce_type"], metadata["ce_id"], metadata["ce_stateid"], metadata["ce_deliveryid"])
+		return payload, nil
+	}
+	contentType := metadata["content-type"]
+	if contentType == "" {
+		contentType = metadata["Content-Type"]
+	}
+	if !strings.HasPrefix(contentType, cloudEventsContentType) {
+		return payload, nil
+	}
+	var ce cloudEvent
+	if err := json.Unmarshal(payload, &ce); err != nil {
+		return nil, err
+	}
+	attributes := map[string]string{
+		"specversion":     ce.SpecVersion,
+		"id":              ce.ID,
+		"source":          ce.Source,
+		"type":            ce.Type,
+		"subject":         ce.Subject,
+		"time":            ce.Time,
+		"datacontenttype": ce.DataContentType,
+		"stateid":         ce.StateID,
+		"deliveryid":      ce.DeliveryID,
+	}
+	for name, value := range attributes {
+		if value != "" {
+			metadata["ce_"+name] = value
+		}
+	}
+	setCloudEventMetadata(metadata, ce.Source, ce.Type, ce.ID, ce.StateID, ce.DeliveryID)
+	if ce.DataBase64 != "" {
+		return base64.StdEncoding.DecodeString(ce.DataBase64)
+	}
+	return ce.Data, nil
+}
+
+// binaryCloudEvent reports whether metadata carries binary-mode CloudEvents
+// attributes. Attributes set as ce-* headers (HTTP binding) are copied to the
+// ce_* keys.
+func binaryCloudEvent(metadata map[string]string) bool {
+	for key, value := range metadata {
+		if name, ok := strings.CutPrefix(strings.ToLower(key), "ce-"); ok && metadata["ce_"+name] == "" {
+			metadata["ce_"+name] = value
+		}
+	}
+	return metadata["ce_specversion"] != ""
+}
+
+func setCloudEventMetadata(metadata map[string]string, source, eventType, id, stateID, deliveryID string) {
+	defaults := map[string]string{
+		"provider":    path.Base(source),
+		"event":       eventType,
+		"request_id":  id,
+		"state_id":    stateID,
+		"delivery_id": deliveryID,
+	}
+	for key, value := range defaults {
+		if metadata[key] == "" && value != "" && value != "." && value != "/" {
+			metadata[key] = value
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package worker
+
+import (
+	"testing"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+)
+
+// TestDefaultCodecCloudEvents tests that binary and structured CloudEvents decode like plain messages.
+func TestDefaultCodecCloudEvents(t *testing.T) {
+	binary := message.NewMessage("1", []byte(`{"action":"opened"}`))
+	binary.Metadata.Set("ce_specversion", "1.0")
+	binary.Metadata.Set("ce_id", "req-1")
+	binary.Metadata.Set("ce_source", "githooks/gitlab")
+	binary.Metadata.Set("ce_type", "merge_request")
+	binary.Metadata.Set("ce_stateid", "acme")
+	binary.Metadata.Set("ce_deliveryid", "delivery-1")
+
+	header := message.NewMessage("3", []byte(`{"action":"opened"}`))
+	header.Metadata.Set("Ce-Specversion", "1.0")
+	header.Metadata.Set("Ce-Id", "req-3")
+	header.Metadata.Set("Ce-Source", "githooks/gitlab")
+	header.Metadata.Set("Ce-Type", "merge_request")
+
+	structured := message.NewMessage("2", []byte(`{"specversion":"1.0","id":"req-2","source":"githooks/gitlab","type":"merge_request","subject":"mr.opened","stateid":"acme","data":{"action":"opened"}}`))
+	structured.Metadata.Set("content-type", "application/cloudevents+json")
+
+	encoded := message.NewMessage("4", []byte(`{"specversion":"1.0","id":"req-4","source":"githooks/gitlab","type":"merge_request","data_base64":"eyJhY3Rpb24iOiJvcGVuZWQifQ=="}`))
+	encoded.Metadata.Set("content-type", "application/cloudevents+json")
+
+	for name, msg := range map[string]*message.Message{"binary": binary, "header": header, "structured": structured, "data_base64": encoded} {
+		event, err := DefaultCodec{}.Decode("mr.opened", msg)
+		if err != nil {
+			t.Fatalf("%s: decode: %v", name, err)
+		}
+		if event.Provider != "gitlab" || event.Type != "merge_request" {
+			t.Fatalf("%s: unexpected provider/type %q/%q", name, event.Provider, event.Type)
+		}
+		if string(event.Payload) != `{"action":"opened"}` {
+			t.Fatalf("%s: expected data as payload, got %s", name, event.Payload)
+		}
+		if event.Normalized["action"] != "opened" {
+			t.Fatalf("%s: expected normalized data, got %v", name, event.Normalized)
+		}
+		if event.Metadata["ce_id"] == "" || event.Metadata["request_id"] == "" {
+			t.Fatalf("%s: expected cloudevents metadata, got %v", name, event.Metadata)
+		}
+	}
+	event, err := DefaultCodec{}.Decode("mr.opened", structured)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if event.Metadata["state_id"] != "acme" || event.Metadata["ce_subject"] != "mr.opened" {
+		t.Fatalf("expected structured extensions in metadata, got %v", event.Metadata)
+	}
+	event, err = DefaultCodec{}.Decode("mr.opened", binary)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if event.Metadata["state_id"] != "acme" || event.Metadata["delivery_id"] != "delivery-1" {
+		t.Fatalf("expected binary extensions in metadata, got %v", event.Metadata)
+	}
+}
+
+// TestDefaultCodecPlainCloudEventShape tests that a plain JSON body with CloudEvents-like fields is not unwrapped.
+func TestDefaultCodecPlainCloudEventShape(t *testing.T) {
+	body := `{"specversion":"1.0","id":"42","source":"ci","type":"build","data":{"status":"ok"}}`
+	msg := message.NewMessage("1", []byte(body))
+	msg.Metadata.Set("provider", "generic")
+	event, err := DefaultCodec{}.Decode("ci.build", msg)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if string(event.Payload) != body {
+		t.Fatalf("expected payload to be left as is, got %s", event.Payload)
+	}
+	if event.Provider != "generic" || event.Metadata["ce_id"] != "" {
+		t.Fatalf("expected no cloudevents metadata, got provider=%q metadata=%v", event.Provider, event.Metadata)
+	}
+}
+
+// TestEventTypedAccessors tests that the normalized metadata is exposed through typed accessors.
+func TestEventTypedAccessors(t *testing.T) {
+	msg := message.NewMessage("1", []byte(`{}`))
+	msg.Metadata.Set("provider", "gitlab")
+	msg.Metadata.Set("normalized", `{"kind":"pull_request","provider":"gitlab","repository":{"full_name":"acme/api"},"pull_request":{"number":3,"state":"open"}}`)
+
+	event, err := DefaultCodec{}.Decode("mr", msg)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if pr := event.PullRequest(); pr == nil || pr.Number != 3 || pr.State != "open" {
+		t.Fatalf("unexpected pull request %+v", pr)
+	}
+	if repo := event.Repository(); repo == nil || repo.FullName != "acme/api" {
+		t.Fatalf("unexpected repository %+v", repo)
+	}
+	if event.Push() != nil {
+		t.Fatalf("expected no push model")
+	}
+}
@@ -0,0 +1,271 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	wmsql "github.com/ThreeDotsLabs/watermill-sql/pkg/sql"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/ThreeDotsLabs/watermill/pubsub/gochannel"
+)
+
+// Metadata keys describing why a message was dead-lettered.
+const (
+	DeadLetterErrorKey    = "dead_letter_error"
+	DeadLetterHandlerKey  = "dead_letter_handler"
+	DeadLetterTopicKey    = "dead_letter_topic"
+	DeadLetterAttemptsKey = "dead_letter_attempts"
+	DeadLetterTimeKey     = "dead_letter_at"
+)
+
+// DeadLetter is a failed message stored on a dead letter topic.
+type DeadLetter struct {
+	ID       string
+	Topic    string
+	Handler  string
+	Error    string
+	Attempts int
+	FailedAt time.Time
+	Payload  []byte
+	Metadata map[string]string
+}
+
+// DeadLetterStore lists dead letters and requeues them to their original topic.
+type DeadLetterStore interface {
+	List(ctx context.Context, limit int) ([]DeadLetter, error)
+	Requeue(ctx context.Context, id string) error
+}
+
+// deadLetterMessage copies msg under a new UUID with the failure context set.
+func deadLetterMessage(msg *message.Message, topic, handler string, attempts int, err error) *message.Message {
+	out := redelivery(msg, attempts)
+	out.Metadata.Set(DeadLetterTopicKey, topic)
+	out.Metadata.Set(DeadLetterHandlerKey, handler)
+	out.Metadata.Set(DeadLetterAttemptsKey, strconv.Itoa(attempts))
+	out.Metadata.Set(DeadLetterTimeKey, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		out.Metadata.Set(DeadLetterErrorKey, err.Error())
+	}
+	return out
+}
+
+func newDeadLetter(msg *message.Message) DeadLetter {
+	attempts, _ := strconv.Atoi(msg.Metadata.Get(DeadLetterAttemptsKey))
+	failedAt, _ := time.Parse(time.RFC3339, msg.Metadata.Get(DeadLetterTimeKey))
+	metadata := make(map[string]string, len(msg.Metadata))
+	for key, value := range msg.Metadata {
+		metadata[key] = value
+	}
+	return DeadLetter{
+		ID:       msg.UUID,
+		Topic:    msg.Metadata.Get(DeadLetterTopicKey),
+		Handler:  msg.Metadata.Get(DeadLetterHandlerKey),
+		Error:    msg.Metadata.Get(DeadLetterErrorKey),
+		Attempts: attempts,
+		FailedAt: failedAt,
+		Payload:  []byte(msg.Payload),
+		Metadata: metadata,
+	}
+}
+
+// requeueMessage rebuilds the original message from a dead letter, resetting
+// its attempt count.
+func requeueMessage(letter DeadLetter) (*message.Message, error) {
+	if letter.Topic == "" {
+		return nil, fmt.Errorf("dead letter %s has no original topic", letter.ID)
+	}
+	msg := message.NewMessage(watermill.NewUUID(), letter.Payload)
+	for key, value := range letter.Metadata {
+		if strings.HasPrefix(key, "dead_letter_") || key == AttemptMetadataKey {
+			continue
+		}
+		msg.Metadata.Set(key, value)
+	}
+	return msg, nil
+}
+
+// GoChannelDeadLetterStore keeps the dead letters published to a gochannel
+// topic in memory.
+type GoChannelDeadLetterStore struct {
+	pubsub *gochannel.GoChannel
+
+	mu      sync.Mutex
+	letters []DeadLetter
+}
+
+// NewGoChannelDeadLetterStore subscribes to topic on pubsub and records every
+// dead letter until ctx is canceled. Requeued messages are published back to
+// pubsub.
+func NewGoChannelDeadLetterStore(ctx context.Context, pubsub *gochannel.GoChannel, topic string) (*GoChannelDeadLetterStore, error) {
+	if pubsub == nil || topic == "" {
+		return nil, errors.New("gochannel and dead letter topic are required")
+	}
+	msgs, err := pubsub.Subscribe(ctx, topic)
+	if err != nil {
+		return nil, err
+	}
+	store := &GoChannelDeadLetterStore{pubsub: pubsub}
+	go func() {
+		for msg := range msgs {
+			store.mu.Lock()
+			store.letters = append(store.letters, newDeadLetter(msg))
+			store.mu.Unlock()
+			msg.Ack()
+		}
+	}()
+	return store, nil
+}
+
+// List returns up to limit dead letters, oldest first. A limit of zero returns all.
+func (s *GoChannelDeadLetterStore) List(ctx context.Context, limit int) ([]DeadLetter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if limit <= 0 || limit > len(s.letters) {
+		limit = len(s.letters)
+	}
+	out := make([]DeadLetter, limit)
+	copy(out, s.letters[:limit])
+	return out, nil
+}
+
+// Requeue publishes the dead letter id to its original topic and removes it.
+// The lock is released while publishing: a gochannel configured with
+// BlockPublishUntilSubscriberAck may wait on the subscriber goroutine, which
+// needs the lock to record letters.
+func (s *GoChannelDeadLetterStore) Requeue(ctx context.Context, id string) error {
+	letter, ok := s.find(id)
+	if !ok {
+		return fmt.Errorf("dead letter not found: %s", id)
+	}
+	msg, err := requeueMessage(letter)
+	if err != nil {
+		return err
+	}
+	if err := s.pubsub.Publish(letter.Topic, msg); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.letters {
+		if s.letters[i].ID == id {
+			s.letters = append(s.letters[:i], s.letters[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (s *GoChannelDeadLetterStore) find(id string) (DeadLetter, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, letter := range s.letters {
+		if letter.ID == id {
+			return letter, true
+		}
+	}
+	return DeadLetter{}, false
+}
+
+// SQLDeadLetterStore reads dead letters from the Watermill SQL messages table
+// of a dead letter topic.
+type SQLDeadLetterStore struct {
+	db        *sql.DB
+	publisher message.Publisher
+	table     string
+	offset    string
+	postgres  bool
+}
+
+// NewSQLDeadLetterStore creates a store for topic using the default Watermill
+// schema of dialect (postgres or mysql). Requeued messages are published with
+// publisher.
+func NewSQLDeadLetterStore(db *sql.DB, dialect, topic string, publisher message.Publisher) (*SQLDeadLetterStore, error) {
+	if db == nil || publisher == nil || topic == "" {
+		return nil, errors.New("db, publisher and dead letter topic are required")
+	}
+	schema, _, err := sqlAdapters(dialect)
+	if err != nil {
+		return nil, err
+	}
+	store := &SQLDeadLetterStore{db: db, publisher: publisher}
+	switch schema := schema.(type) {
+	case wmsql.DefaultPostgreSQLSchema:
+		store.table, store.offset, store.postgres = schema.MessagesTable(topic), `"offset"`, true
+	case wmsql.DefaultMySQLSchema:
+		store.table, store.offset = schema.MessagesTable(topic), "`offset`"
+	}
+	return store, nil
+}
+
+// List returns up to limit dead letters, oldest first. A limit of zero returns all.
+func (s *SQLDeadLetterStore) List(ctx context.Context, limit int) ([]DeadLetter, error) {
+	query := "SELECT uuid, payload, metadata FROM " + s.table + " ORDER BY " + s.offset
+	if limit > 0 {
+		query += " LIMIT " + strconv.Itoa(limit)
+	}
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var letters []DeadLetter
+	for rows.Next() {
+		var uuid string
+		var payload, metadata []byte
+		if err := rows.Scan(&uuid, &payload, &metadata); err != nil {
+			return nil, err
+		}
+		msg := message.NewMessage(uuid, payload)
+		if len(metadata) > 0 {
+			if err := json.Unmarshal(metadata, &msg.Metadata); err != nil {
+				return nil, fmt.Errorf("decode dead letter %s metadata: %w", uuid, err)
+			}
+		}
+		letters = append(letters, newDeadLetter(msg))
+	}
+	return letters, rows.Err()
+}
+
+// Requeue publishes the dead letter id to its original topic and deletes it.
+func (s *SQLDeadLetterStore) Requeue(ctx context.Context, id string) error {
+	var payload, metadata []byte
+	row := s.db.QueryRowContext(ctx, "SELECT payload, metadata FROM "+s.table+" WHERE uuid = "+s.placeholder(), id)
+	if err := row.Scan(&payload, &metadata); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("dead letter not found: %s", id)
+		}
+		return err
+	}
+	msg := message.NewMessage(id, payload)
+	if len(metadata) > 0 {
+		if err := json.Unmarshal(metadata, &msg.Metadata); err != nil {
+			return fmt.Errorf("decode dead letter %s metadata: %w", id, err)
+		}
+	}
+	letter := newDeadLetter(msg)
+	requeued, err := requeueMessage(letter)
+	if err != nil {
+		return err
+	}
+	if err := s.publisher.Publish(letter.Topic, requeued); err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, "DELETE FROM "+s.table+" WHERE uuid = "+s.placeholder(), id)
+	return err
+}
+
+func (s *SQLDeadLetterStore) placeholder() string {
+	if s.postgres {
+		return "$1"
+	}
+	return "?"
+}
@@ -0,0 +1,124 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/ThreeDotsLabs/watermill/pubsub/gochannel"
+)
+
+// TestWorkerDeadLetterRequeue tests that permanent failures are dead-lettered
+// with failure context and can be listed and requeued from a gochannel.
+func TestWorkerDeadLetterRequeue(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pubsub := gochannel.NewGoChannel(gochannel.Config{}, watermill.NopLogger{})
+	defer pubsub.Close()
+
+	store, err := NewGoChannelDeadLetterStore(ctx, pubsub, "dlq")
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	requeued, err := pubsub.Subscribe(ctx, "events")
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+
+	w := New(WithDeadLetter(pubsub, "dlq"))
+	w.HandleType("push", func(ctx context.Context, evt *Event) error {
+		return errors.New("boom")
+	})
+	msg := message.NewMessage("1", []byte(`{"ref":"main"}`))
+	msg.Metadata.Set("provider", "github")
+	msg.Metadata.Set("event", "push")
+	w.handleMessage(ctx, "events", msg)
+	if !acked(msg) {
+		t.Fatalf("expected dead-lettered message to be acked")
+	}
+
+	var letters []DeadLetter
+	for i := 0; i < 100 && len(letters) == 0; i++ {
+		letters, _ = store.List(ctx, 0)
+		time.Sleep(5 * time.Millisecond)
+	}
+	if len(letters) != 1 {
+		t.Fatalf("expected 1 dead letter, got %d", len(letters))
+	}
+	letter := letters[0]
+	if letter.Topic != "events" || letter.Handler != "type:push" || letter.Error != "boom" || letter.Attempts != 1 || letter.FailedAt.IsZero() {
+		t.Fatalf("unexpected dead letter: %+v", letter)
+	}
+
+	if err := store.Requeue(ctx, letter.ID); err != nil {
+		t.Fatalf("requeue: %v", err)
+	}
+	select {
+	case out := <-requeued:
+		out.Ack()
+		if string(out.Payload) != `{"ref":"main"}` || out.Metadata.Get("provider") != "github" || out.Metadata.Get(DeadLetterErrorKey) != "" {
+			t.Fatalf("unexpected requeued message: %s %v", out.Payload, out.Metadata)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected requeued message")
+	}
+	if letters, _ := store.List(ctx, 0); len(letters) != 0 {
+		t.Fatalf("expected requeued letter to be removed, got %d", len(letters))
+	}
+}
+
+// TestGoChannelDeadLetterRequeueBlockingPublish tests that a requeue does not
+// deadlock when the requeued message fails again on a gochannel that blocks
+// publishers until subscribers ack.
+func TestGoChannelDeadLetterRequeueBlockingPublish(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pubsub := gochannel.NewGoChannel(gochannel.Config{BlockPublishUntilSubscriberAck: true}, watermill.NopLogger{})
+	defer pubsub.Close()
+
+	store, err := NewGoChannelDeadLetterStore(ctx, pubsub, "dlq")
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	w := New(WithDeadLetter(pubsub, "dlq"))
+	w.HandleType("push", func(ctx context.Context, evt *Event) error {
+		return errors.New("boom")
+	})
+	requeued, err := pubsub.Subscribe(ctx, "events")
+	if err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	go func() {
+		for msg := range requeued {
+			w.handleMessage(ctx, "events", msg)
+		}
+	}()
+
+	msg := message.NewMessage("1", []byte(`{"ref":"main"}`))
+	msg.Metadata.Set("provider", "github")
+	msg.Metadata.Set("event", "push")
+	w.handleMessage(ctx, "events", msg)
+	letters, _ := store.List(ctx, 0)
+	if len(letters) != 1 {
+		t.Fatalf("expected 1 dead letter, got %d", len(letters))
+	}
+
+	first := letters[0].ID
+	done := make(chan error, 1)
+	go func() { done <- store.Requeue(ctx, first) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("requeue: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("requeue deadlocked")
+	}
+	letters, _ = store.List(ctx, 0)
+	if len(letters) != 1 || letters[0].ID == first {
+		t.Fatalf("expected only the new dead letter to remain, got %+v", letters)
+	}
+}
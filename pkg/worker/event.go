@@ -1,6 +1,10 @@
 package worker
 
-import "encoding/json"
+import (
+	"encoding/json"
+
+	"githooks/pkg/normalized"
+)
 
 // Event represents a message received by the worker.
 type Event struct {
@@ -16,6 +20,97 @@ type Event struct {
 	Payload json.RawMessage `json:"payload"`
 	// Normalized is the decoded JSON payload of the event.
 	Normalized map[string]interface{} `json:"normalized"`
+	// Model is the provider-independent event model published by the server
+	// in the "normalized" metadata, or nil when the event has none.
+	Model *normalized.Event `json:"model,omitempty"`
 	// Client is an API client for the provider, if available.
 	Client interface{} `json:"-"`
 }
+
+// PullRequest returns the normalized pull or merge request, or nil.
+func (e *Event) PullRequest() *normalized.PullRequest {
+	if model := e.model(); model != nil {
+		return model.PullRequest
+	}
+	return nil
+}
+
+// Push returns the normalized branch push, or nil.
+func (e *Event) Push() *normalized.Push {
+	if model := e.model(); model != nil {
+		return model.Push
+	}
+	return nil
+}
+
+// Tag returns the normalized tag event, or nil.
+func (e *Event) Tag() *normalized.Tag {
+	if model := e.model(); model != nil {
+		return model.Tag
+	}
+	return nil
+}
+
+// Comment returns the normalized comment, or nil.
+func (e *Event) Comment() *normalized.Comment {
+	if model := e.model(); model != nil {
+		return model.Comment
+	}
+	return nil
+}
+
+// Pipeline returns the normalized pipeline, workflow run or build, or nil.
+func (e *Event) Pipeline() *normalized.Pipeline {
+	if model := e.model(); model != nil {
+		return model.Pipeline
+	}
+	return nil
+}
+
+// Release returns the normalized release, or nil.
+func (e *Event) Release() *normalized.Release {
+	if model := e.model(); model != nil {
+		return model.Release
+	}
+	return nil
+}
+
+// Repository returns the normalized repository, or nil.
+func (e *Event) Repository() *normalized.Repository {
+	if model := e.model(); model != nil {
+		return model.Repository
+	}
+	return nil
+}
+
+// Actor returns the user who triggered the event, or nil.
+func (e *Event) Actor() *normalized.Actor {
+	if model := e.model(); model != nil {
+		return model.Actor
+	}
+	return nil
+}
+
+// model returns Model, decoding it from metadata for events built by a
+// custom Codec.
+func (e *Event) model() *normalized.Event {
+	if e == nil {
+		return nil
+	}
+	if e.Model == nil {
+		e.Model = decodeModel(e.Metadata)
+	}
+	return e.Model
+}
+
+func decodeModel(metadata map[string]string) *normalized.Event {
+	raw := metadata["normalized"]
+	if raw == "" {
+		return nil
+	}
+	var model normalized.Event
+	if err := json.Unmarshal([]byte(raw), &model); err != nil {
+		return nil
+	}
+	return &model
+}
@@ -12,6 +12,26 @@ func WithSubscriber(sub message.Subscriber) Option {
 	}
 }
 
+// WithPublisher sets the Watermill publisher used to redeliver retried
+// messages and to route them to dead letter topics. Retry policies with an
+// attempt limit require it.
+func WithPublisher(pub message.Publisher) Option {
+	return func(w *Worker) {
+		w.publisher = pub
+	}
+}
+
+// WithDeadLetter publishes messages that fail permanently to topic with pub,
+// adding metadata that describes the error, handler, original topic, attempt
+// count and failure time. Permanent failures are those the retry policy does
+// not retry; a policy's own DeadLetterTopic takes precedence over topic.
+func WithDeadLetter(pub message.Publisher, topic string) Option {
+	return func(w *Worker) {
+		w.deadLetterPublisher = pub
+		w.deadLetterTopic = topic
+	}
+}
+
 // WithTopics adds a list of topics for the worker to subscribe to.
 func WithTopics(topics ...string) Option {
 	return func(w *Worker) {
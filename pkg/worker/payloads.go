@@ -0,0 +1,184 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	bbhook "github.com/go-playground/webhooks/v6/bitbucket"
+	gh "github.com/google/go-github/v57/github"
+	gl "github.com/xanzy/go-gitlab"
+)
+
+// PayloadHandler processes an event together with its decoded provider payload.
+type PayloadHandler[T any] func(ctx context.Context, evt *Event, payload *T) error
+
+// PayloadDecodeError is returned when an event payload cannot be decoded into
+// the type a handler expects. It is passed to the RetryPolicy like any other
+// handler error, so a policy can drop malformed payloads instead of retrying.
+type PayloadDecodeError struct {
+	Provider string
+	Type     string
+	Err      error
+}
+
+func (e *PayloadDecodeError) Error() string {
+	return fmt.Sprintf("decode %s %s payload: %v", e.Provider, e.Type, e.Err)
+}
+
+func (e *PayloadDecodeError) Unwrap() error {
+	return e.Err
+}
+
+// BitbucketPullRequestEvent is the shared shape of the Bitbucket Cloud
+// pullrequest:created, updated, fulfilled and rejected payloads.
+type BitbucketPullRequestEvent struct {
+	Actor       bbhook.Owner       `json:"actor"`
+	PullRequest bbhook.PullRequest `json:"pullrequest"`
+	Repository  bbhook.Repository  `json:"repository"`
+}
+
+// Event types handled by the typed registrations.
+var (
+	gitHubPullRequestTypes    = []string{"pull_request"}
+	gitHubPushTypes           = []string{"push"}
+	gitHubIssueCommentTypes   = []string{"issue_comment"}
+	gitLabMergeRequestTypes   = []string{string(gl.EventTypeMergeRequest)}
+	gitLabPushTypes           = []string{string(gl.EventTypePush)}
+	gitLabTagPushTypes        = []string{string(gl.EventTypeTagPush)}
+	bitbucketPullRequestTypes = []string{"pullrequest:created", "pullrequest:updated", "pullrequest:fulfilled", "pullrequest:rejected"}
+	bitbucketPushTypes        = []string{"repo:push"}
+)
+
+// HandlePayload registers h for events from provider whose type is one of
+// eventTypes, decoding the payload into T before calling it. Decode failures
+// are returned as *PayloadDecodeError.
+func HandlePayload[T any](w *Worker, provider string, eventTypes []string, h PayloadHandler[T]) {
+	if h == nil {
+		return
+	}
+	for _, eventType := range eventTypes {
+		w.HandleProviderType(provider, eventType, func(ctx context.Context, evt *Event) error {
+			payload, err := DecodePayload[T](evt)
+			if err != nil {
+				return err
+			}
+			return h(ctx, evt, payload)
+		})
+	}
+}
+
+// DecodePayload decodes the raw event payload into T.
+func DecodePayload[T any](evt *Event) (*T, error) {
+	if evt == nil {
+		return nil, &PayloadDecodeError{Err: fmt.Errorf("event is nil")}
+	}
+	var payload T
+	if err := json.Unmarshal(evt.Payload, &payload); err != nil {
+		return nil, &PayloadDecodeError{Provider: evt.Provider, Type: evt.Type, Err: err}
+	}
+	return &payload, nil
+}
+
+// HandleGitHubPullRequest registers h for GitHub pull_request events.
+func (w *Worker) HandleGitHubPullRequest(h PayloadHandler[gh.PullRequestEvent]) {
+	HandlePayload(w, "github", gitHubPullRequestTypes, h)
+}
+
+// HandleGitHubPush registers h for GitHub push events.
+func (w *Worker) HandleGitHubPush(h PayloadHandler[gh.PushEvent]) {
+	HandlePayload(w, "github", gitHubPushTypes, h)
+}
+
+// HandleGitHubIssueComment registers h for GitHub issue_comment events,
+// which include comments on pull requests.
+func (w *Worker) HandleGitHubIssueComment(h PayloadHandler[gh.IssueCommentEvent]) {
+	HandlePayload(w, "github", gitHubIssueCommentTypes, h)
+}
+
+// HandleGitLabMergeRequest registers h for GitLab merge request events.
+func (w *Worker) HandleGitLabMergeRequest(h PayloadHandler[gl.MergeEvent]) {
+	HandlePayload(w, "gitlab", gitLabMergeRequestTypes, h)
+}
+
+// HandleGitLabPush registers h for GitLab push events.
+func (w *Worker) HandleGitLabPush(h PayloadHandler[gl.PushEvent]) {
+	HandlePayload(w, "gitlab", gitLabPushTypes, h)
+}
+
+// HandleGitLabTagPush registers h for GitLab tag push events.
+func (w *Worker) HandleGitLabTagPush(h PayloadHandler[gl.TagEvent]) {
+	HandlePayload(w, "gitlab", gitLabTagPushTypes, h)
+}
+
+// HandleBitbucketPullRequest registers h for Bitbucket Cloud pull request
+// created, updated, fulfilled and rejected events.
+func (w *Worker) HandleBitbucketPullRequest(h PayloadHandler[BitbucketPullRequestEvent]) {
+	HandlePayload(w, "bitbucket", bitbucketPullRequestTypes, h)
+}
+
+// HandleBitbucketPush registers h for Bitbucket Cloud repo:push events.
+func (w *Worker) HandleBitbucketPush(h PayloadHandler[bbhook.RepoPushPayload]) {
+	HandlePayload(w, "bitbucket", bitbucketPushTypes, h)
+}
+
+// GitHubPullRequest decodes a GitHub pull_request event payload.
+func GitHubPullRequest(evt *Event) (*gh.PullRequestEvent, error) {
+	return decodeTyped[gh.PullRequestEvent](evt, "github", gitHubPullRequestTypes)
+}
+
+// GitHubPush decodes a GitHub push event payload.
+func GitHubPush(evt *Event) (*gh.PushEvent, error) {
+	return decodeTyped[gh.PushEvent](evt, "github", gitHubPushTypes)
+}
+
+// GitHubIssueComment decodes a GitHub issue_comment event payload.
+func GitHubIssueComment(evt *Event) (*gh.IssueCommentEvent, error) {
+	return decodeTyped[gh.IssueCommentEvent](evt, "github", gitHubIssueCommentTypes)
+}
+
+// GitLabMergeRequest decodes a GitLab merge request event payload.
+func GitLabMergeRequest(evt *Event) (*gl.MergeEvent, error) {
+	return decodeTyped[gl.MergeEvent](evt, "gitlab", gitLabMergeRequestTypes)
+}
+
+// GitLabPush decodes a GitLab push event payload.
+func GitLabPush(evt *Event) (*gl.PushEvent, error) {
+	return decodeTyped[gl.PushEvent](evt, "gitlab", gitLabPushTypes)
+}
+
+// GitLabTagPush decodes a GitLab tag push event payload.
+func GitLabTagPush(evt *Event) (*gl.TagEvent, error) {
+	return decodeTyped[gl.TagEvent](evt, "gitlab", gitLabTagPushTypes)
+}
+
+// BitbucketPullRequest decodes a Bitbucket Cloud pull request event payload.
+func BitbucketPullRequest(evt *Event) (*BitbucketPullRequestEvent, error) {
+	return decodeTyped[BitbucketPullRequestEvent](evt, "bitbucket", bitbucketPullRequestTypes)
+}
+
+// BitbucketPush decodes a Bitbucket Cloud repo:push event payload.
+func BitbucketPush(evt *Event) (*bbhook.RepoPushPayload, error) {
+	return decodeTyped[bbhook.RepoPushPayload](evt, "bitbucket", bitbucketPushTypes)
+}
+
+// decodeTyped checks that evt is one of the expected provider event types
+// before decoding it.
+func decodeTyped[T any](evt *Event, provider string, eventTypes []string) (*T, error) {
+	if evt == nil {
+		return nil, &PayloadDecodeError{Provider: provider, Err: fmt.Errorf("event is nil")}
+	}
+	if evt.Provider != provider || !containsString(eventTypes, evt.Type) {
+		return nil, fmt.Errorf("event %s/%s is not %s %v", evt.Provider, evt.Type, provider, eventTypes)
+	}
+	return DecodePayload[T](evt)
+}
+
+func containsString(values []string, value string) bool {
+	for _, item := range values {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
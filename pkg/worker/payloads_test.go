@@ -0,0 +1,67 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ThreeDotsLabs/watermill/message"
+	gh "github.com/google/go-github/v57/github"
+)
+
+type recordingRetry struct {
+	err error
+}
+
+func (r *recordingRetry) OnError(ctx context.Context, evt *Event, err error) RetryDecision {
+	r.err = err
+	return RetryDecision{}
+}
+
+// TestHandleGitHubPullRequest tests typed dispatch and that decode errors reach the retry policy.
+func TestHandleGitHubPullRequest(t *testing.T) {
+	retry := &recordingRetry{}
+	w := New(WithRetry(retry))
+	var number int
+	w.HandleGitHubPullRequest(func(ctx context.Context, evt *Event, payload *gh.PullRequestEvent) error {
+		number = payload.GetNumber()
+		return nil
+	})
+	w.HandleType("pull_request", func(ctx context.Context, evt *Event) error {
+		t.Fatalf("expected provider handler to take precedence")
+		return nil
+	})
+
+	newMessage := func(payload string) *message.Message {
+		msg := message.NewMessage("1", []byte(payload))
+		msg.Metadata.Set("provider", "github")
+		msg.Metadata.Set("event", "pull_request")
+		return msg
+	}
+
+	w.handleMessage(context.Background(), "pr", newMessage(`{"action":"opened","number":42}`))
+	if number != 42 {
+		t.Fatalf("expected pull request 42, got %d", number)
+	}
+	if retry.err != nil {
+		t.Fatalf("unexpected error: %v", retry.err)
+	}
+
+	w.handleMessage(context.Background(), "pr", newMessage(`{"number":"not-a-number"}`))
+	var decodeErr *PayloadDecodeError
+	if !errors.As(retry.err, &decodeErr) || decodeErr.Provider != "github" || decodeErr.Type != "pull_request" {
+		t.Fatalf("expected payload decode error, got %v", retry.err)
+	}
+}
+
+// TestTypedAccessorChecksEventType tests that typed accessors reject other event types.
+func TestTypedAccessorChecksEventType(t *testing.T) {
+	evt := &Event{Provider: "gitlab", Type: "Push Hook", Payload: []byte(`{"ref":"refs/heads/main"}`)}
+	push, err := GitLabPush(evt)
+	if err != nil || push.Ref != "refs/heads/main" {
+		t.Fatalf("expected push ref, got %v %v", push, err)
+	}
+	if _, err := GitLabMergeRequest(evt); err == nil {
+		t.Fatalf("expected mismatched event type to fail")
+	}
+}
@@ -4,10 +4,12 @@ import "context"
 
 // ProviderClients is a struct that holds a map of client providers for each Git provider.
 type ProviderClients struct {
-	GitHub    func(ctx context.Context, evt *Event) (interface{}, error)
-	GitLab    func(ctx context.Context, evt *Event) (interface{}, error)
-	Bitbucket func(ctx context.Context, evt *Event) (interface{}, error)
-	Default   func(ctx context.Context, evt *Event) (interface{}, error)
+	GitHub      func(ctx context.Context, evt *Event) (interface{}, error)
+	GitLab      func(ctx context.Context, evt *Event) (interface{}, error)
+	Bitbucket   func(ctx context.Context, evt *Event) (interface{}, error)
+	Gitea       func(ctx context.Context, evt *Event) (interface{}, error)
+	AzureDevOps func(ctx context.Context, evt *Event) (interface{}, error)
+	Default     func(ctx context.Context, evt *Event) (interface{}, error)
 }
 
 // Client returns a client for the provider specified in the event.
@@ -26,6 +28,14 @@ func (p ProviderClients) Client(ctx context.Context, evt *Event) (interface{}, e
 		if p.Bitbucket != nil {
 			return p.Bitbucket(ctx, evt)
 		}
+	case "gitea":
+		if p.Gitea != nil {
+			return p.Gitea(ctx, evt)
+		}
+	case "azuredevops":
+		if p.AzureDevOps != nil {
+			return p.AzureDevOps(ctx, evt)
+		}
 	}
 	if p.Default != nil {
 		return p.Default(ctx, evt)
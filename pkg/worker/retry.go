@@ -1,11 +1,31 @@
 package worker
 
-import "context"
+import (
+	"context"
+	"math"
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+// AttemptMetadataKey is the message metadata key holding the delivery attempt,
+// starting at 1. The worker increments it when it republishes a retry through
+// WithPublisher. Nacked messages are redelivered from the broker's copy, which
+// does not carry the increment, so policies with an attempt limit require
+// WithPublisher.
+const AttemptMetadataKey = "attempt"
 
 // RetryDecision defines whether a message should be retried or Nacked.
 type RetryDecision struct {
 	Retry bool
 	Nack  bool
+	// Delay postpones the redelivery of a retried message. The worker waits it
+	// out in the message's handler goroutine, so the message holds one of the
+	// WithConcurrency slots until then.
+	Delay time.Duration
+	// DeadLetterTopic, when set, publishes the message to this topic and Acks
+	// it instead of retrying. It requires WithPublisher.
+	DeadLetterTopic string
 }
 
 // RetryPolicy defines a policy for retrying failed messages.
@@ -13,6 +33,13 @@ type RetryPolicy interface {
 	OnError(ctx context.Context, evt *Event, err error) RetryDecision
 }
 
+// attemptLimiter is implemented by policies that give up after a number of
+// attempts. They rely on the attempt count surviving redelivery, which only
+// happens when the worker republishes retries through WithPublisher.
+type attemptLimiter interface {
+	limitsAttempts() bool
+}
+
 // NoRetry is a retry policy that never retries.
 type NoRetry struct{}
 
@@ -20,3 +47,97 @@ type NoRetry struct{}
 func (NoRetry) OnError(ctx context.Context, evt *Event, err error) RetryDecision {
 	return RetryDecision{Retry: false, Nack: true}
 }
+
+// MaxAttempts retries immediately until Attempts deliveries have failed, then
+// routes the message to DeadLetterTopic, or drops it when no topic is set.
+// Counting attempts requires WithPublisher; see AttemptMetadataKey.
+type MaxAttempts struct {
+	Attempts        int
+	DeadLetterTopic string
+}
+
+// OnError retries until the attempt limit is reached.
+func (p MaxAttempts) OnError(ctx context.Context, evt *Event, err error) RetryDecision {
+	return retryOrGiveUp(evt, p.Attempts, p.DeadLetterTopic, 0)
+}
+
+func (p MaxAttempts) limitsAttempts() bool { return p.Attempts > 0 }
+
+// FixedDelay retries after a constant delay. MaxAttempts of zero retries
+// forever; otherwise the message goes to DeadLetterTopic, or is dropped, once
+// MaxAttempts deliveries have failed.
+type FixedDelay struct {
+	Delay           time.Duration
+	MaxAttempts     int
+	DeadLetterTopic string
+}
+
+// OnError retries after Delay until the attempt limit is reached.
+func (p FixedDelay) OnError(ctx context.Context, evt *Event, err error) RetryDecision {
+	return retryOrGiveUp(evt, p.MaxAttempts, p.DeadLetterTopic, p.Delay)
+}
+
+func (p FixedDelay) limitsAttempts() bool { return p.MaxAttempts > 0 }
+
+// ExponentialBackoff retries with a delay of Initial * Multiplier^(attempt-1),
+// capped at Max. Jitter (0 to 1) randomly shortens each delay by up to that
+// fraction so that failed messages do not retry in lockstep. MaxAttempts and
+// DeadLetterTopic behave as in FixedDelay.
+type ExponentialBackoff struct {
+	Initial         time.Duration
+	Max             time.Duration
+	Multiplier      float64
+	Jitter          float64
+	MaxAttempts     int
+	DeadLetterTopic string
+}
+
+// OnError retries with an exponentially growing delay until the attempt limit is reached.
+func (p ExponentialBackoff) OnError(ctx context.Context, evt *Event, err error) RetryDecision {
+	return retryOrGiveUp(evt, p.MaxAttempts, p.DeadLetterTopic, p.delay(Attempt(evt)))
+}
+
+func (p ExponentialBackoff) limitsAttempts() bool { return p.MaxAttempts > 0 }
+
+func (p ExponentialBackoff) delay(attempt int) time.Duration {
+	initial := p.Initial
+	if initial <= 0 {
+		initial = time.Second
+	}
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+	delay := float64(initial) * math.Pow(multiplier, float64(attempt-1))
+	if p.Max > 0 && delay > float64(p.Max) {
+		delay = float64(p.Max)
+	}
+	if jitter := math.Min(math.Max(p.Jitter, 0), 1); jitter > 0 {
+		delay -= delay * jitter * rand.Float64()
+	}
+	if delay >= math.MaxInt64 {
+		// Without Max the delay outgrows int64 after enough attempts, and the
+		// conversion would wrap to a negative duration.
+		return math.MaxInt64
+	}
+	return time.Duration(delay)
+}
+
+func retryOrGiveUp(evt *Event, maxAttempts int, deadLetterTopic string, delay time.Duration) RetryDecision {
+	if maxAttempts > 0 && Attempt(evt) >= maxAttempts {
+		return RetryDecision{DeadLetterTopic: deadLetterTopic}
+	}
+	return RetryDecision{Retry: true, Nack: true, Delay: delay}
+}
+
+// Attempt returns the delivery attempt of evt, starting at 1.
+func Attempt(evt *Event) int {
+	if evt == nil {
+		return 1
+	}
+	attempt, err := strconv.Atoi(evt.Metadata[AttemptMetadataKey])
+	if err != nil || attempt < 1 {
+		return 1
+	}
+	return attempt
+}
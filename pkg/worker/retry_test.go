@@ -0,0 +1,143 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ThreeDotsLabs/watermill"
+	"github.com/ThreeDotsLabs/watermill/message"
+	"github.com/ThreeDotsLabs/watermill/pubsub/gochannel"
+)
+
+type capturingPublisher struct {
+	topics   []string
+	messages []*message.Message
+}
+
+func (p *capturingPublisher) Publish(topic string, messages ...*message.Message) error {
+	for _, msg := range messages {
+		p.topics = append(p.topics, topic)
+		p.messages = append(p.messages, msg)
+	}
+	return nil
+}
+
+func (p *capturingPublisher) Close() error { return nil }
+
+func acked(msg *message.Message) bool {
+	select {
+	case <-msg.Acked():
+		return true
+	default:
+		return false
+	}
+}
+
+func nacked(msg *message.Message) bool {
+	select {
+	case <-msg.Nacked():
+		return true
+	default:
+		return false
+	}
+}
+
+// TestExponentialBackoffDelay tests delay growth, the cap and jitter bounds.
+func TestExponentialBackoffDelay(t *testing.T) {
+	policy := ExponentialBackoff{Initial: 100 * time.Millisecond, Max: time.Second, Multiplier: 2}
+	for attempt, want := range map[int]time.Duration{1: 100 * time.Millisecond, 3: 400 * time.Millisecond, 6: time.Second} {
+		if got := policy.delay(attempt); got != want {
+			t.Fatalf("attempt %d: expected %s, got %s", attempt, want, got)
+		}
+	}
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := policy.delay(2); got < 100*time.Millisecond || got > 200*time.Millisecond {
+			t.Fatalf("expected jittered delay within [100ms, 200ms], got %s", got)
+		}
+	}
+
+	uncapped := ExponentialBackoff{Initial: time.Second, Multiplier: 2}
+	for _, attempt := range []int{35, 64, 10000} {
+		if got := uncapped.delay(attempt); got != math.MaxInt64 {
+			t.Fatalf("attempt %d: expected delay clamped to the maximum duration, got %s", attempt, got)
+		}
+	}
+}
+
+// TestRetryPolicyGivesUp tests that policies stop retrying at MaxAttempts.
+func TestRetryPolicyGivesUp(t *testing.T) {
+	policy := FixedDelay{Delay: time.Second, MaxAttempts: 3, DeadLetterTopic: "dlq"}
+	evt := &Event{Metadata: map[string]string{AttemptMetadataKey: "2"}}
+	if decision := policy.OnError(context.Background(), evt, errors.New("boom")); !decision.Retry || decision.Delay != time.Second {
+		t.Fatalf("expected retry after 1s, got %+v", decision)
+	}
+	evt.Metadata[AttemptMetadataKey] = "3"
+	if decision := policy.OnError(context.Background(), evt, errors.New("boom")); decision.Retry || decision.DeadLetterTopic != "dlq" {
+		t.Fatalf("expected dead letter decision, got %+v", decision)
+	}
+	if decision := (MaxAttempts{Attempts: 1}).OnError(context.Background(), nil, errors.New("boom")); decision.Retry || decision.Nack {
+		t.Fatalf("expected message to be dropped, got %+v", decision)
+	}
+}
+
+// TestWorkerRetryRedelivery tests that retries are republished with the next
+// attempt and exhausted messages are routed to the dead letter topic.
+func TestWorkerRetryRedelivery(t *testing.T) {
+	pub := &capturingPublisher{}
+	w := New(WithPublisher(pub), WithRetry(MaxAttempts{Attempts: 2, DeadLetterTopic: "dlq"}))
+	w.HandleTopic("events", func(ctx context.Context, evt *Event) error {
+		return errors.New("boom")
+	})
+
+	msg := message.NewMessage("1", []byte(`{}`))
+	msg.Metadata.Set("provider", "github")
+	w.handleMessage(context.Background(), "events", msg)
+	if !acked(msg) || len(pub.messages) != 1 || pub.topics[0] != "events" {
+		t.Fatalf("expected retry republished to events, got %v", pub.topics)
+	}
+	retried := pub.messages[0]
+	if retried.Metadata.Get(AttemptMetadataKey) != "2" || retried.Metadata.Get("provider") != "github" {
+		t.Fatalf("unexpected retry metadata: %v", retried.Metadata)
+	}
+
+	w.handleMessage(context.Background(), "events", retried)
+	if !acked(retried) || len(pub.messages) != 2 || pub.topics[1] != "dlq" {
+		t.Fatalf("expected message routed to dlq, got %v", pub.topics)
+	}
+}
+
+// TestWorkerRetryWithoutPublisher tests that retries Nack and track the attempt on the message.
+func TestWorkerRetryWithoutPublisher(t *testing.T) {
+	w := New(WithRetry(FixedDelay{MaxAttempts: 5}))
+	w.HandleTopic("events", func(ctx context.Context, evt *Event) error {
+		return errors.New("boom")
+	})
+	msg := message.NewMessage("1", []byte(`{}`))
+	w.handleMessage(context.Background(), "events", msg)
+	if !nacked(msg) || msg.Metadata.Get(AttemptMetadataKey) != "2" {
+		t.Fatalf("expected nack with attempt 2, got %v", msg.Metadata)
+	}
+}
+
+// TestWorkerRunRequiresPublisherForAttemptLimit tests that policies with an attempt limit need WithPublisher.
+func TestWorkerRunRequiresPublisherForAttemptLimit(t *testing.T) {
+	sub := gochannel.NewGoChannel(gochannel.Config{}, watermill.NopLogger{})
+	defer sub.Close()
+	w := New(WithSubscriber(sub), WithRetry(ExponentialBackoff{MaxAttempts: 3}))
+	w.HandleTopic("events", func(ctx context.Context, evt *Event) error { return nil })
+	if err := w.Run(context.Background()); err == nil || !strings.Contains(err.Error(), "WithPublisher") {
+		t.Fatalf("expected publisher requirement error, got %v", err)
+	}
+
+	if err := New(WithRetry(FixedDelay{Delay: time.Second})).checkRetryPolicy(); err != nil {
+		t.Fatalf("expected unlimited policy without publisher to be allowed: %v", err)
+	}
+	if err := New(WithPublisher(&capturingPublisher{}), WithRetry(MaxAttempts{Attempts: 3})).checkRetryPolicy(); err != nil {
+		t.Fatalf("expected limited policy with publisher to be allowed: %v", err)
+	}
+}
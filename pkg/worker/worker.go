@@ -3,8 +3,12 @@ package worker
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strconv"
 	"sync"
+	"time"
 
+	"github.com/ThreeDotsLabs/watermill"
 	"github.com/ThreeDotsLabs/watermill/message"
 )
 
@@ -12,30 +16,36 @@ import (
 // messages, and dispatches them to handlers.
 type Worker struct {
 	subscriber  message.Subscriber
+	publisher   message.Publisher
 	codec       Codec
 	retry       RetryPolicy
 	logger      Logger
 	concurrency int
 	topics      []string
 
-	topicHandlers  map[string]Handler
-	typeHandlers   map[string]Handler
-	middleware     []Middleware
-	clientProvider ClientProvider
-	listeners      []Listener
-	allowedTopics  map[string]struct{}
+	topicHandlers    map[string]Handler
+	providerHandlers map[string]Handler
+	typeHandlers     map[string]Handler
+	middleware       []Middleware
+	clientProvider   ClientProvider
+	listeners        []Listener
+	allowedTopics    map[string]struct{}
+
+	deadLetterPublisher message.Publisher
+	deadLetterTopic     string
 }
 
 // New creates a new Worker with the given options.
 func New(opts ...Option) *Worker {
 	w := &Worker{
-		codec:         DefaultCodec{},
-		retry:         NoRetry{},
-		logger:        stdLogger{},
-		concurrency:   1,
-		topicHandlers: make(map[string]Handler),
-		typeHandlers:  make(map[string]Handler),
-		allowedTopics: make(map[string]struct{}),
+		codec:            DefaultCodec{},
+		retry:            NoRetry{},
+		logger:           stdLogger{},
+		concurrency:      1,
+		topicHandlers:    make(map[string]Handler),
+		providerHandlers: make(map[string]Handler),
+		typeHandlers:     make(map[string]Handler),
+		allowedTopics:    make(map[string]struct{}),
 	}
 	for _, opt := range opts {
 		opt(w)
@@ -66,8 +76,22 @@ func (w *Worker) HandleType(eventType string, h Handler) {
 	w.typeHandlers[eventType] = h
 }
 
+// HandleProviderType registers a handler for an event type from a specific
+// provider. It takes precedence over HandleType for that provider.
+func (w *Worker) HandleProviderType(provider, eventType string, h Handler) {
+	if h == nil || provider == "" || eventType == "" {
+		return
+	}
+	w.providerHandlers[providerTypeKey(provider, eventType)] = h
+}
+
+func providerTypeKey(provider, eventType string) string {
+	return provider + "/" + eventType
+}
+
 // Run starts the worker, subscribing to topics and processing messages.
-// It blocks until the context is canceled.
+// It blocks until the context is canceled. It fails when the retry policy
+// limits attempts and no publisher is set.
 func (w *Worker) Run(ctx context.Context) error {
 	if w.subscriber == nil {
 		return errors.New("subscriber is required")
@@ -75,6 +99,9 @@ func (w *Worker) Run(ctx context.Context) error {
 	if len(w.topics) == 0 {
 		return errors.New("at least one topic is required")
 	}
+	if err := w.checkRetryPolicy(); err != nil {
+		return err
+	}
 
 	topics := unique(w.topics)
 	w.notifyStart(ctx)
@@ -132,12 +159,7 @@ func (w *Worker) handleMessage(ctx context.Context, topic string, msg *message.M
 	if err != nil {
 		w.logger.Printf("decode failed: %v", err)
 		w.notifyError(ctx, nil, err)
-		decision := w.retry.OnError(ctx, nil, err)
-		if decision.Retry || decision.Nack {
-			msg.Nack()
-			return
-		}
-		msg.Ack()
+		w.handleFailure(ctx, topic, "codec", msg, &Event{Topic: topic, Metadata: msg.Metadata}, err)
 		return
 	}
 
@@ -146,12 +168,7 @@ func (w *Worker) handleMessage(ctx context.Context, topic string, msg *message.M
 		if err != nil {
 			w.logger.Printf("client init failed: %v", err)
 			w.notifyError(ctx, evt, err)
-			decision := w.retry.OnError(ctx, evt, err)
-			if decision.Retry || decision.Nack {
-				msg.Nack()
-				return
-			}
-			msg.Ack()
+			w.handleFailure(ctx, topic, "client_provider", msg, evt, err)
 			return
 		}
 		evt.Client = client
@@ -163,10 +180,7 @@ func (w *Worker) handleMessage(ctx context.Context, topic string, msg *message.M
 
 	w.notifyMessageStart(ctx, evt)
 
-	handler := w.topicHandlers[topic]
-	if handler == nil {
-		handler = w.typeHandlers[evt.Type]
-	}
+	handler, handlerName := w.resolveHandler(topic, evt)
 	if handler == nil {
 		w.logger.Printf("no handler for topic=%s type=%s", topic, evt.Type)
 		w.notifyMessageFinish(ctx, evt, nil)
@@ -178,16 +192,121 @@ func (w *Worker) handleMessage(ctx context.Context, topic string, msg *message.M
 	if err := wrapped(ctx, evt); err != nil {
 		w.notifyMessageFinish(ctx, evt, err)
 		w.notifyError(ctx, evt, err)
-		decision := w.retry.OnError(ctx, evt, err)
-		if decision.Retry || decision.Nack {
+		w.handleFailure(ctx, topic, handlerName, msg, evt, err)
+		return
+	}
+	w.notifyMessageFinish(ctx, evt, nil)
+	msg.Ack()
+}
+
+// resolveHandler returns the handler for evt and a name describing how it was
+// matched.
+func (w *Worker) resolveHandler(topic string, evt *Event) (Handler, string) {
+	if handler := w.topicHandlers[topic]; handler != nil {
+		return handler, "topic:" + topic
+	}
+	key := providerTypeKey(evt.Provider, evt.Type)
+	if handler := w.providerHandlers[key]; handler != nil {
+		return handler, "provider:" + key
+	}
+	if handler := w.typeHandlers[evt.Type]; handler != nil {
+		return handler, "type:" + evt.Type
+	}
+	return nil, ""
+}
+
+// checkRetryPolicy rejects a retry policy that gives up after a number of
+// attempts when no publisher is set: Nacked retries are redelivered without the
+// attempt count, so the limit would never be reached.
+func (w *Worker) checkRetryPolicy() error {
+	if limiter, ok := w.retry.(attemptLimiter); ok && limiter.limitsAttempts() && w.publisher == nil {
+		return fmt.Errorf("retry policy %T limits attempts and requires WithPublisher", w.retry)
+	}
+	return nil
+}
+
+// handleFailure applies the retry policy to a failed message. With a
+// publisher configured, retries are republished to the topic with the next
+// attempt number and the original message is Acked; otherwise the message is
+// Nacked after the delay and the broker redelivers it. Messages the policy
+// gives up on go to the dead letter topic when one is configured.
+//
+// The retry delay is slept in the calling handler goroutine, so a message in
+// backoff occupies a concurrency slot; with concurrency 1 it stalls the worker.
+func (w *Worker) handleFailure(ctx context.Context, topic, handler string, msg *message.Message, evt *Event, err error) {
+	decision := w.retry.OnError(ctx, evt, err)
+	attempt := Attempt(evt)
+	deadLetterTopic := decision.DeadLetterTopic
+	if deadLetterTopic == "" && !decision.Retry {
+		deadLetterTopic = w.deadLetterTopic
+	}
+	switch {
+	case deadLetterTopic != "":
+		pub := w.deadLetterPublisher
+		if pub == nil {
+			pub = w.publisher
+		}
+		if pub == nil {
+			w.logger.Printf("dead letter topic %s requires a publisher; nacking message", deadLetterTopic)
+			msg.Nack()
+			return
+		}
+		if err := pub.Publish(deadLetterTopic, deadLetterMessage(msg, topic, handler, attempt, err)); err != nil {
+			w.logger.Printf("dead letter publish failed: %v", err)
 			msg.Nack()
 			return
 		}
+		w.logger.Printf("message moved to dead letter topic=%s after attempt=%d", deadLetterTopic, attempt)
+		msg.Ack()
+	case decision.Retry:
+		if !sleepContext(ctx, decision.Delay) {
+			msg.Nack()
+			return
+		}
+		if w.publisher == nil {
+			msg.Metadata.Set(AttemptMetadataKey, strconv.Itoa(attempt+1))
+			msg.Nack()
+			return
+		}
+		if err := w.publisher.Publish(topic, redelivery(msg, attempt+1)); err != nil {
+			w.logger.Printf("retry publish failed: %v", err)
+			msg.Nack()
+			return
+		}
+		msg.Ack()
+	case decision.Nack:
+		msg.Nack()
+	default:
+		if attempt > 1 {
+			w.logger.Printf("message dropped after attempt=%d: %v", attempt, err)
+		}
 		msg.Ack()
-		return
 	}
-	w.notifyMessageFinish(ctx, evt, nil)
-	msg.Ack()
+}
+
+// redelivery copies msg under a new UUID with its attempt number set.
+func redelivery(msg *message.Message, attempt int) *message.Message {
+	out := message.NewMessage(watermill.NewUUID(), msg.Payload)
+	for key, value := range msg.Metadata {
+		out.Metadata.Set(key, value)
+	}
+	out.Metadata.Set(AttemptMetadataKey, strconv.Itoa(attempt))
+	return out
+}
+
+// sleepContext waits for d and reports whether ctx is still active.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
 }
 
 func (w *Worker) wrap(h Handler) Handler {
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"githooks/internal"
+	"githooks/pkg/webhook"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ruleFixture is the sidecar YAML next to a payload fixture. Provider defaults
+// to the fixture directory name and Event to the payload file name.
+type ruleFixture struct {
+	Provider string   `yaml:"provider"`
+	Event    string   `yaml:"event"`
+	Topics   []string `yaml:"topics"`
+}
+
+// runRulesCommand runs the `githooks rules` subcommands and returns the exit code.
+func runRulesCommand(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] != "test" {
+		fmt.Fprintln(stderr, "usage: githooks rules test -config config.yaml -fixtures DIR")
+		return 2
+	}
+	flags := flag.NewFlagSet("rules test", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	configPath := flags.String("config", "config.yaml", "Path to config file")
+	fixtures := flags.String("fixtures", ".", "Directory of payload fixtures (*.json) with sidecar *.yaml expectations")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+	failed, err := runRulesTest(*configPath, *fixtures, stdout)
+	if err != nil {
+		fmt.Fprintf(stderr, "rules test: %v\n", err)
+		return 2
+	}
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+// runRulesTest evaluates the rules of the config at configPath against every
+// fixture in dir and reports whether any emitted topics differ from the
+// expected ones.
+func runRulesTest(configPath, dir string, out io.Writer) (bool, error) {
+	config, err := internal.LoadConfig(configPath)
+	if err != nil {
+		return false, fmt.Errorf("load config: %w", err)
+	}
+	rulesConfig := config.RulesConfig()
+	rulesConfig.Logger = log.New(io.Discard, "", 0)
+	engine, err := internal.NewRuleEngine(rulesConfig)
+	if err != nil {
+		return false, fmt.Errorf("compile rules: %w", err)
+	}
+	payloads, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return false, err
+	}
+	sort.Strings(payloads)
+
+	tested, failures := 0, 0
+	for _, payloadPath := range payloads {
+		base := strings.TrimSuffix(filepath.Base(payloadPath), ".json")
+		sidecar, err := os.ReadFile(filepath.Join(dir, base+".yaml"))
+		if os.IsNotExist(err) {
+			fmt.Fprintf(out, "SKIP %s (no %s.yaml)\n", filepath.Base(payloadPath), base)
+			continue
+		}
+		if err != nil {
+			return false, err
+		}
+		var fixture ruleFixture
+		if err := yaml.Unmarshal(sidecar, &fixture); err != nil {
+			return false, fmt.Errorf("%s.yaml: %w", base, err)
+		}
+		if fixture.Provider == "" {
+			fixture.Provider = filepath.Base(filepath.Clean(dir))
+		}
+		if fixture.Event == "" {
+			fixture.Event = base
+		}
+		payload, err := os.ReadFile(payloadPath)
+		if err != nil {
+			return false, err
+		}
+
+		matches := engine.Evaluate(webhook.EventFromPayload(fixture.Provider, fixture.Event, payload))
+		got := make([]string, 0, len(matches))
+		for _, match := range matches {
+			got = append(got, match.Topic)
+		}
+		tested++
+		missing, extra := diffTopics(fixture.Topics, got)
+		if len(missing) == 0 && len(extra) == 0 {
+			fmt.Fprintf(out, "PASS %s (%s/%s)\n", filepath.Base(payloadPath), fixture.Provider, fixture.Event)
+			continue
+		}
+		failures++
+		fmt.Fprintf(out, "FAIL %s (%s/%s)\n", filepath.Base(payloadPath), fixture.Provider, fixture.Event)
+		for _, topic := range missing {
+			fmt.Fprintf(out, "  - %s\n", topic)
+		}
+		for _, topic := range extra {
+			fmt.Fprintf(out, "  + %s\n", topic)
+		}
+	}
+	if tested == 0 {
+		return false, fmt.Errorf("no fixtures with sidecar yaml in %s", dir)
+	}
+	fmt.Fprintf(out, "%d passed, %d failed\n", tested-failures, failures)
+	return failures > 0, nil
+}
+
+// diffTopics returns the expected topics that were not emitted and the
+// emitted topics that were not expected, counting duplicates.
+func diffTopics(expected, got []string) (missing, extra []string) {
+	counts := make(map[string]int, len(got))
+	for _, topic := range got {
+		counts[topic]++
+	}
+	for _, topic := range expected {
+		if counts[topic] > 0 {
+			counts[topic]--
+			continue
+		}
+		missing = append(missing, topic)
+	}
+	for _, topic := range got {
+		if counts[topic] > 0 {
+			counts[topic]--
+			extra = append(extra, topic)
+		}
+	}
+	return missing, extra
+}
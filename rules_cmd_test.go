@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestRunRulesTest tests that fixtures are evaluated and mismatches are reported as a diff.
+func TestRunRulesTest(t *testing.T) {
+	dir := t.TempDir()
+	fixtures := filepath.Join(dir, "github")
+	if err := os.Mkdir(fixtures, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	files := map[string]string{
+		filepath.Join(dir, "config.yaml"):      "rules:\n  - when: action == \"opened\"\n    emit: [pr.opened, audit]\n",
+		filepath.Join(fixtures, "opened.json"): `{"action":"opened"}`,
+		filepath.Join(fixtures, "opened.yaml"): "event: pull_request\ntopics: [pr.opened, audit]\n",
+		filepath.Join(fixtures, "closed.json"): `{"action":"closed"}`,
+		filepath.Join(fixtures, "closed.yaml"): "event: pull_request\ntopics: [pr.closed]\n",
+		filepath.Join(fixtures, "notes.json"):  `{}`,
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+	}
+
+	var out bytes.Buffer
+	failed, err := runRulesTest(filepath.Join(dir, "config.yaml"), fixtures, &out)
+	if err != nil {
+		t.Fatalf("rules test: %v", err)
+	}
+	if !failed {
+		t.Fatalf("expected a failure, got:\n%s", out.String())
+	}
+	for _, want := range []string{"PASS opened.json (github/pull_request)", "FAIL closed.json (github/pull_request)", "  - pr.closed", "SKIP notes.json", "1 passed, 1 failed"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("expected %q in output:\n%s", want, out.String())
+		}
+	}
+}